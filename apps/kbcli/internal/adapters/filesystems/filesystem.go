@@ -2,7 +2,10 @@ package filesystems
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 // MediaInfo contains information about a media file
@@ -36,6 +39,16 @@ func ReadFile(filePath string) ([]byte, error) {
 	return file, nil
 }
 
+// OpenFile opens the file to be read, so big files can be read in parts.
+func OpenFile(filePath string) (*os.File, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file: %w", err)
+	}
+
+	return f, nil
+}
+
 func CheckFile(filePath string) (*MediaInfo, error) {
 	var result MediaInfo
 	stat, err := os.Stat(filePath)
@@ -154,3 +167,36 @@ func TruncateFile(filePath string) error {
 
 	return nil
 }
+
+// MakeFolders makes the given directory along with any missing parents.
+func MakeFolders(folderPath string) error {
+	err := os.MkdirAll(folderPath, dirPerms)
+	if err != nil {
+		return fmt.Errorf("unable to make directories: %w", err)
+	}
+
+	return nil
+}
+
+// FindFiles gets the paths of the files with the given extension in the
+// directory and its subdirectories, in lexical order.
+func FindFiles(folderPath, extension string) ([]string, error) {
+	var files []string
+
+	err := filepath.WalkDir(folderPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(path), extension) {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to find files in %q: %w", folderPath, err)
+	}
+
+	return files, nil
+}
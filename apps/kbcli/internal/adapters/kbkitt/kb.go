@@ -29,6 +29,7 @@ type NewKBResponse struct {
 // url patterns
 const (
 	kbURL        = "%s/kbs"
+	listKBsURL   = "%s/kbs/all"
 	getKBByIDURL = "%s/kbs/%s"
 )
 
@@ -146,6 +147,46 @@ func (c *Client) Search(ctx context.Context, filter kbs.KBQueryFilter) (*kbs.Sea
 	return &kbResponse, nil
 }
 
+// List gets a page of all server kbs sorted by key, only the limit and offset
+// of the filter are used.
+func (c *Client) List(ctx context.Context, filter kbs.KBQueryFilter) (*kbs.SearchResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.getListKBsURL(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build list kbs request: %w", err)
+	}
+
+	q := req.URL.Query()
+	q.Add(limitParam, fmt.Sprintf("%d", filter.Limit))
+	q.Add(offsetParam, fmt.Sprintf("%d", filter.Offset))
+
+	req.URL.RawQuery = q.Encode()
+
+	//nolint:gosec // Trusted domain and controlled requests
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list kbs: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response after trying to list kbs: %w", err)
+	}
+
+	if isNotSuccess(resp.StatusCode) {
+		return nil, fmt.Errorf("server failed to list kbs: %s", string(respBody))
+	}
+
+	var kbResponse kbs.SearchResult
+	err = json.Unmarshal(respBody, &kbResponse)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshall kbs response: %w", err)
+	}
+
+	return &kbResponse, nil
+}
+
 func (c *Client) Get(ctx context.Context, id string) (*kbs.KB, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.getGetKBByIDURL(id), nil)
 	if err != nil {
@@ -215,10 +256,50 @@ func (c *Client) Update(ctx context.Context, kb *kbs.KB) error {
 	return nil
 }
 
+// Delete removes the server kb with the given id. A kb that does not exist
+// is taken as already deleted.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.getGetKBByIDURL(id), nil)
+	if err != nil {
+		return fmt.Errorf("unable to create delete kb request: %w", err)
+	}
+
+	//nolint:gosec // Trusted domain and controlled requests
+	resp, err := c.client.Do(request)
+	if err != nil {
+		return kbs.NewServerErrorWithWrapper("unable to delete kb", err)
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read response after trying to delete kb: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if isServerError(resp.StatusCode) {
+		return kbs.NewServerError(fmt.Sprintf("server failed to delete kb: %s", string(respBody)))
+	}
+
+	if isClientError(resp.StatusCode) {
+		return kbs.NewClientError(fmt.Sprintf("invalid request: %s", string(respBody)))
+	}
+
+	return nil
+}
+
 func (c *Client) getKBURL() string {
 	return fmt.Sprintf(kbURL, c.host)
 }
 
+func (c *Client) getListKBsURL() string {
+	return fmt.Sprintf(listKBsURL, c.host)
+}
+
 func (c *Client) getGetKBByIDURL(id string) string {
 	return fmt.Sprintf(getKBByIDURL, c.host, id)
 }
@@ -0,0 +1,76 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+)
+
+const (
+	queryCategoriesSQL = "SELECT NAME, DESCRIPTION, REQUIRED_FIELDS, VALUE_FORMAT FROM categories ORDER BY NAME"
+	saveCategorySQL    = `INSERT INTO categories (NAME, DESCRIPTION, REQUIRED_FIELDS, VALUE_FORMAT) VALUES (?, ?, ?, ?)
+ON CONFLICT (NAME) DO UPDATE SET DESCRIPTION = excluded.DESCRIPTION, REQUIRED_FIELDS = excluded.REQUIRED_FIELDS, VALUE_FORMAT = excluded.VALUE_FORMAT`
+	registerCategorySQL = "INSERT OR IGNORE INTO categories (NAME) VALUES (?)"
+)
+
+// GetCategories gets the registered categories sorted by name.
+func (s *SQLite) GetCategories(ctx context.Context) ([]kbs.Category, error) {
+	rows, err := s.conn.QueryContext(ctx, queryCategoriesSQL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query categories: %w", err)
+	}
+
+	defer rows.Close()
+
+	result := make([]kbs.Category, 0)
+
+	for rows.Next() {
+		var category kbs.Category
+		var requiredFields, valueFormat string
+
+		err := rows.Scan(&category.Name, &category.Description, &requiredFields, &valueFormat)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read categories: %w", err)
+		}
+
+		if requiredFields != "" {
+			category.RequiredFields = strings.Fields(requiredFields)
+		}
+
+		category.ValueFormat = kbs.ValueFormat(valueFormat)
+
+		result = append(result, category)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read categories: %w", err)
+	}
+
+	return result, nil
+}
+
+// SaveCategory registers the category or replaces the rules of the one with the same name.
+func (s *SQLite) SaveCategory(ctx context.Context, category kbs.Category) error {
+	_, err := s.conn.ExecContext(ctx, saveCategorySQL,
+		category.Name, category.Description,
+		strings.Join(category.RequiredFields, aSpace), string(category.ValueFormat),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to save category %q: %w", category.Name, err)
+	}
+
+	return nil
+}
+
+// RegisterCategory registers the category without rules. A category that is
+// already registered keeps its rules.
+func (s *SQLite) RegisterCategory(ctx context.Context, name string) error {
+	_, err := s.conn.ExecContext(ctx, registerCategorySQL, name)
+	if err != nil {
+		return fmt.Errorf("unable to register category %q: %w", name, err)
+	}
+
+	return nil
+}
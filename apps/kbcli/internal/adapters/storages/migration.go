@@ -0,0 +1,250 @@
+package storages
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration describes a versioned change of the database schema.
+type Migration struct {
+	Version   int
+	Name      string
+	AppliedOn *time.Time
+	script    string
+}
+
+// MigrationStatus describes the schema version of the database.
+type MigrationStatus struct {
+	// Current is the version of the last applied migration.
+	Current int
+	// Latest is the version of the last migration known by this app.
+	Latest int
+	// Migrations contains all known migrations, applied or not.
+	Migrations []Migration
+}
+
+const (
+	migrationsDir       = "migrations"
+	migrationFileSuffix = ".sql"
+
+	createMigrationsTableSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	VERSION INTEGER PRIMARY KEY,
+	NAME VARCHAR(128) NOT NULL,
+	APPLIED_ON DATETIME DEFAULT CURRENT_TIMESTAMP
+);`
+	queryAppliedMigrationsSQL = "SELECT VERSION, APPLIED_ON FROM schema_migrations ORDER BY VERSION"
+	insertMigrationSQL        = "INSERT INTO schema_migrations (VERSION, NAME, APPLIED_ON) VALUES (?, ?, ?)"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var errInvalidMigrationName = errors.New("migration file name must follow the pattern <version>_<name>.sql")
+
+// Applied indicates if the migration was already applied to the database.
+func (m Migration) Applied() bool {
+	return m.AppliedOn != nil
+}
+
+// Pending returns the migrations that have not been applied yet.
+func (m MigrationStatus) Pending() []Migration {
+	var pending []Migration
+
+	for _, v := range m.Migrations {
+		if !v.Applied() {
+			pending = append(pending, v)
+		}
+	}
+
+	return pending
+}
+
+// UpToDate indicates if all known migrations were applied.
+func (m MigrationStatus) UpToDate() bool {
+	return m.Current >= m.Latest
+}
+
+// Migrate applies in order all migrations that have not been applied yet,
+// each one in its own transaction, and returns the ones it applied.
+func (s *SQLite) Migrate(ctx context.Context) ([]Migration, error) {
+	status, err := s.MigrationStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to migrate database: %w", err)
+	}
+
+	applied := make([]Migration, 0)
+
+	for _, migration := range status.Pending() {
+		appliedOn, err := s.applyMigration(ctx, migration)
+		if err != nil {
+			return applied, fmt.Errorf("unable to apply migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+
+		slog.Info("database migration applied",
+			slog.Int("version", migration.Version),
+			slog.String("name", migration.Name),
+		)
+
+		migration.AppliedOn = &appliedOn
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+// MigrationStatus gets the known migrations and which of them were applied.
+func (s *SQLite) MigrationStatus(ctx context.Context) (*MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load migrations: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, createMigrationsTableSQL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create migrations table: %w", err)
+	}
+
+	appliedVersions, err := s.queryAppliedMigrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get applied migrations: %w", err)
+	}
+
+	status := MigrationStatus{
+		Migrations: migrations,
+	}
+
+	for i, migration := range migrations {
+		status.Latest = migration.Version
+
+		appliedOn, ok := appliedVersions[migration.Version]
+		if !ok {
+			continue
+		}
+
+		status.Migrations[i].AppliedOn = &appliedOn
+		status.Current = migration.Version
+	}
+
+	return &status, nil
+}
+
+func (s *SQLite) applyMigration(ctx context.Context, migration Migration) (time.Time, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to start transaction: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx, migration.script)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to run script: %w", err)
+	}
+
+	appliedOn := time.Now().UTC()
+
+	_, err = tx.ExecContext(ctx, insertMigrationSQL, migration.Version, migration.Name, appliedOn)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to register migration: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to commit migration: %w", err)
+	}
+
+	return appliedOn, nil
+}
+
+func (s *SQLite) queryAppliedMigrations(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := s.db.QueryContext(ctx, queryAppliedMigrationsSQL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query applied migrations: %w", err)
+	}
+
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+
+	for rows.Next() {
+		var version int
+		var appliedOn sql.NullTime
+
+		err := rows.Scan(&version, &appliedOn)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan applied migration: %w", err)
+		}
+
+		applied[version] = appliedOn.Time
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("applied migrations query had some errors: %w", err)
+	}
+
+	return applied, nil
+}
+
+// loadMigrations reads the embedded migration scripts sorted by version.
+func loadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, migrationsDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), migrationFileSuffix) {
+			continue
+		}
+
+		migration, err := newMigration(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		script, err := fs.ReadFile(migrationFiles, path.Join(migrationsDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read migration %q: %w", entry.Name(), err)
+		}
+
+		migration.script = string(script)
+		migrations = append(migrations, migration)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+
+	return migrations, nil
+}
+
+func newMigration(fileName string) (Migration, error) {
+	rawVersion, name, ok := strings.Cut(strings.TrimSuffix(fileName, migrationFileSuffix), "_")
+	if !ok {
+		return Migration{}, fmt.Errorf("%q: %w", fileName, errInvalidMigrationName)
+	}
+
+	version, err := strconv.Atoi(rawVersion)
+	if err != nil {
+		return Migration{}, fmt.Errorf("%q: %w", fileName, errInvalidMigrationName)
+	}
+
+	return Migration{
+		Version: version,
+		Name:    name,
+	}, nil
+}
@@ -0,0 +1,109 @@
+package storages_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/adapters/storages"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// legacySchemaSQL is the schema created by kbcli before migrations existed.
+const legacySchemaSQL = `CREATE TABLE IF NOT EXISTS kbs (
+	INTERNAL_ID INTEGER PRIMARY KEY AUTOINCREMENT,
+	KB_ID VARCHAR(36) UNIQUE,
+	KB_KEY VARCHAR(64) NOT NULL UNIQUE,
+	KB_VALUE TEXT NOT NULL,
+	NOTES TEXT NOT NULL,
+	CATEGORY VARCHAR(64) NOT NULL,
+	NAMESPACE VARCHAR(64) NOT NULL,
+	TAG_VALUES VARCHAR(256) NOT NULL,
+	REFERENCE VARCHAR(64),
+	CREATED_ON DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS tags_idx USING fts5(tag_values, content='kbs', content_rowid='INTERNAL_ID');
+CREATE TRIGGER kbs_ai AFTER INSERT ON kbs BEGIN
+INSERT INTO tags_idx(rowid, tag_values) VALUES (new.INTERNAL_ID, new.TAG_VALUES);
+END;
+INSERT INTO kbs (KB_ID, KB_KEY, KB_VALUE, NOTES, CATEGORY, NAMESPACE, TAG_VALUES, REFERENCE)
+VALUES ('legacy-uuid-0001', 'legacy-key', 'legacy value', 'legacy notes', 'legacy', 'default', 'old legacy', '');`
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { db.Close() })
+
+	storage := storages.NewSQLite(&storages.SQLiteSetup{DB: db})
+	ctx := context.Background()
+
+	applied, err := storage.Migrate(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, applied)
+
+	status, err := storage.MigrationStatus(ctx)
+	require.NoError(t, err)
+	assert.True(t, status.UpToDate())
+	assert.Equal(t, status.Latest, status.Current)
+	assert.Empty(t, status.Pending())
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	applied, err := storage.Migrate(ctx)
+
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func TestMigrateLegacyDatabaseKeepsData(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+
+	_, err = db.ExecContext(ctx, legacySchemaSQL)
+	require.NoError(t, err)
+
+	storage := storages.NewSQLite(&storages.SQLiteSetup{DB: db})
+
+	err = storage.InitializeDB(ctx)
+	require.NoError(t, err)
+
+	legacyKB, err := storage.GetByKey(ctx, "legacy-key")
+	require.NoError(t, err)
+	require.NotNil(t, legacyKB)
+	assert.Equal(t, "legacy value", legacyKB.Value)
+
+	found, err := storage.Search(ctx, kbs.KBQueryFilter{Keyword: "legacy", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, found.Total)
+
+	// existing tags are backfilled.
+	tags, err := storage.GetTagCounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []kbs.TagCount{{Tag: "legacy", Count: 1}, {Tag: "old", Count: 1}}, tags)
+
+	found, err = storage.Search(ctx, kbs.KBQueryFilter{AllTags: []string{"old", "legacy"}, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, found.Total)
+
+	// categories of existing kbs are registered.
+	categories, err := storage.GetCategories(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, categories, kbs.Category{Name: "legacy", ValueFormat: kbs.ValueFormatText})
+
+	// new columns are available after the upgrade.
+	require.NoError(t, storage.Delete(ctx, legacyKB.ID))
+
+	trash, err := storage.GetTrash(ctx, kbs.KBQueryFilter{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, trash.Total)
+}
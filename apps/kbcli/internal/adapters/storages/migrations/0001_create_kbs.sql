@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS kbs (
+	INTERNAL_ID INTEGER PRIMARY KEY AUTOINCREMENT,
+	KB_ID VARCHAR(36) UNIQUE,
+	KB_KEY VARCHAR(64) NOT NULL UNIQUE,
+	KB_VALUE TEXT NOT NULL,
+	NOTES TEXT NOT NULL,
+	CATEGORY VARCHAR(64) NOT NULL,
+	NAMESPACE VARCHAR(64) NOT NULL,
+	TAG_VALUES VARCHAR(256) NOT NULL,
+	REFERENCE VARCHAR(64),
+	CREATED_ON DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS tags_idx
+USING fts5(
+	tag_values,
+	content='kbs',
+	content_rowid='INTERNAL_ID'
+);
+
+-- Triggers to keep the FTS index up to date.
+DROP TRIGGER IF EXISTS kbs_ai;
+CREATE TRIGGER kbs_ai AFTER INSERT ON kbs BEGIN
+INSERT INTO tags_idx(rowid, tag_values) VALUES (new.INTERNAL_ID, new.TAG_VALUES);
+END;
+
+DROP TRIGGER IF EXISTS kbs_ad;
+CREATE TRIGGER kbs_ad AFTER DELETE ON kbs BEGIN
+INSERT INTO tags_idx(tags_idx, rowid, tag_values) VALUES('delete', old.INTERNAL_ID, old.TAG_VALUES);
+END;
+
+DROP TRIGGER IF EXISTS kbs_au;
+CREATE TRIGGER kbs_au AFTER UPDATE ON kbs BEGIN
+INSERT INTO tags_idx(tags_idx, rowid, tag_values) VALUES('delete', old.INTERNAL_ID, old.TAG_VALUES);
+INSERT INTO tags_idx(rowid, tag_values) VALUES (new.INTERNAL_ID, new.TAG_VALUES);
+END;
@@ -0,0 +1,2 @@
+-- Soft deleted kbs are kept in the trash until they are purged.
+ALTER TABLE kbs ADD COLUMN DELETED_ON DATETIME;
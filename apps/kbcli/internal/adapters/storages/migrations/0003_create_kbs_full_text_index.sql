@@ -0,0 +1,38 @@
+-- Replace the tags only index with a full text index over key, value, notes,
+-- reference and tags.
+DROP TRIGGER IF EXISTS kbs_ai;
+DROP TRIGGER IF EXISTS kbs_ad;
+DROP TRIGGER IF EXISTS kbs_au;
+DROP TABLE IF EXISTS tags_idx;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS kbs_idx
+USING fts5(
+	kb_key,
+	kb_value,
+	notes,
+	reference,
+	tag_values,
+	content='kbs',
+	content_rowid='INTERNAL_ID'
+);
+
+-- Triggers to keep the FTS index up to date.
+CREATE TRIGGER kbs_ai AFTER INSERT ON kbs BEGIN
+INSERT INTO kbs_idx(rowid, kb_key, kb_value, notes, reference, tag_values)
+VALUES (new.INTERNAL_ID, new.KB_KEY, new.KB_VALUE, new.NOTES, new.REFERENCE, new.TAG_VALUES);
+END;
+
+CREATE TRIGGER kbs_ad AFTER DELETE ON kbs BEGIN
+INSERT INTO kbs_idx(kbs_idx, rowid, kb_key, kb_value, notes, reference, tag_values)
+VALUES ('delete', old.INTERNAL_ID, old.KB_KEY, old.KB_VALUE, old.NOTES, old.REFERENCE, old.TAG_VALUES);
+END;
+
+CREATE TRIGGER kbs_au AFTER UPDATE ON kbs BEGIN
+INSERT INTO kbs_idx(kbs_idx, rowid, kb_key, kb_value, notes, reference, tag_values)
+VALUES ('delete', old.INTERNAL_ID, old.KB_KEY, old.KB_VALUE, old.NOTES, old.REFERENCE, old.TAG_VALUES);
+INSERT INTO kbs_idx(rowid, kb_key, kb_value, notes, reference, tag_values)
+VALUES (new.INTERNAL_ID, new.KB_KEY, new.KB_VALUE, new.NOTES, new.REFERENCE, new.TAG_VALUES);
+END;
+
+-- Index the kbs that already exist.
+INSERT INTO kbs_idx(kbs_idx) VALUES ('rebuild');
@@ -0,0 +1,8 @@
+-- Tracks which kbs must be pushed to the server and which remote kb they map to.
+-- SYNC_STATE is one of: new, modified or synced.
+ALTER TABLE kbs ADD COLUMN SYNC_STATE TEXT NOT NULL DEFAULT 'new';
+ALTER TABLE kbs ADD COLUMN REMOTE_ID TEXT;
+ALTER TABLE kbs ADD COLUMN LAST_SYNCED_ON DATETIME;
+
+CREATE UNIQUE INDEX IF NOT EXISTS kbs_remote_id_idx ON kbs(REMOTE_ID);
+CREATE INDEX IF NOT EXISTS kbs_sync_state_idx ON kbs(SYNC_STATE);
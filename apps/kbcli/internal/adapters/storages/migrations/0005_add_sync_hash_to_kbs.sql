@@ -0,0 +1,3 @@
+-- Content hash of the kb the last time it was synced, it is the common ancestor
+-- used to know if a kb was changed on the server since then.
+ALTER TABLE kbs ADD COLUMN SYNC_HASH TEXT;
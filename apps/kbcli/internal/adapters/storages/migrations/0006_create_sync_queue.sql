@@ -0,0 +1,11 @@
+-- Outbox of kbs that must be sent to the server once it is reachable. Entries
+-- are removed only after the server accepted them.
+CREATE TABLE IF NOT EXISTS sync_queue (
+	ENTRY_ID INTEGER PRIMARY KEY AUTOINCREMENT,
+	KB_KEY VARCHAR(128) NOT NULL,
+	PAYLOAD TEXT NOT NULL,
+	ATTEMPTS INTEGER NOT NULL DEFAULT 0,
+	LAST_ERROR TEXT,
+	NEXT_ATTEMPT_ON DATETIME NOT NULL,
+	CREATED_ON DATETIME NOT NULL
+);
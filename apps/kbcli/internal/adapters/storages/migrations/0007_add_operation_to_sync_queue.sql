@@ -0,0 +1,4 @@
+-- Sync queue entries are typed operations. Entries queued by previous
+-- versions are creations.
+ALTER TABLE sync_queue ADD COLUMN OPERATION TEXT NOT NULL DEFAULT 'create';
+ALTER TABLE sync_queue ADD COLUMN REMOTE_ID TEXT;
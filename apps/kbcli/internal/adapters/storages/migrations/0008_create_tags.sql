@@ -0,0 +1,42 @@
+-- Tags of each kb. TAG_VALUES is kept as the space separated copy that feeds
+-- the full text index, while kb_tags is used to filter and count by tag.
+CREATE TABLE IF NOT EXISTS tags (
+	TAG_ID INTEGER PRIMARY KEY AUTOINCREMENT,
+	NAME VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS kb_tags (
+	KB_INTERNAL_ID INTEGER NOT NULL REFERENCES kbs(INTERNAL_ID) ON DELETE CASCADE,
+	TAG_ID INTEGER NOT NULL REFERENCES tags(TAG_ID) ON DELETE CASCADE,
+	PRIMARY KEY (KB_INTERNAL_ID, TAG_ID)
+);
+
+CREATE INDEX IF NOT EXISTS kb_tags_tag_id ON kb_tags(TAG_ID);
+
+-- Foreign keys are not enforced by default, so relations of purged kbs are
+-- removed by a trigger, and so are the tags no kb has anymore.
+CREATE TRIGGER kbs_tags_ad AFTER DELETE ON kbs BEGIN
+DELETE FROM kb_tags WHERE KB_INTERNAL_ID = old.INTERNAL_ID;
+END;
+
+CREATE TRIGGER kb_tags_ad AFTER DELETE ON kb_tags BEGIN
+DELETE FROM tags WHERE TAG_ID = old.TAG_ID AND NOT EXISTS (SELECT 1 FROM kb_tags WHERE TAG_ID = old.TAG_ID);
+END;
+
+-- Split the tags of the kbs that already exist.
+CREATE TEMP TABLE split_tags AS
+WITH RECURSIVE split(KB_INTERNAL_ID, NAME, REST) AS (
+	SELECT INTERNAL_ID, '', TAG_VALUES || ' ' FROM kbs
+	UNION ALL
+	SELECT KB_INTERNAL_ID, substr(REST, 1, instr(REST, ' ') - 1), substr(REST, instr(REST, ' ') + 1)
+	FROM split
+	WHERE REST <> ''
+)
+SELECT DISTINCT KB_INTERNAL_ID, NAME FROM split WHERE NAME <> '';
+
+INSERT OR IGNORE INTO tags (NAME) SELECT DISTINCT NAME FROM split_tags ORDER BY NAME;
+
+INSERT OR IGNORE INTO kb_tags (KB_INTERNAL_ID, TAG_ID)
+SELECT s.KB_INTERNAL_ID, t.TAG_ID FROM split_tags s JOIN tags t ON (t.NAME = s.NAME);
+
+DROP TABLE split_tags;
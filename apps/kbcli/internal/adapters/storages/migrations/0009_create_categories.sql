@@ -0,0 +1,16 @@
+-- Categories kbs can have, with the fields each one requires. REQUIRED_FIELDS
+-- are space separated field names, e.g. 'notes reference'.
+CREATE TABLE IF NOT EXISTS categories (
+	NAME VARCHAR(64) PRIMARY KEY,
+	DESCRIPTION VARCHAR(256) NOT NULL DEFAULT '',
+	REQUIRED_FIELDS VARCHAR(128) NOT NULL DEFAULT '',
+	VALUE_FORMAT VARCHAR(16) NOT NULL DEFAULT 'text'
+);
+
+INSERT OR IGNORE INTO categories (NAME, DESCRIPTION, REQUIRED_FIELDS, VALUE_FORMAT) VALUES
+	('bookmark', 'links to web pages', '', 'url'),
+	('media', 'images, videos and documents that can be saved locally', '', 'media'),
+	('quote', 'quotes of an author', 'reference', 'text');
+
+-- Register the categories of the kbs that already exist, without rules.
+INSERT OR IGNORE INTO categories (NAME) SELECT DISTINCT CATEGORY FROM kbs WHERE CATEGORY <> '' ORDER BY CATEGORY;
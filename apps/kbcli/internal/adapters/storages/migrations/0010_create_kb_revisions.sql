@@ -0,0 +1,23 @@
+-- Content each kb had before every update, so it can be compared and restored.
+-- REVISION is the number of the revision in the history of its kb, starting at 1.
+CREATE TABLE IF NOT EXISTS kb_revisions (
+	REVISION_ID INTEGER PRIMARY KEY AUTOINCREMENT,
+	KB_INTERNAL_ID INTEGER NOT NULL REFERENCES kbs(INTERNAL_ID) ON DELETE CASCADE,
+	REVISION INTEGER NOT NULL,
+	KB_KEY VARCHAR(64) NOT NULL,
+	KB_VALUE TEXT NOT NULL,
+	NOTES TEXT NOT NULL,
+	CATEGORY VARCHAR(64) NOT NULL,
+	NAMESPACE VARCHAR(64) NOT NULL,
+	TAG_VALUES VARCHAR(256) NOT NULL,
+	REFERENCE VARCHAR(64),
+	CHANGED_FIELDS VARCHAR(128) NOT NULL,
+	CREATED_ON DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (KB_INTERNAL_ID, REVISION)
+);
+
+-- Foreign keys are not enforced by default, so revisions of purged kbs are
+-- removed by a trigger.
+CREATE TRIGGER kbs_revisions_ad AFTER DELETE ON kbs BEGIN
+DELETE FROM kb_revisions WHERE KB_INTERNAL_ID = old.INTERNAL_ID;
+END;
@@ -0,0 +1,5 @@
+-- Last time the content of the kb changed, and how often and when it was last
+-- opened, so kbs can be sorted by recent or frequent use.
+ALTER TABLE kbs ADD COLUMN UPDATED_ON DATETIME;
+ALTER TABLE kbs ADD COLUMN LAST_ACCESSED_ON DATETIME;
+ALTER TABLE kbs ADD COLUMN ACCESS_COUNT INTEGER NOT NULL DEFAULT 0;
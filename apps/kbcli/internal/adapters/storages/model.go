@@ -1,6 +1,7 @@
 package storages
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
@@ -20,6 +21,18 @@ type kb struct {
 	Namespace   string
 	Tags        string
 	DateCreated time.Time
+	DateDeleted sql.NullTime
+	SyncState   string
+	RemoteID    sql.NullString
+	// DateLastSynced is the last time the kb was pushed to or pulled from the server.
+	DateLastSynced sql.NullTime
+	SyncHash       sql.NullString
+	DateUpdated    sql.NullTime
+	// DateLastAccessed is the last time the kb was opened.
+	DateLastAccessed sql.NullTime
+	AccessCount      int
+	// sortValues are the values the kb is sorted by, read only for cursors.
+	sortValues []any
 }
 
 type kbItem struct {
@@ -28,59 +41,127 @@ type kbItem struct {
 	Category  string
 	Namespace string
 	Tags      string
+	Snippet   string
 }
 
+// recordState defines if a query looks for active or soft deleted kbs.
+type recordState int
+
 type filterBuilder struct {
 	query          string
 	countStatement string
 	filters        []string
 	queryArgs      []any
 	countArgs      []any
+	// sortValues is the number of sort values the query selects after the kb columns.
+	sortValues int
 }
 
 const (
 	aSpace = " "
 
-	equalsOperator = "="
-	whereOperator  = "WHERE"
-	andOperator    = "AND"
-	likeOperator   = "LIKE"
-	matchOperator  = "MATCH"
-	limitOperator  = "LIMIT"
-	offsetOperator = "OFFSET"
+	equalsOperator      = "="
+	greaterThanOperator = ">"
+	lessThanOperator    = "<"
+	isNullOperator      = "IS NULL"
+	isNotNullOperator   = "IS NOT NULL"
+	whereOperator       = "WHERE"
+	andOperator         = "AND"
+	likeOperator        = "LIKE"
+	inOperator          = "IN"
+	matchOperator       = "MATCH"
+	limitOperator       = "LIMIT"
+	offsetOperator      = "OFFSET"
 )
 
 // user columns.
 const (
-	keyColumn              = "k.KB_KEY"
-	categoryColumn         = "k.CATEGORY"
-	namespaceColumn        = "k.NAMESPACE"
-	tagValuesVirtualColumn = "t.tag_values"
-	rowIDVirtualColum      = "t.rowid"
-	internalIDColumn       = "k.INTERNAL_ID"
+	keyColumn             = "k.KB_KEY"
+	categoryColumn        = "k.CATEGORY"
+	namespaceColumn       = "k.NAMESPACE"
+	fullTextVirtualColumn = "t.kbs_idx"
+	tagValuesIndexColumn  = "tag_values"
+	internalIDColumn      = "k.INTERNAL_ID"
+	deletedOnColumn       = "k.DELETED_ON"
+	accessCountColumn     = "k.ACCESS_COUNT"
+	lastAccessedOnColumn  = "k.LAST_ACCESSED_ON"
+)
+
+// sortExpressions are the expressions kbs are sorted by for every sort field,
+// before the internal id that breaks the ties.
+var sortExpressions = map[kbs.SortBy][]string{
+	kbs.SortByRelevance: {relevanceRankSQL},
+	// kbs are created in internal id order.
+	kbs.SortByCreated:      nil,
+	kbs.SortByAlphabetical: {keyColumn},
+	kbs.SortByRecent:       {lastUsedOnSQL},
+	// kbs never opened sort first, as nulls do, but they can be compared in a cursor.
+	kbs.SortByMostUsed: {accessCountColumn, "COALESCE(" + lastAccessedOnColumn + ", '')"},
+}
+
+// record states a query can look for.
+const (
+	activeRecords recordState = iota
+	deletedRecords
 )
 
 // errors
 var (
 	errUnableToSearchKBS = errors.New("unable to search kbs")
 	errUnableToGetAllKBS = errors.New("unable to get all kbs")
+	errUnableToGetTrash  = errors.New("unable to get kbs in the trash")
+	errInvalidCursor     = errors.New("invalid cursor, it must be the next cursor of a previous page")
 )
 
 func (k kb) toKB() *kbs.KB {
 	newKB := kbs.KB{
-		ID:        k.KeyID,
-		Key:       k.Key,
-		Value:     k.Value,
-		Notes:     k.Notes,
-		Category:  k.Category,
-		Namespace: k.Namespace,
-		Reference: k.Reference,
-		Tags:      strings.Split(k.Tags, aSpace),
+		ID:          k.KeyID,
+		Key:         k.Key,
+		Value:       k.Value,
+		Notes:       k.Notes,
+		Category:    k.Category,
+		Namespace:   k.Namespace,
+		Reference:   k.Reference,
+		Tags:        strings.Split(k.Tags, aSpace),
+		SyncState:   kbs.SyncState(k.SyncState),
+		RemoteID:    k.RemoteID.String,
+		SyncHash:    k.SyncHash.String,
+		CreatedOn:   k.DateCreated,
+		AccessCount: k.AccessCount,
+	}
+
+	if k.DateDeleted.Valid {
+		deletedOn := k.DateDeleted.Time
+		newKB.DeletedOn = &deletedOn
+	}
+
+	if k.DateLastSynced.Valid {
+		lastSyncedOn := k.DateLastSynced.Time
+		newKB.LastSyncedOn = &lastSyncedOn
+	}
+
+	if k.DateUpdated.Valid {
+		updatedOn := k.DateUpdated.Time
+		newKB.UpdatedOn = &updatedOn
+	}
+
+	if k.DateLastAccessed.Valid {
+		lastAccessedOn := k.DateLastAccessed.Time
+		newKB.LastAccessedOn = &lastAccessedOn
 	}
 
 	return &newKB
 }
 
+// fields returns the destinations to scan a row selected with kbColumnsSQL.
+func (k *kb) fields() []any {
+	return []any{
+		&k.InternalID, &k.KeyID, &k.Key, &k.Value, &k.Notes, &k.Namespace, &k.Category,
+		&k.Tags, &k.Reference, &k.DateCreated, &k.DateDeleted, &k.SyncState, &k.RemoteID,
+		&k.DateLastSynced, &k.SyncHash, &k.DateUpdated, &k.DateLastAccessed, &k.AccessCount,
+	}
+}
+
 func (f *filterBuilder) addCondition(field, operator string, value any) *filterBuilder {
 	isHint := false
 	condition := whereOperator
@@ -94,8 +175,75 @@ func (f *filterBuilder) addCondition(field, operator string, value any) *filterB
 	return f.addFilter(newStatement, value, isHint)
 }
 
+// addOrder adds an order by clause, it must be called after all conditions were added.
+func (f *filterBuilder) addOrder(orderBy string) *filterBuilder {
+	f.filters = append(f.filters, aSpace+orderBy)
+
+	return f
+}
+
+// addStateCondition adds a condition that does not require any argument, e.g. IS NULL.
+func (f *filterBuilder) addStateCondition(field, operator string) *filterBuilder {
+	condition := whereOperator
+
+	if len(f.filters) > 0 {
+		condition = " " + andOperator
+	}
+
+	f.filters = append(f.filters, fmt.Sprintf("%s %s %s", condition, field, operator))
+
+	return f
+}
+
+// addSubqueryCondition adds a condition on the kb internal id being in the subquery,
+// which refers to its only argument with %[1]s.
+func (f *filterBuilder) addSubqueryCondition(subquery string, value any) *filterBuilder {
+	return f.addExpressionCondition(fmt.Sprintf("%s %s (%s)", internalIDColumn, inOperator, subquery), value)
+}
+
+// addExpressionCondition adds a condition that refers to its only argument with %[1]s,
+// so the argument can be used more than once.
+func (f *filterBuilder) addExpressionCondition(expression string, value any) *filterBuilder {
+	condition := whereOperator
+
+	if len(f.filters) > 0 {
+		condition = " " + andOperator
+	}
+
+	placeholder := fmt.Sprintf("$%d", len(f.queryArgs)+1)
+
+	f.filters = append(f.filters, fmt.Sprintf("%s %s", condition, fmt.Sprintf(expression, placeholder)))
+	f.countArgs = append(f.countArgs, value)
+	f.queryArgs = append(f.queryArgs, value)
+
+	return f
+}
+
+// addKeysetCondition adds a condition on the given expressions being after the
+// given values in the operator order, e.g. (k.KB_KEY, k.INTERNAL_ID) > ($1, $2).
+func (f *filterBuilder) addKeysetCondition(expressions []string, operator string, values []any) *filterBuilder {
+	condition := whereOperator
+
+	if len(f.filters) > 0 {
+		condition = " " + andOperator
+	}
+
+	placeholders := make([]string, 0, len(values))
+
+	for _, value := range values {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(f.queryArgs)+1))
+		f.countArgs = append(f.countArgs, value)
+		f.queryArgs = append(f.queryArgs, value)
+	}
+
+	f.filters = append(f.filters, fmt.Sprintf("%s (%s) %s (%s)",
+		condition, strings.Join(expressions, ", "), operator, strings.Join(placeholders, ", ")))
+
+	return f
+}
+
 func (f *filterBuilder) addFilter(statement string, value any, isHint bool) *filterBuilder {
-	index := len(f.filters) + 1
+	index := len(f.queryArgs) + 1
 
 	statement = fmt.Sprintf("%s $%d", statement, index)
 
@@ -111,7 +259,7 @@ func (f *filterBuilder) addFilter(statement string, value any, isHint bool) *fil
 }
 
 func toDBKB(akb *kbs.KB) kb {
-	return kb{
+	newKB := kb{
 		KeyID:       akb.ID,
 		Key:         akb.Key,
 		Value:       akb.Value,
@@ -121,7 +269,26 @@ func toDBKB(akb *kbs.KB) kb {
 		Namespace:   akb.Namespace,
 		Tags:        strings.Join(akb.Tags, aSpace),
 		DateCreated: time.Now().UTC(),
+		SyncState:   string(akb.SyncState),
+		RemoteID: sql.NullString{
+			String: akb.RemoteID,
+			Valid:  akb.RemoteID != "",
+		},
+		SyncHash: sql.NullString{
+			String: akb.SyncHash,
+			Valid:  akb.SyncHash != "",
+		},
+	}
+
+	if newKB.SyncState == "" {
+		newKB.SyncState = string(kbs.SyncStateNew)
+	}
+
+	if akb.LastSyncedOn != nil {
+		newKB.DateLastSynced = sql.NullTime{Time: *akb.LastSyncedOn, Valid: true}
 	}
+
+	return newKB
 }
 
 func (k kbItem) toKBItem() kbs.KBItem {
@@ -131,6 +298,7 @@ func (k kbItem) toKBItem() kbs.KBItem {
 		Category:  k.Category,
 		Namespace: k.Namespace,
 		Tags:      strings.Split(k.Tags, aSpace),
+		Snippet:   k.Snippet,
 	}
 }
 
@@ -0,0 +1,66 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+)
+
+const (
+	queryNamespaceCountsSQL = "SELECT NAMESPACE, COUNT(*) FROM kbs WHERE DELETED_ON IS NULL GROUP BY NAMESPACE ORDER BY NAMESPACE"
+	// the namespace or any of its descendants, whose paths start with the namespace and a separator.
+	namespaceTreeConditionSQL = "(" + namespaceColumn + " = %[1]s OR substr(" + namespaceColumn + ", 1, length(%[1]s) + 1) = %[1]s || '/')"
+	// changing the namespace of a synced kb means it has local changes that must be pushed.
+	moveNamespaceSQL = `UPDATE kbs
+SET NAMESPACE = ?2 || substr(NAMESPACE, length(?1) + 1), UPDATED_ON = ?3,
+	SYNC_STATE = CASE SYNC_STATE WHEN 'synced' THEN 'modified' ELSE SYNC_STATE END
+WHERE (NAMESPACE = ?1 OR substr(NAMESPACE, 1, length(?1) + 1) = ?1 || '/') AND DELETED_ON IS NULL`
+)
+
+// GetNamespaceCounts gets every namespace of active kbs with the number of kbs in it, sorted by namespace.
+func (s *SQLite) GetNamespaceCounts(ctx context.Context) ([]kbs.NamespaceCount, error) {
+	rows, err := s.conn.QueryContext(ctx, queryNamespaceCountsSQL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query namespaces: %w", err)
+	}
+
+	defer rows.Close()
+
+	result := make([]kbs.NamespaceCount, 0)
+
+	for rows.Next() {
+		var namespaceCount kbs.NamespaceCount
+
+		err := rows.Scan(&namespaceCount.Namespace, &namespaceCount.Count)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read namespaces: %w", err)
+		}
+
+		result = append(result, namespaceCount)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read namespaces: %w", err)
+	}
+
+	return result, nil
+}
+
+// MoveNamespace replaces the namespace with the new one in every kb in it or in
+// any of its descendants. Kbs in the trash are left as they are. It returns the
+// number of kbs that were changed.
+func (s *SQLite) MoveNamespace(ctx context.Context, namespace, newNamespace string) (int64, error) {
+	result, err := s.conn.ExecContext(ctx, moveNamespaceSQL, namespace, newNamespace, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("unable to move namespace: %w", err)
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("unable to get number of moved kbs: %w", err)
+	}
+
+	return changed, nil
+}
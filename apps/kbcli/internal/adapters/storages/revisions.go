@@ -0,0 +1,89 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+)
+
+const (
+	queryAnyKBByIDSQL = "SELECT " + kbColumnsSQL + " FROM kbs k WHERE k.KB_ID = ?"
+	// copies the current content of the kb as its next revision.
+	insertRevisionSQL = `INSERT INTO kb_revisions
+	(KB_INTERNAL_ID, REVISION, KB_KEY, KB_VALUE, NOTES, CATEGORY, NAMESPACE, TAG_VALUES, REFERENCE, CHANGED_FIELDS, CREATED_ON)
+SELECT k.INTERNAL_ID, (SELECT COALESCE(MAX(r.REVISION), 0) + 1 FROM kb_revisions r WHERE r.KB_INTERNAL_ID = k.INTERNAL_ID),
+	k.KB_KEY, k.KB_VALUE, k.NOTES, k.CATEGORY, k.NAMESPACE, k.TAG_VALUES, COALESCE(k.REFERENCE, ''), ?, ?
+FROM kbs k WHERE k.KB_ID = ?`
+	queryRevisionsSQL = `SELECT r.REVISION, r.KB_KEY, r.KB_VALUE, r.NOTES, r.CATEGORY, r.NAMESPACE, r.TAG_VALUES, COALESCE(r.REFERENCE, ''),
+	r.CHANGED_FIELDS, r.CREATED_ON
+FROM kb_revisions r JOIN kbs k ON (k.INTERNAL_ID = r.KB_INTERNAL_ID)
+WHERE k.KB_ID = ?
+ORDER BY r.REVISION`
+)
+
+// GetRevisions gets the revisions recorded by the updates of the kb, the oldest first.
+func (s *SQLite) GetRevisions(ctx context.Context, id string) ([]kbs.Revision, error) {
+	rows, err := s.conn.QueryContext(ctx, queryRevisionsSQL, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query revisions of kb %q: %w", id, err)
+	}
+
+	defer rows.Close()
+
+	result := make([]kbs.Revision, 0)
+
+	for rows.Next() {
+		var revision kbs.Revision
+		var tags, changedFields string
+
+		err := rows.Scan(
+			&revision.Number, &revision.KB.Key, &revision.KB.Value, &revision.KB.Notes,
+			&revision.KB.Category, &revision.KB.Namespace, &tags, &revision.KB.Reference,
+			&changedFields, &revision.CreatedOn,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read revisions of kb %q: %w", id, err)
+		}
+
+		revision.KB.ID = id
+		revision.KB.Tags = strings.Split(tags, aSpace)
+		revision.ChangedFields = strings.Fields(changedFields)
+
+		result = append(result, revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read revisions of kb %q: %w", id, err)
+	}
+
+	return result, nil
+}
+
+// saveRevision keeps the current content of the kb as a revision if the update
+// changes any of its fields, and indicates if it did. It must run in a
+// transaction, before the update.
+func (s *SQLite) saveRevision(ctx context.Context, kb *kbs.KB) (bool, error) {
+	current, err := s.getKBRecord(ctx, queryAnyKBByIDSQL, kb.ID)
+	if err != nil {
+		return false, fmt.Errorf("unable to save revision of kb %q: %w", kb.ID, err)
+	}
+
+	if current == nil {
+		return false, nil
+	}
+
+	changedFields := current.ChangedFields(*kb)
+	if len(changedFields) == 0 {
+		return false, nil
+	}
+
+	_, err = s.conn.ExecContext(ctx, insertRevisionSQL, strings.Join(changedFields, aSpace), time.Now().UTC(), kb.ID)
+	if err != nil {
+		return false, fmt.Errorf("unable to save revision of kb %q: %w", kb.ID, err)
+	}
+
+	return true, nil
+}
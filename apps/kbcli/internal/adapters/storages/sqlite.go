@@ -3,10 +3,12 @@ package storages
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"log"
 	"log/slog"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
 
@@ -22,78 +24,75 @@ type SQLiteSetup struct {
 // SQLite implements logic to store data into sqlite repository.
 type SQLite struct {
 	db *sql.DB
+	// conn runs the statements. It is the transaction of storages created by WithTransaction.
+	conn querier
+	tx   *sql.Tx
+	// stmts prepared statements reused until the transaction ends.
+	stmts map[string]*sql.Stmt
+}
+
+// querier is implemented by sql.DB and sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
 }
 
 const (
 	sqliteVersion = "sqlite3"
 
 	createKBSQL = `INSERT INTO kbs
-	(KB_ID, KB_KEY, KB_VALUE, NOTES, CATEGORY, TAG_VALUES, REFERENCE, NAMESPACE, CREATED_ON)
+	(KB_ID, KB_KEY, KB_VALUE, NOTES, CATEGORY, TAG_VALUES, REFERENCE, NAMESPACE, CREATED_ON, SYNC_STATE, REMOTE_ID, LAST_SYNCED_ON, SYNC_HASH)
 VALUES
-	(?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
+	// updating a synced kb means it has local changes that must be pushed.
 	updateKBSQL = `UPDATE kbs
-SET KB_KEY = ?, KB_VALUE = ?, NOTES = ?, CATEGORY = ?, TAG_VALUES = ?, REFERENCE = ?, NAMESPACE = ?
+SET KB_KEY = ?, KB_VALUE = ?, NOTES = ?, CATEGORY = ?, TAG_VALUES = ?, REFERENCE = ?, NAMESPACE = ?,
+	UPDATED_ON = COALESCE(?, UPDATED_ON),
+	SYNC_STATE = CASE SYNC_STATE WHEN 'synced' THEN 'modified' ELSE SYNC_STATE END
 WHERE KB_ID = ?`
 
-	queryAKBByIDSQL            = "SELECT INTERNAL_ID, KB_ID, KB_KEY, KB_VALUE, NOTES, NAMESPACE, CATEGORY, TAG_VALUES, REFERENCE, CREATED_ON FROM kbs WHERE KB_ID = ?"
-	queryAKBByKeySQL           = "SELECT INTERNAL_ID, KB_ID, KB_KEY, KB_VALUE, NOTES, NAMESPACE, CATEGORY, TAG_VALUES, REFERENCE, CREATED_ON FROM kbs WHERE KB_KEY = ?"
-	queryKBsByFilterSQL        = "SELECT k.KB_ID, k.KB_KEY, k.CATEGORY, k.NAMESPACE, k.TAG_VALUES FROM kbs k %s;"
-	queryKBsByFilterAndTagsSQL = "SELECT k.KB_ID, k.KB_KEY, k.CATEGORY, k.NAMESPACE, k.TAG_VALUES FROM kbs k JOIN tags_idx t ON (t.rowid = k.INTERNAL_ID) %s;"
-	countKBsByCategorySQL      = "SELECT COUNT(k.KB_ID) FROM kbs k WHERE k.CATEGORY = ?"
-	countKBsByFilterSQL        = "SELECT COUNT(k.KB_ID) FROM kbs k %s;"
-	countKBsByFilterAndTagsSQL = "SELECT COUNT(k.KB_ID) FROM kbs k JOIN tags_idx t ON (t.rowid = k.INTERNAL_ID) %s;"
+	markKBAsSyncedSQL = "UPDATE kbs SET SYNC_STATE = 'synced', REMOTE_ID = ?, SYNC_HASH = ?, LAST_SYNCED_ON = ? WHERE KB_ID = ?"
+	// a kb whose server kb was deleted is created again on the server if it is restored.
+	markKBAsDeletedOnServerSQL = "UPDATE kbs SET SYNC_STATE = 'new', REMOTE_ID = NULL, SYNC_HASH = NULL, LAST_SYNCED_ON = NULL WHERE REMOTE_ID = ?"
+
+	markKBAsAccessedSQL = "UPDATE kbs SET ACCESS_COUNT = ACCESS_COUNT + 1, LAST_ACCESSED_ON = ? WHERE KB_ID = ? AND DELETED_ON IS NULL"
+
+	softDeleteKBSQL = "UPDATE kbs SET DELETED_ON = ? WHERE KB_ID = ? AND DELETED_ON IS NULL"
+	restoreKBSQL    = "UPDATE kbs SET DELETED_ON = NULL WHERE KB_ID = ? AND DELETED_ON IS NOT NULL"
+	purgeKBSQL      = "DELETE FROM kbs WHERE KB_ID = ? AND DELETED_ON IS NOT NULL"
+	emptyTrashSQL   = "DELETE FROM kbs WHERE DELETED_ON IS NOT NULL"
+
+	kbColumnsSQL = "k.INTERNAL_ID, k.KB_ID, k.KB_KEY, k.KB_VALUE, k.NOTES, k.NAMESPACE, k.CATEGORY, k.TAG_VALUES, k.REFERENCE, k.CREATED_ON, k.DELETED_ON, " +
+		"k.SYNC_STATE, k.REMOTE_ID, k.LAST_SYNCED_ON, k.SYNC_HASH, k.UPDATED_ON, k.LAST_ACCESSED_ON, k.ACCESS_COUNT"
+
+	queryAKBByIDSQL             = "SELECT " + kbColumnsSQL + " FROM kbs k WHERE k.KB_ID = ? AND k.DELETED_ON IS NULL"
+	queryAKBByKeySQL            = "SELECT " + kbColumnsSQL + " FROM kbs k WHERE k.KB_KEY = ? AND k.DELETED_ON IS NULL"
+	queryATrashedKBByIDSQL      = "SELECT " + kbColumnsSQL + " FROM kbs k WHERE k.KB_ID = ? AND k.DELETED_ON IS NOT NULL"
+	queryATrashedKBByKeySQL     = "SELECT " + kbColumnsSQL + " FROM kbs k WHERE k.KB_KEY = ? AND k.DELETED_ON IS NOT NULL"
+	queryKeysSQL                = "SELECT k.KB_KEY FROM kbs k WHERE k.DELETED_ON IS NULL ORDER BY k.KB_KEY"
+	queryAKBByRemoteIDSQL       = "SELECT " + kbColumnsSQL + " FROM kbs k WHERE k.REMOTE_ID = ?"
+	queryKBsBySyncStateSQL      = "SELECT " + kbColumnsSQL + " FROM kbs k WHERE k.SYNC_STATE = ? AND k.DELETED_ON IS NULL ORDER BY k.INTERNAL_ID"
+	queryKBsByFilterSQL         = "SELECT k.KB_ID, k.KB_KEY, k.CATEGORY, k.NAMESPACE, k.TAG_VALUES, '' FROM kbs k %s;"
+	queryKBsByFilterAndMatchSQL = "SELECT k.KB_ID, k.KB_KEY, k.CATEGORY, k.NAMESPACE, k.TAG_VALUES, snippet(kbs_idx, -1, '[', ']', '...', 8) FROM kbs k JOIN kbs_idx t ON (t.rowid = k.INTERNAL_ID) %s;"
+	countKBsByCategorySQL       = "SELECT COUNT(k.KB_ID) FROM kbs k WHERE k.CATEGORY = ? AND k.DELETED_ON IS NULL"
+	countKBsByFilterSQL         = "SELECT COUNT(k.KB_ID) FROM kbs k %s;"
+	countKBsByFilterAndMatchSQL = "SELECT COUNT(k.KB_ID) FROM kbs k JOIN kbs_idx t ON (t.rowid = k.INTERNAL_ID) %s;"
+	// lower ranks are better matches.
+	relevanceRankSQL = "bm25(kbs_idx, 10.0, 2.0, 1.0, 1.0, 5.0)"
+	// the last time a kb was opened or changed, or its creation if it was neither.
+	lastUsedOnSQL = "MAX(COALESCE(k.LAST_ACCESSED_ON, k.CREATED_ON), COALESCE(k.UPDATED_ON, k.CREATED_ON))"
 
 	countKBsSQL = "SELECT COUNT(k.KB_ID) FROM kbs k %s;"
-	queryKBsSQL = `SELECT k.INTERNAL_ID, k.KB_ID, k.KB_KEY, k.KB_VALUE, k.NOTES, k.NAMESPACE, k.CATEGORY, k.TAG_VALUES, k.REFERENCE, k.CREATED_ON 
-FROM kbs k %s`
-
-	createKBTableSQL = `DROP TABLE IF EXISTS kbs;
-CREATE TABLE IF NOT EXISTS kbs (
-	INTERNAL_ID INTEGER PRIMARY KEY AUTOINCREMENT,
-	KB_ID VARCHAR(36) UNIQUE,
-	KB_KEY VARCHAR(64) NOT NULL UNIQUE,
-	KB_VALUE TEXT NOT NULL,
-	NOTES TEXT NOT NULL,
-	CATEGORY VARCHAR(64) NOT NULL,
-	NAMESPACE VARCHAR(64) NOT NULL,
-	TAG_VALUES VARCHAR(256) NOT NULL,
-	REFERENCE VARCHAR(64),
-	CREATED_ON DATETIME DEFAULT CURRENT_TIMESTAMP
-);
-`
-
-	createFTSKBTableSQL = `DROP TABLE IF EXISTS tags_idx;
-CREATE VIRTUAL TABLE IF NOT EXISTS tags_idx 
-USING fts5(
-	tag_values,
-	content='kbs',
-	content_rowid='INTERNAL_ID'
-);
-`
-
-	createTriggerAfterInsertSQL = `DROP TRIGGER IF EXISTS kbs_ai;
-CREATE TRIGGER kbs_ai AFTER INSERT ON kbs BEGIN
-INSERT INTO tags_idx(rowid, tag_values) VALUES (new.INTERNAL_ID, new.TAG_VALUES);
-END;
-`
-
-	createTriggerAfterDeleteSQL = `DROP TRIGGER IF EXISTS kbs_ad;
-CREATE TRIGGER kbs_ad AFTER DELETE ON kbs BEGIN
-DELETE FROM tags_idx WHERE rowid = old.INTERNAL_ID;
-END;
-`
-
-	createTriggerAfterUpdateSQL = `DROP TRIGGER IF EXISTS kbs_au;
-CREATE TRIGGER kbs_au AFTER UPDATE ON kbs BEGIN
-INSERT INTO tags_idx(tags_idx, rowid, tag_values) VALUES('delete', old.INTERNAL_ID, old.TAG_VALUES);
-INSERT INTO tags_idx(rowid, tag_values) VALUES (new.INTERNAL_ID, new.TAG_VALUES);
-END;`
+	queryKBsSQL = "SELECT " + kbColumnsSQL + " FROM kbs k %s"
 )
 
 func NewSQLite(setup *SQLiteSetup) *SQLite {
 	newSQLite := SQLite{
-		db: setup.DB,
+		db:   setup.DB,
+		conn: setup.DB,
 	}
 
 	return &newSQLite
@@ -120,61 +119,52 @@ func (s *SQLite) Version() (string, error) {
 	return version, nil
 }
 
+// InitializeDB creates or upgrades the database schema applying pending migrations.
 func (s *SQLite) InitializeDB(ctx context.Context) error {
-	_, err := s.db.ExecContext(ctx, createKBTableSQL)
-	if err != nil {
-		return fmt.Errorf("unable to initialize db with kb table: %w", err)
-	}
-
-	_, err = s.db.ExecContext(ctx, createFTSKBTableSQL)
-	if err != nil {
-		log.Println("unable to initialize db with fts table", err)
-		return err
-	}
-
-	// -- Triggers to keep the FTS index up to date.
-	_, err = s.db.ExecContext(ctx, createTriggerAfterInsertSQL)
-	if err != nil {
-		return fmt.Errorf("unable to initialize db with trigger ai: %w", err)
-	}
-	_, err = s.db.ExecContext(ctx, createTriggerAfterDeleteSQL)
+	_, err := s.Migrate(ctx)
 	if err != nil {
-		return fmt.Errorf("unable to initialize db with trigger ad: %w", err)
-	}
-
-	_, err = s.db.ExecContext(ctx, createTriggerAfterUpdateSQL)
-	if err != nil {
-		return fmt.Errorf("unable to initialize db with trigger au: %w", err)
+		return fmt.Errorf("unable to initialize db: %w", err)
 	}
 
 	return nil
 }
 
+// Create saves the kb and its tags in one transaction.
 func (s *SQLite) Create(ctx context.Context, newKB kbs.KB) (string, error) {
-	stmt, err := s.db.Prepare(createKBSQL)
-	if err != nil {
-		return "", fmt.Errorf("unable to create kb: %w", err)
-	}
+	var lastInsertID int64
 
-	defer stmt.Close()
+	err := s.inTransaction(ctx, func(tx *SQLite) error {
+		stmt, release, err := tx.prepare(ctx, createKBSQL)
+		if err != nil {
+			return err
+		}
 
-	dbKB := toDBKB(&newKB)
+		defer release()
 
-	result, err := stmt.ExecContext(ctx,
-		dbKB.KeyID, dbKB.Key, dbKB.Value,
-		dbKB.Notes, dbKB.Category, dbKB.Tags, dbKB.Reference,
-		dbKB.Namespace, dbKB.DateCreated,
-	)
-	if err != nil {
-		return "", fmt.Errorf("unable to create kb: %w", err)
-	}
+		dbKB := toDBKB(&newKB)
+
+		result, err := stmt.ExecContext(ctx,
+			dbKB.KeyID, dbKB.Key, dbKB.Value,
+			dbKB.Notes, dbKB.Category, dbKB.Tags, dbKB.Reference,
+			dbKB.Namespace, dbKB.DateCreated, dbKB.SyncState,
+			dbKB.RemoteID, dbKB.DateLastSynced, dbKB.SyncHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		lastInsertID, err = result.LastInsertId()
+		if err != nil {
+			slog.Error("unable to get id from embedded database", slog.String("error", err.Error()))
+		}
 
-	lastInserID, err := result.LastInsertId()
+		return tx.saveKBTags(ctx, newKB.ID, newKB.Tags)
+	})
 	if err != nil {
-		slog.Error("unable to get id from embedded database", slog.String("error", err.Error()))
+		return "", fmt.Errorf("unable to create kb: %w", err)
 	}
 
-	return fmt.Sprintf("%d", lastInserID), nil
+	return fmt.Sprintf("%d", lastInsertID), nil
 }
 
 func (s *SQLite) GetAll(ctx context.Context, filter kbs.KBQueryFilter) (*kbs.GetAllResult, error) {
@@ -184,7 +174,7 @@ func (s *SQLite) GetAll(ctx context.Context, filter kbs.KBQueryFilter) (*kbs.Get
 		Offset: filter.Offset,
 	}
 
-	searchFilters := buildSQLFilters(filter, countKBsSQL, queryKBsSQL)
+	searchFilters := buildSQLFilters(filter, activeRecords, countKBsSQL, queryKBsSQL)
 
 	count, err := s.queryCount(ctx, searchFilters)
 	if err != nil {
@@ -215,6 +205,128 @@ func (s *SQLite) GetAll(ctx context.Context, filter kbs.KBQueryFilter) (*kbs.Get
 	return &result, nil
 }
 
+// GetAllAfter gets a page of kbs in the order of the filter after the kb of the
+// cursor. The cursor keeps the values the last kb of the previous page is sorted
+// by and its internal id. Unlike offsets, the cursor does not read the kbs it
+// skips and it does not skip or repeat kbs if kbs are added or deleted meanwhile.
+func (s *SQLite) GetAllAfter(ctx context.Context, filter kbs.KBQueryFilter, cursor string) (*kbs.KBPage, error) {
+	expressions := keysetExpressions(filter)
+
+	var after []any
+
+	if cursor != "" {
+		var err error
+
+		after, err = decodeCursor(cursor, len(expressions))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", cursor, err)
+		}
+	}
+
+	filter.Offset = 0
+
+	countSQL, fromSQL := countKBsSQL, " FROM kbs k %s"
+	// the full text condition and the relevance rank need the full text index.
+	if filter.IsFullTextSearch() {
+		countSQL, fromSQL = countKBsByFilterAndMatchSQL, " FROM kbs k JOIN kbs_idx t ON (t.rowid = k.INTERNAL_ID) %s"
+	}
+
+	querySQL := "SELECT " + kbColumnsSQL + ", " + strings.Join(expressions, ", ") + fromSQL
+
+	searchFilters := buildSQLFiltersAfter(filter, activeRecords, after, countSQL, querySQL)
+	searchFilters.sortValues = len(expressions)
+
+	var result kbs.KBPage
+
+	// the total is the same for every page, so it is counted only once.
+	if cursor == "" {
+		count, err := s.queryCount(ctx, searchFilters)
+		if err != nil {
+			slog.Error("running count query to get all kbs after cursor",
+				slog.Any("filter", searchFilters),
+				slog.String("query", searchFilters.countStatement),
+				slog.String("error", err.Error()),
+			)
+
+			return nil, errUnableToGetAllKBS
+		}
+
+		result.Total = count
+	}
+
+	kbsFound, err := s.queryKBs(ctx, searchFilters)
+	if err != nil {
+		slog.Error("querying all kbs after cursor",
+			slog.Any("filter", searchFilters),
+			slog.String("query", searchFilters.query),
+			slog.String("error", err.Error()),
+		)
+
+		return nil, errUnableToGetAllKBS
+	}
+
+	result.KBs = toKBs(kbsFound)
+
+	if len(kbsFound) > 0 && len(kbsFound) == int(filter.Limit) {
+		result.Next, err = encodeCursor(kbsFound[len(kbsFound)-1].sortValues)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get all kbs after cursor: %w", err)
+		}
+	}
+
+	return &result, nil
+}
+
+// encodeCursor returns the cursor of the page after the kb with the given sort values.
+func encodeCursor(sortValues []any) (string, error) {
+	cursor, err := json.Marshal(sortValues)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode cursor: %w", err)
+	}
+
+	return string(cursor), nil
+}
+
+// decodeCursor returns the sort values of the cursor, which must have the given
+// number of values, the internal id last.
+func decodeCursor(cursor string, size int) ([]any, error) {
+	decoder := json.NewDecoder(strings.NewReader(cursor))
+	decoder.UseNumber()
+
+	var values []any
+
+	err := decoder.Decode(&values)
+	if err != nil || len(values) != size {
+		return nil, errInvalidCursor
+	}
+
+	for i, value := range values {
+		switch value := value.(type) {
+		case string:
+		case json.Number:
+			if number, err := value.Int64(); err == nil {
+				values[i] = number
+				continue
+			}
+
+			number, err := value.Float64()
+			if err != nil {
+				return nil, errInvalidCursor
+			}
+
+			values[i] = number
+		default:
+			return nil, errInvalidCursor
+		}
+	}
+
+	if id, ok := values[size-1].(int64); !ok || id < 1 {
+		return nil, errInvalidCursor
+	}
+
+	return values, nil
+}
+
 func (s *SQLite) Search(ctx context.Context, filter kbs.KBQueryFilter) (*kbs.SearchResult, error) {
 	result := kbs.SearchResult{
 		Total:  0,
@@ -225,12 +337,12 @@ func (s *SQLite) Search(ctx context.Context, filter kbs.KBQueryFilter) (*kbs.Sea
 	querySQL := queryKBsByFilterSQL
 	countSQL := countKBsByFilterSQL
 
-	if filter.Keyword != "" {
-		querySQL = queryKBsByFilterAndTagsSQL
-		countSQL = countKBsByFilterAndTagsSQL
+	if filter.IsFullTextSearch() {
+		querySQL = queryKBsByFilterAndMatchSQL
+		countSQL = countKBsByFilterAndMatchSQL
 	}
 
-	searchFilters := buildSQLFilters(filter, countSQL, querySQL)
+	searchFilters := buildSQLFilters(filter, activeRecords, countSQL, querySQL)
 
 	count, err := s.queryCount(ctx, searchFilters)
 	if err != nil {
@@ -279,12 +391,61 @@ func (s *SQLite) GetByKey(ctx context.Context, key string) (*kbs.KB, error) {
 	return kb, nil
 }
 
+// GetTrashedByID gets the kb with the given id if it is in the trash.
+func (s *SQLite) GetTrashedByID(ctx context.Context, id string) (*kbs.KB, error) {
+	kb, err := s.getKBRecord(ctx, queryATrashedKBByIDSQL, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get trashed kb by id: %w", err)
+	}
+
+	return kb, nil
+}
+
+// GetTrashedByKey gets the kb with the given key if it is in the trash.
+func (s *SQLite) GetTrashedByKey(ctx context.Context, key string) (*kbs.KB, error) {
+	kb, err := s.getKBRecord(ctx, queryATrashedKBByKeySQL, key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get trashed kb by key: %w", err)
+	}
+
+	return kb, nil
+}
+
+// GetKeys gets the keys of every kb that is not in the trash, sorted alphabetically.
+func (s *SQLite) GetKeys(ctx context.Context) ([]string, error) {
+	rows, err := s.conn.QueryContext(ctx, queryKeysSQL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query kb keys: %w", err)
+	}
+
+	defer rows.Close()
+
+	result := make([]string, 0)
+
+	for rows.Next() {
+		var key string
+
+		err := rows.Scan(&key)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read kb keys: %w", err)
+		}
+
+		result = append(result, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read kb keys: %w", err)
+	}
+
+	return result, nil
+}
+
 func (s *SQLite) getKBRecord(ctx context.Context, sqlQuery, value string) (*kbs.KB, error) {
-	row := s.db.QueryRowContext(ctx, sqlQuery, value)
+	row := s.conn.QueryRowContext(ctx, sqlQuery, value)
 
 	var aKB kb
 
-	err := row.Scan(&aKB.InternalID, &aKB.KeyID, &aKB.Key, &aKB.Value, &aKB.Notes, &aKB.Namespace, &aKB.Category, &aKB.Tags, &aKB.Reference, &aKB.DateCreated)
+	err := row.Scan(aKB.fields()...)
 	if err != nil && err == sql.ErrNoRows {
 		return nil, nil // it does not exist
 	}
@@ -296,33 +457,275 @@ func (s *SQLite) getKBRecord(ctx context.Context, sqlQuery, value string) (*kbs.
 	return aKB.toKB(), nil
 }
 
+// Update saves the kb and its tags in one transaction, keeping the content it
+// replaces as a revision.
 func (s *SQLite) Update(ctx context.Context, kb *kbs.KB) error {
-	stmt, err := s.db.Prepare(updateKBSQL)
+	err := s.inTransaction(ctx, func(tx *SQLite) error {
+		changed, err := tx.saveRevision(ctx, kb)
+		if err != nil {
+			return err
+		}
+
+		stmt, release, err := tx.prepare(ctx, updateKBSQL)
+		if err != nil {
+			return err
+		}
+
+		defer release()
+
+		dbKB := toDBKB(kb)
+
+		// the update date is kept if the update does not change the content.
+		updatedOn := sql.NullTime{Time: time.Now().UTC(), Valid: changed}
+
+		result, err := stmt.ExecContext(ctx,
+			dbKB.Key, dbKB.Value, dbKB.Notes,
+			dbKB.Category, dbKB.Tags, dbKB.Reference,
+			dbKB.Namespace, updatedOn, dbKB.KeyID,
+		)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			slog.Error("rows affected", slog.String("error", err.Error()))
+		} else {
+			slog.Info("rows affected", slog.Int64("rows", rowsAffected))
+		}
+
+		return tx.saveKBTags(ctx, kb.ID, kb.Tags)
+	})
 	if err != nil {
 		return fmt.Errorf("unable to update kb: %w", err)
 	}
 
-	defer stmt.Close()
+	return nil
+}
+
+// GetTrash gets kbs that were soft deleted.
+func (s *SQLite) GetTrash(ctx context.Context, filter kbs.KBQueryFilter) (*kbs.GetAllResult, error) {
+	result := kbs.GetAllResult{
+		Total:  0,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
 
-	dbKB := toDBKB(kb)
+	searchFilters := buildSQLFilters(filter, deletedRecords, countKBsSQL, queryKBsSQL)
 
-	result, err := stmt.ExecContext(ctx,
-		dbKB.Key, dbKB.Value, dbKB.Notes,
-		dbKB.Category, dbKB.Tags, dbKB.Reference,
-		dbKB.Namespace, dbKB.KeyID,
-	)
+	count, err := s.queryCount(ctx, searchFilters)
 	if err != nil {
-		return fmt.Errorf("unable to update kb: %w", err)
+		slog.Error("running count query to get kbs in the trash",
+			slog.Any("filter", searchFilters),
+			slog.String("query", searchFilters.countStatement),
+			slog.String("error", err.Error()),
+		)
+
+		return nil, errUnableToGetTrash
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	result.Total = count
+
+	kbsFound, err := s.queryKBs(ctx, searchFilters)
+	if err != nil {
+		slog.Error("querying kbs in the trash",
+			slog.Any("filter", searchFilters),
+			slog.String("query", searchFilters.query),
+			slog.String("error", err.Error()),
+		)
+
+		return nil, errUnableToGetTrash
+	}
+
+	result.KBs = toKBs(kbsFound)
+
+	return &result, nil
+}
+
+// Delete moves the kb with the given id to the trash. If the kb exists on the
+// server, its deletion is queued in the same transaction.
+func (s *SQLite) Delete(ctx context.Context, id string) error {
+	err := s.inTransaction(ctx, func(tx *SQLite) error {
+		now := time.Now().UTC()
+
+		_, err := tx.conn.ExecContext(ctx, enqueueDeletedKBSQL, string(kbs.SyncOperationDelete), now, now, id)
+		if err != nil {
+			return fmt.Errorf("unable to queue deletion for sync: %w", err)
+		}
+
+		return tx.execByID(ctx, softDeleteKBSQL, now, id)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to delete kb: %w", err)
+	}
+
+	return nil
+}
+
+// Restore takes the kb with the given id out of the trash, and drops its
+// deletion from the sync queue if it was not sent to the server yet.
+func (s *SQLite) Restore(ctx context.Context, id string) error {
+	err := s.inTransaction(ctx, func(tx *SQLite) error {
+		_, err := tx.conn.ExecContext(ctx, dequeueRestoredKBSQL, string(kbs.SyncOperationDelete), id)
+		if err != nil {
+			return fmt.Errorf("unable to remove deletion from sync queue: %w", err)
+		}
+
+		return tx.execByID(ctx, restoreKBSQL, id)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to restore kb: %w", err)
+	}
+
+	return nil
+}
+
+// Purge removes permanently the kb with the given id, only if it is in the trash.
+// If the kb exists on the server, its deletion is queued in the same transaction.
+func (s *SQLite) Purge(ctx context.Context, id string) error {
+	purged, err := s.purge(ctx, enqueuePurgedKBSQL, purgeKBSQL, id)
+	if err != nil {
+		return fmt.Errorf("unable to purge kb: %w", err)
+	}
+
+	if purged == 0 {
+		return fmt.Errorf("unable to purge kb: %w", kbs.ErrKBNotFound)
+	}
+
+	return nil
+}
+
+// EmptyTrash removes permanently all kbs in the trash and queues the deletion
+// of the ones that exist on the server.
+func (s *SQLite) EmptyTrash(ctx context.Context) (int64, error) {
+	purged, err := s.purge(ctx, enqueuePurgedKBsSQL, emptyTrashSQL)
 	if err != nil {
-		slog.Error("rows affected", slog.String("error", err.Error()))
+		return 0, fmt.Errorf("unable to empty trash: %w", err)
+	}
+
+	return purged, nil
+}
+
+// purge queues the deletion of the trashed kbs and removes them in one transaction.
+func (s *SQLite) purge(ctx context.Context, enqueueStatement, deleteStatement string, args ...any) (int64, error) {
+	var purged int64
+
+	err := s.inTransaction(ctx, func(tx *SQLite) error {
+		now := time.Now().UTC()
+
+		operation := string(kbs.SyncOperationDelete)
+
+		_, err := tx.conn.ExecContext(ctx, enqueueStatement, append([]any{operation, now, now, operation}, args...)...)
+		if err != nil {
+			return fmt.Errorf("unable to queue deletion for sync: %w", err)
+		}
+
+		result, err := tx.conn.ExecContext(ctx, deleteStatement, args...)
+		if err != nil {
+			return fmt.Errorf("unable to delete kbs: %w", err)
+		}
+
+		purged, err = result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("unable to get number of purged kbs: %w", err)
+		}
 
 		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// GetByRemoteID gets the kb that is linked to the given server kb id, even if it is in the trash.
+func (s *SQLite) GetByRemoteID(ctx context.Context, remoteID string) (*kbs.KB, error) {
+	kb, err := s.getKBRecord(ctx, queryAKBByRemoteIDSQL, remoteID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get kb by remote id: %w", err)
+	}
+
+	return kb, nil
+}
+
+// GetBySyncState gets active kbs in the given sync state, in the order they were created.
+func (s *SQLite) GetBySyncState(ctx context.Context, state kbs.SyncState) ([]kbs.KB, error) {
+	rows, err := s.conn.QueryContext(ctx, queryKBsBySyncStateSQL, string(state))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get kbs by sync state: %w", err)
+	}
+
+	defer rows.Close()
+
+	kbsFound := make([]kb, 0)
+
+	for rows.Next() {
+		kb := new(kb)
+
+		err := rows.Scan(kb.fields()...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan kb rows: %w", err)
+		}
+
+		kbsFound = append(kbsFound, *kb)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get kbs by sync state query had some errors: %w", err)
+	}
+
+	return toKBs(kbsFound), nil
+}
+
+// MarkAsSynced links the kb with the given id to its server kb and flags it as synced,
+// hash is the content hash both kbs share.
+func (s *SQLite) MarkAsSynced(ctx context.Context, id, remoteID, hash string) error {
+	err := s.execByID(ctx, markKBAsSyncedSQL, remoteID, hash, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("unable to mark kb as synced: %w", err)
+	}
+
+	return nil
+}
+
+// MarkAsDeletedOnServer unlinks the kb from the given server kb once it was
+// deleted there. Nothing changes if no kb is linked to it, e.g. it was purged.
+func (s *SQLite) MarkAsDeletedOnServer(ctx context.Context, remoteID string) error {
+	_, err := s.conn.ExecContext(ctx, markKBAsDeletedOnServerSQL, remoteID)
+	if err != nil {
+		return fmt.Errorf("unable to mark kb as deleted on server: %w", err)
 	}
 
-	slog.Info("rows affected", slog.Int64("rows", rowsAffected))
+	return nil
+}
+
+// MarkAsAccessed counts that the kb with the given id was opened now.
+func (s *SQLite) MarkAsAccessed(ctx context.Context, id string) error {
+	err := s.execByID(ctx, markKBAsAccessedSQL, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("unable to mark kb as accessed: %w", err)
+	}
+
+	return nil
+}
+
+// execByID runs a statement that affects one kb and returns kbs.ErrKBNotFound
+// if no rows were affected.
+func (s *SQLite) execByID(ctx context.Context, statement string, args ...any) error {
+	result, err := s.conn.ExecContext(ctx, statement, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return kbs.ErrKBNotFound
+	}
 
 	return nil
 }
@@ -330,7 +733,7 @@ func (s *SQLite) Update(ctx context.Context, kb *kbs.KB) error {
 func (s *SQLite) queryCount(ctx context.Context, searchFilters *filterBuilder) (int, error) {
 	var count int
 
-	countStmt, err := s.db.Prepare(searchFilters.countStatement)
+	countStmt, release, err := s.prepare(ctx, searchFilters.countStatement)
 	if err != nil {
 		slog.Error("building count kbs prepared statement",
 			slog.Any("filter", searchFilters),
@@ -340,7 +743,7 @@ func (s *SQLite) queryCount(ctx context.Context, searchFilters *filterBuilder) (
 		return -1, fmt.Errorf("unable to build query to count kbs: %w", err)
 	}
 
-	defer countStmt.Close()
+	defer release()
 
 	row := countStmt.QueryRowContext(ctx, searchFilters.countArgs...)
 
@@ -363,7 +766,7 @@ func (s *SQLite) queryCount(ctx context.Context, searchFilters *filterBuilder) (
 }
 
 func (s *SQLite) queryKBItems(ctx context.Context, searchFilters *filterBuilder) ([]kbItem, error) {
-	rows, err := s.db.QueryContext(ctx, searchFilters.query, searchFilters.queryArgs...)
+	rows, err := s.conn.QueryContext(ctx, searchFilters.query, searchFilters.queryArgs...)
 	if err != nil {
 		slog.Error("running query to find kbs with given criteria",
 			slog.Any("filter", searchFilters),
@@ -381,7 +784,7 @@ func (s *SQLite) queryKBItems(ctx context.Context, searchFilters *filterBuilder)
 	for rows.Next() {
 		kb := new(kbItem)
 		// id, firstname, lastname, nickname, country
-		rowErr := rows.Scan(&kb.ID, &kb.Key, &kb.Category, &kb.Namespace, &kb.Tags)
+		rowErr := rows.Scan(&kb.ID, &kb.Key, &kb.Category, &kb.Namespace, &kb.Tags, &kb.Snippet)
 		if rowErr != nil {
 			slog.Error("scanning rows for searching kbs with search criteria",
 				slog.Any("filter", searchFilters),
@@ -409,7 +812,7 @@ func (s *SQLite) queryKBItems(ctx context.Context, searchFilters *filterBuilder)
 }
 
 func (s *SQLite) queryKBs(ctx context.Context, searchFilters *filterBuilder) ([]kb, error) {
-	rows, err := s.db.QueryContext(ctx, searchFilters.query, searchFilters.queryArgs...)
+	rows, err := s.conn.QueryContext(ctx, searchFilters.query, searchFilters.queryArgs...)
 	if err != nil {
 		slog.Error("running query to get all kbs",
 			slog.Any("filter", searchFilters),
@@ -426,7 +829,16 @@ func (s *SQLite) queryKBs(ctx context.Context, searchFilters *filterBuilder) ([]
 
 	for rows.Next() {
 		kb := new(kb)
-		rowErr := rows.Scan(&kb.InternalID, &kb.KeyID, &kb.Key, &kb.Value, &kb.Notes, &kb.Namespace, &kb.Category, &kb.Tags, &kb.Reference, &kb.DateCreated)
+		fields := kb.fields()
+
+		if searchFilters.sortValues > 0 {
+			kb.sortValues = make([]any, searchFilters.sortValues)
+			for i := range kb.sortValues {
+				fields = append(fields, &kb.sortValues[i])
+			}
+		}
+
+		rowErr := rows.Scan(fields...)
 		if rowErr != nil {
 			slog.Error("scanning rows to get all kbs",
 				slog.Any("filter", searchFilters),
@@ -454,7 +866,7 @@ func (s *SQLite) queryKBs(ctx context.Context, searchFilters *filterBuilder) ([]
 }
 
 func (s *SQLite) CountByCategory(ctx context.Context, category string) (int64, error) {
-	countStmt, err := s.db.Prepare(countKBsByCategorySQL)
+	countStmt, release, err := s.prepare(ctx, countKBsByCategorySQL)
 	if err != nil {
 		slog.Error("building count kbs by category prepared statement",
 			slog.Any("category", category),
@@ -465,7 +877,7 @@ func (s *SQLite) CountByCategory(ctx context.Context, category string) (int64, e
 		return -1, fmt.Errorf("unable to build query to count kbs by category: %w", err)
 	}
 
-	defer countStmt.Close()
+	defer release()
 
 	row := countStmt.QueryRowContext(ctx, category)
 
@@ -489,15 +901,29 @@ func (s *SQLite) CountByCategory(ctx context.Context, category string) (int64, e
 	return count, nil
 }
 
-func buildSQLFilters(filters kbs.KBQueryFilter, countSQL, querySQL string) *filterBuilder {
+func buildSQLFilters(filters kbs.KBQueryFilter, state recordState, countSQL, querySQL string) *filterBuilder {
+	return buildSQLFiltersAfter(filters, state, nil, countSQL, querySQL)
+}
+
+// buildSQLFiltersAfter builds the filters of kbs after the kb with the given
+// sort values, see keysetExpressions, in the order of the filter. No values
+// means from the first kb.
+func buildSQLFiltersAfter(filters kbs.KBQueryFilter, state recordState, after []any, countSQL, querySQL string) *filterBuilder {
 	newFilterBuilder := &filterBuilder{
 		filters:   make([]string, 0),
 		countArgs: make([]any, 0),
 		queryArgs: make([]any, 0),
 	}
 
-	if filters.Keyword != "" {
-		newFilterBuilder.addCondition(tagValuesVirtualColumn, matchOperator, filters.Keyword+"*")
+	switch state {
+	case deletedRecords:
+		newFilterBuilder.addStateCondition(deletedOnColumn, isNotNullOperator)
+	default:
+		newFilterBuilder.addStateCondition(deletedOnColumn, isNullOperator)
+	}
+
+	if filters.IsFullTextSearch() {
+		newFilterBuilder.addCondition(fullTextVirtualColumn, matchOperator, buildMatchExpression(filters))
 	}
 
 	if filters.Key != "" {
@@ -508,10 +934,30 @@ func buildSQLFilters(filters kbs.KBQueryFilter, countSQL, querySQL string) *filt
 		newFilterBuilder.addCondition(categoryColumn, equalsOperator, filters.Category)
 	}
 
-	if filters.Namespace != "" {
+	switch {
+	case filters.Namespace != "" && filters.IncludeSubNamespaces:
+		newFilterBuilder.addExpressionCondition(namespaceTreeConditionSQL, filters.Namespace)
+	case filters.Namespace != "":
 		newFilterBuilder.addCondition(namespaceColumn, equalsOperator, filters.Namespace)
 	}
 
+	if len(filters.AllTags) > 0 {
+		newFilterBuilder.addSubqueryCondition(allTagsSubquerySQL, tagsFilterParam(filters.AllTags))
+	}
+
+	if len(filters.AnyTags) > 0 {
+		newFilterBuilder.addSubqueryCondition(anyTagsSubquerySQL, tagsFilterParam(filters.AnyTags))
+	}
+
+	if len(after) > 0 {
+		operator := greaterThanOperator
+		if _, order := filters.Sorting(); order == kbs.SortDescending {
+			operator = lessThanOperator
+		}
+
+		newFilterBuilder.addKeysetCondition(keysetExpressions(filters), operator, after)
+	}
+
 	var countWhereClause strings.Builder
 	for _, v := range newFilterBuilder.filters {
 		countWhereClause.WriteString(v)
@@ -520,6 +966,8 @@ func buildSQLFilters(filters kbs.KBQueryFilter, countSQL, querySQL string) *filt
 	countStatement := fmt.Sprintf(countSQL, countWhereClause.String())
 	newFilterBuilder.countStatement = countStatement
 
+	newFilterBuilder.addOrder(orderBySQL(filters))
+
 	newFilterBuilder.addFilter(fmt.Sprintf(" %s", limitOperator), filters.Limit, true)
 	newFilterBuilder.addFilter(fmt.Sprintf(" %s", offsetOperator), filters.Offset, true)
 
@@ -534,6 +982,129 @@ func buildSQLFilters(filters kbs.KBQueryFilter, countSQL, querySQL string) *filt
 	return newFilterBuilder
 }
 
+// orderBySQL returns the order by clause of the filter. Its last expression is
+// the internal id, so kbs that are equal keep the same order between pages.
+func orderBySQL(filters kbs.KBQueryFilter) string {
+	_, order := filters.Sorting()
+
+	direction := "ASC"
+	if order == kbs.SortDescending {
+		direction = "DESC"
+	}
+
+	expressions := keysetExpressions(filters)
+
+	for i, expression := range expressions {
+		expressions[i] = expression + aSpace + direction
+	}
+
+	return "ORDER BY " + strings.Join(expressions, ", ")
+}
+
+// keysetExpressions returns the expressions kbs are sorted by, the internal id
+// that breaks the ties last.
+func keysetExpressions(filters kbs.KBQueryFilter) []string {
+	sortBy, _ := filters.Sorting()
+
+	return append(slices.Clone(sortExpressions[sortBy]), internalIDColumn)
+}
+
+// buildMatchExpression combines the free text query, which supports fts5 syntax,
+// and the keyword, which is a prefix search on tags.
+func buildMatchExpression(filters kbs.KBQueryFilter) string {
+	expressions := make([]string, 0, 2)
+
+	if filters.Query != "" {
+		expressions = append(expressions, fmt.Sprintf("(%s)", filters.Query))
+	}
+
+	if filters.Keyword != "" {
+		expressions = append(expressions, fmt.Sprintf("%s:%s*", tagValuesIndexColumn, quoteFTSString(filters.Keyword)))
+	}
+
+	return strings.Join(expressions, aSpace+andOperator+aSpace)
+}
+
+// quoteFTSString makes the given value an fts5 string, so its characters are not
+// interpreted as part of the query syntax.
+func quoteFTSString(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
+
+// WithTransaction runs fn with a storage that writes in a single transaction.
+// Changes are committed if fn returns no error and rolled back otherwise.
+func (s *SQLite) WithTransaction(ctx context.Context, fn func(storage kbs.Storage) error) error {
+	return s.inTransaction(ctx, func(tx *SQLite) error {
+		return fn(tx)
+	})
+}
+
+// inTransaction runs fn with the storage itself if it is already in a transaction,
+// or with a storage in a new one otherwise.
+func (s *SQLite) inTransaction(ctx context.Context, fn func(tx *SQLite) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to start transaction: %w", err)
+	}
+
+	txStorage := SQLite{
+		db:    s.db,
+		conn:  tx,
+		tx:    tx,
+		stmts: make(map[string]*sql.Stmt),
+	}
+
+	defer func() {
+		for _, stmt := range txStorage.stmts {
+			stmt.Close()
+		}
+
+		_ = tx.Rollback()
+	}()
+
+	err = fn(&txStorage)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// prepare returns a prepared statement and the function to release it. Inside
+// a transaction, statements are kept and reused until the transaction ends.
+func (s *SQLite) prepare(ctx context.Context, query string) (*sql.Stmt, func(), error) {
+	if s.tx == nil {
+		stmt, err := s.conn.PrepareContext(ctx, query)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return stmt, func() { stmt.Close() }, nil
+	}
+
+	if stmt, ok := s.stmts[query]; ok {
+		return stmt, func() {}, nil
+	}
+
+	stmt, err := s.tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.stmts[query] = stmt
+
+	return stmt, func() {}, nil
+}
+
 func (s *SQLite) Close() {
 	if s == nil || s.db == nil {
 		return // just for initializing app
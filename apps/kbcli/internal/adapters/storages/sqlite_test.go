@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/adapters/storages"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
@@ -68,6 +69,248 @@ func TestCreateKBDuplicateKey(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// ---- WithTransaction ----
+
+func TestWithTransactionCommits(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	err := storage.WithTransaction(ctx, func(txStorage kbs.Storage) error {
+		for i := range 3 {
+			kb := makeTestKB()
+			kb.ID = fmt.Sprintf("tx-id-%d", i)
+			kb.Key = fmt.Sprintf("tx-key-%d", i)
+
+			_, err := txStorage.Create(ctx, kb)
+			if err != nil {
+				return err
+			}
+		}
+
+		// changes are visible inside the transaction.
+		found, err := txStorage.GetByKey(ctx, "tx-key-2")
+		require.NoError(t, err)
+		require.NotNil(t, found)
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	all, err := storage.GetAll(ctx, kbs.KBQueryFilter{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 3, all.Total)
+}
+
+func TestWithTransactionRollsBack(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	err := storage.WithTransaction(ctx, func(txStorage kbs.Storage) error {
+		_, err := txStorage.Create(ctx, kb)
+		require.NoError(t, err)
+
+		// the duplicated key fails, so the first kb must not be saved either.
+		_, err = txStorage.Create(ctx, kb)
+
+		return err
+	})
+
+	require.Error(t, err)
+	found, err := storage.GetByKey(ctx, kb.Key)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+// ---- Tags ----
+
+func createTaggedKBs(t *testing.T, storage *storages.SQLite, tags ...[]string) {
+	t.Helper()
+
+	for i, kbTags := range tags {
+		kb := makeTestKB()
+		kb.ID = fmt.Sprintf("tagged-id-%d", i)
+		kb.Key = fmt.Sprintf("tagged-key-%d", i)
+		kb.Tags = kbTags
+
+		_, err := storage.Create(context.Background(), kb)
+		require.NoError(t, err)
+	}
+}
+
+func TestGetTagCounts(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	createTaggedKBs(t, storage, []string{"docker", "linux"}, []string{"docker"}, []string{"bitcoin"})
+	// tags of kbs in the trash are not counted.
+	require.NoError(t, storage.Delete(ctx, "tagged-id-2"))
+
+	got, err := storage.GetTagCounts(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, []kbs.TagCount{{Tag: "docker", Count: 2}, {Tag: "linux", Count: 1}}, got)
+}
+
+func TestReplaceTags(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	createTaggedKBs(t, storage, []string{"docker", "k8s"}, []string{"kube"}, []string{"linux"}, []string{"k8s-tools"})
+	require.NoError(t, storage.MarkAsSynced(ctx, "tagged-id-1", "remote-1", "hash"))
+
+	changed, err := storage.ReplaceTags(ctx, []string{"k8s", "kube"}, "docker")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), changed)
+
+	first, err := storage.GetByID(ctx, "tagged-id-0")
+	require.NoError(t, err)
+	// the kb already had the new tag, so it is kept once.
+	assert.Equal(t, []string{"docker"}, first.Tags)
+
+	second, err := storage.GetByID(ctx, "tagged-id-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docker"}, second.Tags)
+	assert.Equal(t, kbs.SyncStateModified, second.SyncState)
+
+	// only whole tags are replaced.
+	fourth, err := storage.GetByID(ctx, "tagged-id-3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k8s-tools"}, fourth.Tags)
+
+	counts, err := storage.GetTagCounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []kbs.TagCount{{Tag: "docker", Count: 2}, {Tag: "k8s-tools", Count: 1}, {Tag: "linux", Count: 1}}, counts)
+}
+
+func TestReplaceTagsLeavesKBsInTrash(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	createTaggedKBs(t, storage, []string{"k8s"}, []string{"k8s"})
+	require.NoError(t, storage.MarkAsSynced(ctx, "tagged-id-1", "remote-1", "hash"))
+	require.NoError(t, storage.Delete(ctx, "tagged-id-1"))
+
+	changed, err := storage.ReplaceTags(ctx, []string{"k8s"}, "kubernetes")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), changed)
+
+	trashed, err := storage.GetTrashedByID(ctx, "tagged-id-1")
+	require.NoError(t, err)
+	require.NotNil(t, trashed)
+	assert.Equal(t, []string{"k8s"}, trashed.Tags)
+	assert.Equal(t, kbs.SyncStateSynced, trashed.SyncState)
+}
+
+func TestUpdateKBReplacesItsTags(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	createTaggedKBs(t, storage, []string{"docker", "linux"})
+
+	kb, err := storage.GetByID(ctx, "tagged-id-0")
+	require.NoError(t, err)
+
+	kb.Tags = []string{"linux", "podman"}
+	require.NoError(t, storage.Update(ctx, kb))
+
+	got, err := storage.GetTagCounts(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, []kbs.TagCount{{Tag: "linux", Count: 1}, {Tag: "podman", Count: 1}}, got)
+}
+
+func TestPurgeKBRemovesItsTags(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	createTaggedKBs(t, storage, []string{"docker", "linux"}, []string{"linux"})
+	require.NoError(t, storage.Delete(ctx, "tagged-id-0"))
+	require.NoError(t, storage.Purge(ctx, "tagged-id-0"))
+
+	found, err := storage.Search(ctx, kbs.KBQueryFilter{AnyTags: []string{"docker"}, Limit: 10})
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, found.Total)
+}
+
+func TestCreateKBWithManyTags(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	tags := make([]string, 0, 100)
+	for i := range 100 {
+		tags = append(tags, fmt.Sprintf("a-long-tag-name-%03d", i))
+	}
+
+	createTaggedKBs(t, storage, tags)
+
+	got, err := storage.GetByID(ctx, "tagged-id-0")
+
+	require.NoError(t, err)
+	assert.Equal(t, tags, got.Tags)
+}
+
+func TestSearchByTags(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	createTaggedKBs(t, storage,
+		[]string{"docker", "linux"},
+		[]string{"docker"},
+		[]string{"linux", "podman"},
+		[]string{"docker-compose"},
+	)
+
+	cases := map[string]struct {
+		filter kbs.KBQueryFilter
+		want   []string
+	}{
+		"all of": {
+			filter: kbs.KBQueryFilter{AllTags: []string{"docker", "linux"}},
+			want:   []string{"tagged-key-0"},
+		},
+		"all of with a repeated tag": {
+			filter: kbs.KBQueryFilter{AllTags: []string{"docker", "docker"}},
+			want:   []string{"tagged-key-0", "tagged-key-1"},
+		},
+		"any of": {
+			filter: kbs.KBQueryFilter{AnyTags: []string{"docker", "podman"}},
+			want:   []string{"tagged-key-0", "tagged-key-1", "tagged-key-2"},
+		},
+		"all of and any of": {
+			filter: kbs.KBQueryFilter{AllTags: []string{"linux"}, AnyTags: []string{"docker", "podman"}},
+			want:   []string{"tagged-key-0", "tagged-key-2"},
+		},
+		"with another criteria": {
+			filter: kbs.KBQueryFilter{Key: "key-1", AnyTags: []string{"docker"}},
+			want:   []string{"tagged-key-1"},
+		},
+		"with full text": {
+			filter: kbs.KBQueryFilter{Query: "compose", AnyTags: []string{"docker-compose"}},
+			want:   []string{"tagged-key-3"},
+		},
+		"unknown tag": {
+			filter: kbs.KBQueryFilter{AnyTags: []string{"dock"}},
+			want:   []string{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tc.filter.Limit = 10
+
+			found, err := storage.Search(ctx, tc.filter)
+
+			require.NoError(t, err)
+			assert.Equal(t, len(tc.want), found.Total)
+
+			keys := make([]string, 0, len(found.Items))
+			for _, item := range found.Items {
+				keys = append(keys, item.Key)
+			}
+
+			assert.ElementsMatch(t, tc.want, keys)
+		})
+	}
+}
+
 // ---- GetByID ----
 
 func TestGetByIDExisting(t *testing.T) {
@@ -127,6 +370,20 @@ func TestGetByKeyNotFound(t *testing.T) {
 	assert.Nil(t, result)
 }
 
+// ---- GetKeys ----
+
+func TestGetKeys(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	createKBsInNamespaces(t, storage, "a", "a", "a")
+	require.NoError(t, storage.Delete(ctx, "namespaced-id-1"))
+
+	got, err := storage.GetKeys(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"namespaced-key-0", "namespaced-key-2"}, got)
+}
+
 // ---- Update ----
 
 func TestUpdateKB(t *testing.T) {
@@ -151,6 +408,164 @@ func TestUpdateKB(t *testing.T) {
 	assert.Equal(t, "Updated notes", result.Notes)
 }
 
+// ---- Delete ----
+
+func TestDeleteKBHidesIt(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	err = storage.Delete(ctx, kb.ID)
+	require.NoError(t, err)
+
+	byID, err := storage.GetByID(ctx, kb.ID)
+	require.NoError(t, err)
+	assert.Nil(t, byID)
+
+	byKey, err := storage.GetByKey(ctx, kb.Key)
+	require.NoError(t, err)
+	assert.Nil(t, byKey)
+
+	all, err := storage.GetAll(ctx, kbs.KBQueryFilter{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 0, all.Total)
+
+	found, err := storage.Search(ctx, kbs.KBQueryFilter{Keyword: "bitcoin", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 0, found.Total)
+
+	count, err := storage.CountByCategory(ctx, kb.Category)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	trash, err := storage.GetTrash(ctx, kbs.KBQueryFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, trash.KBs, 1)
+	assert.Equal(t, kb.ID, trash.KBs[0].ID)
+	assert.NotNil(t, trash.KBs[0].DeletedOn)
+}
+
+func TestDeleteKBNotFound(t *testing.T) {
+	storage := newTestDB(t)
+
+	err := storage.Delete(context.Background(), "nonexistent-id")
+
+	assert.ErrorIs(t, err, kbs.ErrKBNotFound)
+}
+
+func TestRestoreKB(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+	require.NoError(t, storage.Delete(ctx, kb.ID))
+
+	err = storage.Restore(ctx, kb.ID)
+	require.NoError(t, err)
+
+	result, err := storage.GetByID(ctx, kb.ID)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Nil(t, result.DeletedOn)
+
+	err = storage.Restore(ctx, kb.ID)
+	assert.ErrorIs(t, err, kbs.ErrKBNotFound)
+}
+
+func TestGetTrashedKB(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	// active kbs are not in the trash
+	trashed, err := storage.GetTrashedByKey(ctx, kb.Key)
+	require.NoError(t, err)
+	assert.Nil(t, trashed)
+
+	require.NoError(t, storage.Delete(ctx, kb.ID))
+
+	trashed, err = storage.GetTrashedByKey(ctx, kb.Key)
+	require.NoError(t, err)
+	require.NotNil(t, trashed)
+	assert.Equal(t, kb.ID, trashed.ID)
+	assert.NotNil(t, trashed.DeletedOn)
+
+	trashed, err = storage.GetTrashedByID(ctx, kb.ID)
+	require.NoError(t, err)
+	require.NotNil(t, trashed)
+	assert.Equal(t, kb.Key, trashed.Key)
+}
+
+func TestPurgeKB(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	// only kbs in the trash can be purged
+	err = storage.Purge(ctx, kb.ID)
+	require.ErrorIs(t, err, kbs.ErrKBNotFound)
+
+	require.NoError(t, storage.Delete(ctx, kb.ID))
+
+	err = storage.Purge(ctx, kb.ID)
+	require.NoError(t, err)
+
+	trash, err := storage.GetTrash(ctx, kbs.KBQueryFilter{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 0, trash.Total)
+
+	// the key can be used again and the fts index is still consistent
+	_, err = storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	found, err := storage.Search(ctx, kbs.KBQueryFilter{Keyword: "halving", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, found.Total)
+}
+
+func TestEmptyTrash(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	for i := range 3 {
+		kb := kbs.KB{
+			ID:        fmt.Sprintf("test-uuid-trash-%04d", i),
+			Key:       fmt.Sprintf("trash-%d", i),
+			Value:     "Value",
+			Notes:     "Notes",
+			Category:  "test",
+			Namespace: "testns",
+			Tags:      []string{"tag"},
+		}
+		_, err := storage.Create(ctx, kb)
+		require.NoError(t, err)
+
+		if i > 0 {
+			require.NoError(t, storage.Delete(ctx, kb.ID))
+		}
+	}
+
+	purged, err := storage.EmptyTrash(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+
+	all, err := storage.GetAll(ctx, kbs.KBQueryFilter{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, all.Total)
+}
+
 // ---- GetAll ----
 
 func TestGetAllEmpty(t *testing.T) {
@@ -557,3 +972,1025 @@ func TestCountByCategoryMultiple(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, int64(3), count)
 }
+
+// ---- Full text search ----
+
+func TestSearchByQueryMatchesValueAndNotes(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		"value": "block",
+		"notes": "finite",
+		"key":   "halving",
+		"tags":  "bitcoin",
+	}
+
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := storage.Search(ctx, kbs.KBQueryFilter{Query: query, Limit: 10})
+
+			require.NoError(t, err)
+			assert.Equal(t, 1, result.Total)
+			require.Len(t, result.Items, 1)
+			assert.Equal(t, kb.Key, result.Items[0].Key)
+			assert.Contains(t, result.Items[0].Snippet, "["+query+"]")
+		})
+	}
+}
+
+func TestSearchByQuerySyntax(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	for i, value := range []string{"docker compose up", "docker run image", "podman compose up"} {
+		kb := kbs.KB{
+			ID:        fmt.Sprintf("test-uuid-syntax-%04d", i),
+			Key:       fmt.Sprintf("command-%d", i),
+			Value:     value,
+			Notes:     "Notes",
+			Category:  "command",
+			Namespace: "containers",
+			Tags:      []string{"containers"},
+		}
+		_, err := storage.Create(ctx, kb)
+		require.NoError(t, err)
+	}
+
+	tests := map[string]int{
+		`"compose up"`:            2,
+		"docker AND compose":      1,
+		"docker NOT compose":      1,
+		"podman OR run":           2,
+		"dock*":                   2,
+		"compose up containers":   2,
+		"kubernetes":              0,
+		`"compose up" NOT podman`: 1,
+	}
+
+	for query, want := range tests {
+		t.Run(query, func(t *testing.T) {
+			result, err := storage.Search(ctx, kbs.KBQueryFilter{Query: query, Limit: 10})
+
+			require.NoError(t, err)
+			assert.Equal(t, want, result.Total)
+			assert.Len(t, result.Items, want)
+		})
+	}
+}
+
+func TestSearchByQueryRanksByRelevance(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	mentionedInNotes := kbs.KB{
+		ID:        "test-uuid-rank-0001",
+		Key:       "ci-pipeline",
+		Value:     "Pipeline that builds and tests the project",
+		Notes:     "It also runs terraform plan",
+		Category:  "devops",
+		Namespace: "default",
+		Tags:      []string{"ci"},
+	}
+	mentionedInKey := kbs.KB{
+		ID:        "test-uuid-rank-0002",
+		Key:       "terraform",
+		Value:     "terraform apply -auto-approve",
+		Notes:     "Applies terraform changes without asking",
+		Category:  "command",
+		Namespace: "default",
+		Tags:      []string{"terraform", "iac"},
+	}
+
+	for _, kb := range []kbs.KB{mentionedInNotes, mentionedInKey} {
+		_, err := storage.Create(ctx, kb)
+		require.NoError(t, err)
+	}
+
+	result, err := storage.Search(ctx, kbs.KBQueryFilter{Query: "terraform", Limit: 10})
+
+	require.NoError(t, err)
+	require.Len(t, result.Items, 2)
+	assert.Equal(t, mentionedInKey.Key, result.Items[0].Key)
+	assert.Equal(t, mentionedInNotes.Key, result.Items[1].Key)
+}
+
+func TestSearchByQueryAndKeyword(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	result, err := storage.Search(ctx, kbs.KBQueryFilter{Query: "finite", Keyword: "halv", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Total)
+
+	// the keyword only looks for tags
+	result, err = storage.Search(ctx, kbs.KBQueryFilter{Query: "finite", Keyword: "supply", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Total)
+}
+
+func TestSearchByKeywordWithQuotes(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	_, err := storage.Create(ctx, makeTestKB())
+	require.NoError(t, err)
+
+	result, err := storage.Search(ctx, kbs.KBQueryFilter{Keyword: `bit"coin`, Limit: 10})
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Total)
+}
+
+func TestSearchByInvalidQuery(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	_, err := storage.Create(ctx, makeTestKB())
+	require.NoError(t, err)
+
+	_, err = storage.Search(ctx, kbs.KBQueryFilter{Query: `"unterminated`, Limit: 10})
+
+	assert.Error(t, err)
+}
+
+// ---- Sync state ----
+
+func TestCreateKBIsNewForSync(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	pending, err := storage.GetBySyncState(ctx, kbs.SyncStateNew)
+
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, kb.ID, pending[0].ID)
+	assert.Empty(t, pending[0].RemoteID)
+	assert.Nil(t, pending[0].LastSyncedOn)
+}
+
+func TestMarkAsSynced(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	err = storage.MarkAsSynced(ctx, kb.ID, "remote-1", kb.ContentHash())
+	require.NoError(t, err)
+
+	got, err := storage.GetByRemoteID(ctx, "remote-1")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, kb.ID, got.ID)
+	assert.Equal(t, kbs.SyncStateSynced, got.SyncState)
+	assert.NotNil(t, got.LastSyncedOn)
+	assert.Equal(t, kb.ContentHash(), got.SyncHash)
+
+	pending, err := storage.GetBySyncState(ctx, kbs.SyncStateNew)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestMarkAsSyncedNotFound(t *testing.T) {
+	storage := newTestDB(t)
+
+	err := storage.MarkAsSynced(context.Background(), "non-existent-id", "remote-1", "hash")
+
+	assert.ErrorIs(t, err, kbs.ErrKBNotFound)
+}
+
+func TestUpdateSyncedKBMarksItAsModified(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+	require.NoError(t, storage.MarkAsSynced(ctx, kb.ID, "remote-1", kb.ContentHash()))
+
+	kb.Value = "updated value"
+	require.NoError(t, storage.Update(ctx, &kb))
+
+	modified, err := storage.GetBySyncState(ctx, kbs.SyncStateModified)
+	require.NoError(t, err)
+	require.Len(t, modified, 1)
+	assert.Equal(t, "remote-1", modified[0].RemoteID)
+	assert.Equal(t, "updated value", modified[0].Value)
+}
+
+func TestUpdateNewKBKeepsItNew(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	kb.Value = "updated value"
+	require.NoError(t, storage.Update(ctx, &kb))
+
+	pending, err := storage.GetBySyncState(ctx, kbs.SyncStateNew)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+}
+
+func TestCreatePulledKB(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+	kb.SyncState = kbs.SyncStateSynced
+	kb.RemoteID = "remote-1"
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	got, err := storage.GetByRemoteID(ctx, "remote-1")
+
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, kbs.SyncStateSynced, got.SyncState)
+}
+
+func TestGetByRemoteIDNotFound(t *testing.T) {
+	storage := newTestDB(t)
+
+	got, err := storage.GetByRemoteID(context.Background(), "remote-1")
+
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+// ---- Sync queue ----
+
+func TestEnqueueAndGetSyncQueue(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	first := kbs.NewKB{Key: "first", Value: "one", Category: "test", Namespace: "default", Tags: []string{"a"}}
+	second := kbs.NewKB{Key: "second", Value: "two", Category: "test", Namespace: "default", Tags: []string{"b"}}
+
+	err := storage.Enqueue(ctx,
+		kbs.SyncQueueEntry{Operation: kbs.SyncOperationCreate, KB: first},
+		kbs.SyncQueueEntry{Operation: kbs.SyncOperationDelete, RemoteID: "remote-2", KB: second},
+	)
+	require.NoError(t, err)
+
+	entries, err := storage.GetSyncQueue(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, first, entries[0].KB)
+	assert.Equal(t, kbs.SyncOperationCreate, entries[0].Operation)
+	assert.Empty(t, entries[0].RemoteID)
+	assert.Equal(t, second, entries[1].KB)
+	assert.Equal(t, kbs.SyncOperationDelete, entries[1].Operation)
+	assert.Equal(t, "remote-2", entries[1].RemoteID)
+	assert.Zero(t, entries[0].Attempts)
+	assert.True(t, entries[0].Due(time.Now()))
+}
+
+func TestPostponeSyncQueueEntry(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	err := storage.Enqueue(ctx, kbs.SyncQueueEntry{Operation: kbs.SyncOperationCreate, KB: kbs.NewKB{Key: "first", Value: "one"}})
+	require.NoError(t, err)
+
+	entries, err := storage.GetSyncQueue(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	nextAttemptOn := time.Now().Add(time.Hour)
+	err = storage.PostponeEntry(ctx, entries[0].ID, "server error", nextAttemptOn)
+	require.NoError(t, err)
+
+	entries, err = storage.GetSyncQueue(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 1, entries[0].Attempts)
+	assert.Equal(t, "server error", entries[0].LastError)
+	assert.False(t, entries[0].Due(time.Now()))
+}
+
+func TestDequeueSyncQueueEntry(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	err := storage.Enqueue(ctx, kbs.SyncQueueEntry{Operation: kbs.SyncOperationCreate, KB: kbs.NewKB{Key: "first", Value: "one"}})
+	require.NoError(t, err)
+
+	entries, err := storage.GetSyncQueue(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	err = storage.Dequeue(ctx, entries[0].ID)
+	require.NoError(t, err)
+
+	entries, err = storage.GetSyncQueue(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	err = storage.Dequeue(ctx, 1)
+	assert.ErrorIs(t, err, kbs.ErrKBNotFound)
+}
+
+func TestPurgeSyncedKBQueuesItsDeletion(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+	require.NoError(t, storage.MarkAsSynced(ctx, kb.ID, "remote-1", kb.ContentHash()))
+	require.NoError(t, storage.Delete(ctx, kb.ID))
+
+	err = storage.Purge(ctx, kb.ID)
+	require.NoError(t, err)
+
+	// the deletion queued when it was moved to the trash is not queued again.
+	entries, err := storage.GetSyncQueue(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, kbs.SyncOperationDelete, entries[0].Operation)
+	assert.Equal(t, "remote-1", entries[0].RemoteID)
+	assert.Equal(t, kb.Key, entries[0].KB.Key)
+}
+
+func TestDeleteSyncedKBQueuesItsDeletion(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+	require.NoError(t, storage.MarkAsSynced(ctx, kb.ID, "remote-1", kb.ContentHash()))
+
+	err = storage.Delete(ctx, kb.ID)
+	require.NoError(t, err)
+
+	entries, err := storage.GetSyncQueue(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, kbs.SyncOperationDelete, entries[0].Operation)
+	assert.Equal(t, "remote-1", entries[0].RemoteID)
+	assert.Equal(t, kb.Key, entries[0].KB.Key)
+}
+
+func TestDeleteNewKBQueuesNothing(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	err = storage.Delete(ctx, kb.ID)
+	require.NoError(t, err)
+
+	entries, err := storage.GetSyncQueue(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRestoreKBDropsQueuedDeletion(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+	require.NoError(t, storage.MarkAsSynced(ctx, kb.ID, "remote-1", kb.ContentHash()))
+	require.NoError(t, storage.Delete(ctx, kb.ID))
+
+	err = storage.Restore(ctx, kb.ID)
+	require.NoError(t, err)
+
+	entries, err := storage.GetSyncQueue(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestPurgeQueuesDeletionOfKBTrashedByPreviousVersions(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+	require.NoError(t, storage.Delete(ctx, kb.ID))
+	// linked while it was in the trash, so its deletion was never queued.
+	require.NoError(t, storage.MarkAsSynced(ctx, kb.ID, "remote-1", kb.ContentHash()))
+
+	err = storage.Purge(ctx, kb.ID)
+	require.NoError(t, err)
+
+	entries, err := storage.GetSyncQueue(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "remote-1", entries[0].RemoteID)
+}
+
+func TestMarkAsDeletedOnServer(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+	require.NoError(t, storage.MarkAsSynced(ctx, kb.ID, "remote-1", kb.ContentHash()))
+
+	err = storage.MarkAsDeletedOnServer(ctx, "remote-1")
+	require.NoError(t, err)
+
+	got, err := storage.GetByID(ctx, kb.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Empty(t, got.RemoteID)
+	assert.Equal(t, kbs.SyncStateNew, got.SyncState)
+
+	// nothing is linked to it anymore.
+	err = storage.MarkAsDeletedOnServer(ctx, "remote-1")
+	require.NoError(t, err)
+}
+
+func TestEmptyTrashQueuesDeletionOfSyncedKBs(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	for i := range 3 {
+		kb := kbs.KB{
+			ID:        fmt.Sprintf("test-uuid-trash-%04d", i),
+			Key:       fmt.Sprintf("trash-%d", i),
+			Value:     "Value",
+			Category:  "test",
+			Namespace: "testns",
+			Tags:      []string{"tag"},
+		}
+		_, err := storage.Create(ctx, kb)
+		require.NoError(t, err)
+
+		// only the first two exist on the server
+		if i < 2 {
+			require.NoError(t, storage.MarkAsSynced(ctx, kb.ID, fmt.Sprintf("remote-%d", i), kb.ContentHash()))
+		}
+
+		require.NoError(t, storage.Delete(ctx, kb.ID))
+	}
+
+	purged, err := storage.EmptyTrash(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+
+	entries, err := storage.GetSyncQueue(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	for _, entry := range entries {
+		assert.Equal(t, kbs.SyncOperationDelete, entry.Operation)
+		assert.NotEmpty(t, entry.RemoteID)
+	}
+}
+
+// ---- Namespaces ----
+
+func createKBsInNamespaces(t *testing.T, storage *storages.SQLite, namespaces ...string) {
+	t.Helper()
+
+	for i, namespace := range namespaces {
+		kb := makeTestKB()
+		kb.ID = fmt.Sprintf("namespaced-id-%d", i)
+		kb.Key = fmt.Sprintf("namespaced-key-%d", i)
+		kb.Namespace = namespace
+
+		_, err := storage.Create(context.Background(), kb)
+		require.NoError(t, err)
+	}
+}
+
+func TestSearchByNamespaceWithSubNamespaces(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	createKBsInNamespaces(t, storage, "team", "team/backend", "team/backend/payments", "team/backend-old", "teams")
+
+	exact, err := storage.Search(ctx, kbs.KBQueryFilter{Namespace: "team/backend", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, exact.Total)
+
+	found, err := storage.Search(ctx, kbs.KBQueryFilter{Namespace: "team/backend", IncludeSubNamespaces: true, Limit: 10})
+	require.NoError(t, err)
+
+	keys := make([]string, 0, len(found.Items))
+	for _, item := range found.Items {
+		keys = append(keys, item.Key)
+	}
+
+	// sibling namespaces that only share the prefix are not descendants.
+	assert.ElementsMatch(t, []string{"namespaced-key-1", "namespaced-key-2"}, keys)
+
+	all, err := storage.GetAll(ctx, kbs.KBQueryFilter{Namespace: "team", IncludeSubNamespaces: true, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 4, all.Total)
+}
+
+func TestGetNamespaceCounts(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	createKBsInNamespaces(t, storage, "team/backend", "team/backend", "default", "team")
+	// kbs in the trash are not counted.
+	require.NoError(t, storage.Delete(ctx, "namespaced-id-3"))
+
+	got, err := storage.GetNamespaceCounts(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, []kbs.NamespaceCount{{Namespace: "default", Count: 1}, {Namespace: "team/backend", Count: 2}}, got)
+}
+
+func TestMoveNamespace(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	createKBsInNamespaces(t, storage, "team/backend", "team/backend/payments", "team/backend-old", "team")
+	require.NoError(t, storage.MarkAsSynced(ctx, "namespaced-id-1", "remote-1", "hash"))
+
+	moved, err := storage.MoveNamespace(ctx, "team/backend", "platform/backend")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), moved)
+
+	got, err := storage.GetNamespaceCounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []kbs.NamespaceCount{
+		{Namespace: "platform/backend", Count: 1},
+		{Namespace: "platform/backend/payments", Count: 1},
+		{Namespace: "team", Count: 1},
+		{Namespace: "team/backend-old", Count: 1},
+	}, got)
+
+	payments, err := storage.GetByID(ctx, "namespaced-id-1")
+	require.NoError(t, err)
+	assert.Equal(t, kbs.SyncStateModified, payments.SyncState)
+}
+
+func TestMoveNamespaceLeavesKBsInTrash(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	createKBsInNamespaces(t, storage, "team/backend", "team/backend/payments", "team/backend")
+	require.NoError(t, storage.Delete(ctx, "namespaced-id-2"))
+
+	moved, err := storage.MoveNamespace(ctx, "team/backend", "team/core")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), moved)
+
+	trashed, err := storage.GetTrashedByID(ctx, "namespaced-id-2")
+	require.NoError(t, err)
+	require.NotNil(t, trashed)
+	assert.Equal(t, "team/backend", trashed.Namespace)
+}
+
+// ---- Categories ----
+
+func TestGetCategoriesHasDefaultCategories(t *testing.T) {
+	storage := newTestDB(t)
+
+	got, err := storage.GetCategories(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []kbs.Category{
+		{Name: kbs.BookmarkCategory, Description: "links to web pages", ValueFormat: kbs.ValueFormatURL},
+		{Name: kbs.MediaCategory, Description: "images, videos and documents that can be saved locally", ValueFormat: kbs.ValueFormatMedia},
+		{Name: kbs.QuoteCategory, Description: "quotes of an author", RequiredFields: []string{kbs.ReferenceField}, ValueFormat: kbs.ValueFormatText},
+	}, got)
+}
+
+func TestSaveCategory(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	command := kbs.Category{Name: "command", Description: "shell commands", ValueFormat: kbs.ValueFormatText}
+	quote := kbs.Category{Name: kbs.QuoteCategory, RequiredFields: []string{kbs.NotesField, kbs.ReferenceField}, ValueFormat: kbs.ValueFormatText}
+
+	require.NoError(t, storage.SaveCategory(ctx, command))
+	// saving an existing category replaces its rules.
+	require.NoError(t, storage.SaveCategory(ctx, quote))
+
+	got, err := storage.GetCategories(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, 4)
+	assert.Equal(t, command, got[1])
+	assert.Equal(t, quote, got[3])
+}
+
+func TestRegisterCategory(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, storage.RegisterCategory(ctx, "recipes"))
+	// registering an existing category keeps its rules.
+	require.NoError(t, storage.RegisterCategory(ctx, kbs.QuoteCategory))
+
+	got, err := storage.GetCategories(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, 4)
+	assert.Equal(t, kbs.Category{Name: kbs.QuoteCategory, Description: "quotes of an author", RequiredFields: []string{kbs.ReferenceField}, ValueFormat: kbs.ValueFormatText}, got[2])
+	assert.Equal(t, kbs.Category{Name: "recipes", ValueFormat: kbs.ValueFormatText}, got[3])
+}
+
+// ---- Revisions ----
+
+func TestUpdateSavesRevisions(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	original := makeTestKB()
+	_, err := storage.Create(ctx, original)
+	require.NoError(t, err)
+
+	changed := original
+	changed.Value = "new value"
+	changed.Notes = "new notes"
+	require.NoError(t, storage.Update(ctx, &changed))
+	// an update that changes nothing does not save a revision.
+	require.NoError(t, storage.Update(ctx, &changed))
+
+	retagged := changed
+	retagged.Tags = []string{"bitcoin"}
+	require.NoError(t, storage.Update(ctx, &retagged))
+
+	got, err := storage.GetRevisions(ctx, original.ID)
+
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, 1, got[0].Number)
+	assert.Equal(t, []string{kbs.ValueField, kbs.NotesField}, got[0].ChangedFields)
+	assert.Equal(t, original.Value, got[0].KB.Value)
+	assert.Equal(t, original.Notes, got[0].KB.Notes)
+	assert.Equal(t, original.Tags, got[0].KB.Tags)
+	assert.False(t, got[0].CreatedOn.IsZero())
+	assert.Equal(t, 2, got[1].Number)
+	assert.Equal(t, []string{kbs.TagsField}, got[1].ChangedFields)
+	assert.Equal(t, "new value", got[1].KB.Value)
+}
+
+func TestPurgeRemovesRevisions(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	kb.Value = "new value"
+	require.NoError(t, storage.Update(ctx, &kb))
+	require.NoError(t, storage.Delete(ctx, kb.ID))
+	require.NoError(t, storage.Purge(ctx, kb.ID))
+
+	// a kb created again with the same id starts a new history.
+	_, err = storage.Create(ctx, makeTestKB())
+	require.NoError(t, err)
+
+	got, err := storage.GetRevisions(ctx, kb.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+// ---- Access stats ----
+
+func TestMarkAsAccessed(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.MarkAsAccessed(ctx, kb.ID))
+	require.NoError(t, storage.MarkAsAccessed(ctx, kb.ID))
+
+	got, err := storage.GetByID(ctx, kb.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, 2, got.AccessCount)
+	assert.NotNil(t, got.LastAccessedOn)
+	assert.False(t, got.CreatedOn.IsZero())
+	assert.Nil(t, got.UpdatedOn)
+	// opening a kb is not a change that must be synced.
+	assert.Equal(t, kbs.SyncStateNew, got.SyncState)
+}
+
+func TestMarkAsAccessedNotFound(t *testing.T) {
+	storage := newTestDB(t)
+
+	err := storage.MarkAsAccessed(context.Background(), "non-existent-id")
+
+	assert.ErrorIs(t, err, kbs.ErrKBNotFound)
+}
+
+func TestUpdateKBSetsUpdatedOnOnlyIfItChanged(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	kb := makeTestKB()
+	_, err := storage.Create(ctx, kb)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.Update(ctx, &kb))
+
+	got, err := storage.GetByID(ctx, kb.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.UpdatedOn)
+
+	kb.Value = "new value"
+	require.NoError(t, storage.Update(ctx, &kb))
+
+	got, err = storage.GetByID(ctx, kb.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, got.UpdatedOn)
+}
+
+func TestGetAllSorted(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"charlie", "alpha", "bravo"} {
+		kb := makeTestKB()
+		kb.ID = key + "-id"
+		kb.Key = key
+		_, err := storage.Create(ctx, kb)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, storage.MarkAsAccessed(ctx, "bravo-id"))
+	require.NoError(t, storage.MarkAsAccessed(ctx, "bravo-id"))
+	require.NoError(t, storage.MarkAsAccessed(ctx, "alpha-id"))
+
+	cases := []struct {
+		sortBy kbs.SortBy
+		order  kbs.SortOrder
+		want   []string
+	}{
+		{want: []string{"charlie", "alpha", "bravo"}},
+		{sortBy: kbs.SortByCreated, order: kbs.SortDescending, want: []string{"bravo", "alpha", "charlie"}},
+		// relevance is only possible for full text searches.
+		{sortBy: kbs.SortByRelevance, want: []string{"charlie", "alpha", "bravo"}},
+		{sortBy: kbs.SortByAlphabetical, want: []string{"alpha", "bravo", "charlie"}},
+		{sortBy: kbs.SortByAlphabetical, order: kbs.SortDescending, want: []string{"charlie", "bravo", "alpha"}},
+		{sortBy: kbs.SortByMostUsed, want: []string{"bravo", "alpha", "charlie"}},
+		{sortBy: kbs.SortByMostUsed, order: kbs.SortAscending, want: []string{"charlie", "alpha", "bravo"}},
+		{sortBy: kbs.SortByRecent, want: []string{"alpha", "bravo", "charlie"}},
+	}
+
+	for _, tc := range cases {
+		result, err := storage.GetAll(ctx, kbs.KBQueryFilter{SortBy: tc.sortBy, SortOrder: tc.order, Limit: 10})
+		require.NoError(t, err)
+
+		assert.Equal(t, tc.want, kbKeys(result.KBs), "%s %s", tc.sortBy, tc.order)
+	}
+}
+
+func TestGetAllPagesWithTiesKeepTheirOrder(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	// none of the kbs was opened, so they tie when sorted by use.
+	for i := range 5 {
+		kb := makeTestKB()
+		kb.ID = fmt.Sprintf("tie-id-%d", i)
+		kb.Key = fmt.Sprintf("tie-%d", i)
+		_, err := storage.Create(ctx, kb)
+		require.NoError(t, err)
+	}
+
+	var keys []string
+
+	for offset := uint32(0); offset < 5; offset += 2 {
+		page, err := storage.GetAll(ctx, kbs.KBQueryFilter{SortBy: kbs.SortByMostUsed, Limit: 2, Offset: offset})
+		require.NoError(t, err)
+
+		keys = append(keys, kbKeys(page.KBs)...)
+	}
+
+	assert.Equal(t, []string{"tie-4", "tie-3", "tie-2", "tie-1", "tie-0"}, keys)
+}
+
+// ---- GetAllAfter ----
+
+func TestGetAllAfterReadsEveryPage(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	createKBsInNamespaces(t, storage, "a", "b", "a", "a", "c", "a")
+
+	filter := kbs.KBQueryFilter{Namespace: "a", Limit: 2}
+
+	first, err := storage.GetAllAfter(ctx, filter, "")
+	require.NoError(t, err)
+	assert.Equal(t, 4, first.Total)
+	assert.Equal(t, []string{"namespaced-key-0", "namespaced-key-2"}, kbKeys(first.KBs))
+	require.NotEmpty(t, first.Next)
+
+	// a kb deleted meanwhile does not make the next page skip any kb.
+	require.NoError(t, storage.Delete(ctx, "namespaced-id-0"))
+
+	second, err := storage.GetAllAfter(ctx, filter, first.Next)
+	require.NoError(t, err)
+	assert.Zero(t, second.Total)
+	assert.Equal(t, []string{"namespaced-key-3", "namespaced-key-5"}, kbKeys(second.KBs))
+
+	last, err := storage.GetAllAfter(ctx, filter, second.Next)
+	require.NoError(t, err)
+	assert.Empty(t, last.KBs)
+	assert.Empty(t, last.Next)
+}
+
+func TestGetAllAfterDescending(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+	createKBsInNamespaces(t, storage, "a", "a", "a")
+
+	filter := kbs.KBQueryFilter{SortOrder: kbs.SortDescending, Limit: 2}
+
+	first, err := storage.GetAllAfter(ctx, filter, "")
+	require.NoError(t, err)
+
+	second, err := storage.GetAllAfter(ctx, filter, first.Next)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"namespaced-key-2", "namespaced-key-1"}, kbKeys(first.KBs))
+	assert.Equal(t, []string{"namespaced-key-0"}, kbKeys(second.KBs))
+	assert.Empty(t, second.Next)
+}
+
+func TestGetAllAfterReadsEveryPageInTheSortOrder(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"echo", "alpha", "delta", "bravo", "charlie"} {
+		kb := makeTestKB()
+		kb.ID = key + "-id"
+		kb.Key = key
+		_, err := storage.Create(ctx, kb)
+		require.NoError(t, err)
+	}
+
+	// delta and bravo tie on use, the others were never opened.
+	require.NoError(t, storage.MarkAsAccessed(ctx, "delta-id"))
+	require.NoError(t, storage.MarkAsAccessed(ctx, "bravo-id"))
+	require.NoError(t, storage.MarkAsAccessed(ctx, "alpha-id"))
+	require.NoError(t, storage.MarkAsAccessed(ctx, "alpha-id"))
+
+	cases := []struct {
+		sortBy kbs.SortBy
+		order  kbs.SortOrder
+	}{
+		{sortBy: kbs.SortByAlphabetical},
+		{sortBy: kbs.SortByAlphabetical, order: kbs.SortDescending},
+		{sortBy: kbs.SortByMostUsed},
+		{sortBy: kbs.SortByMostUsed, order: kbs.SortAscending},
+		{sortBy: kbs.SortByRecent},
+		{sortBy: kbs.SortByRecent, order: kbs.SortAscending},
+	}
+
+	for _, tc := range cases {
+		filter := kbs.KBQueryFilter{SortBy: tc.sortBy, SortOrder: tc.order, Limit: 2}
+
+		all, err := storage.GetAll(ctx, kbs.KBQueryFilter{SortBy: tc.sortBy, SortOrder: tc.order, Limit: 10})
+		require.NoError(t, err)
+
+		var keys []string
+
+		cursor := ""
+
+		for range 5 {
+			page, err := storage.GetAllAfter(ctx, filter, cursor)
+			require.NoError(t, err)
+
+			keys = append(keys, kbKeys(page.KBs)...)
+
+			if page.Next == "" {
+				break
+			}
+
+			cursor = page.Next
+		}
+
+		assert.Equal(t, kbKeys(all.KBs), keys, "%s %s", tc.sortBy, tc.order)
+	}
+}
+
+func TestGetAllAfterWithFullTextSearch(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	values := []string{
+		"bitcoin",
+		"bitcoin bitcoin bitcoin",
+		"ethereum",
+		"bitcoin and ethereum",
+		"bitcoin bitcoin",
+		"bitcoin halving",
+	}
+
+	for i, value := range values {
+		kb := makeTestKB()
+		kb.ID = fmt.Sprintf("fts-id-%d", i)
+		kb.Key = fmt.Sprintf("fts-key-%d", i)
+		kb.Value = value
+		kb.Notes = "notes"
+		kb.Reference = ""
+		kb.Tags = []string{"crypto"}
+		_, err := storage.Create(ctx, kb)
+		require.NoError(t, err)
+	}
+
+	filters := []kbs.KBQueryFilter{
+		{Keyword: "crypto"},
+		{Query: "bitcoin"},
+		{Query: "bitcoin", SortBy: kbs.SortByRelevance, SortOrder: kbs.SortAscending},
+		{Query: "bitcoin", SortBy: kbs.SortByAlphabetical},
+	}
+
+	for _, filter := range filters {
+		all := filter
+		all.Limit = 10
+
+		want, err := storage.Search(ctx, all)
+		require.NoError(t, err)
+
+		filter.Limit = 2
+
+		var keys []string
+
+		cursor := ""
+
+		for range 5 {
+			page, err := storage.GetAllAfter(ctx, filter, cursor)
+			require.NoError(t, err, "%+v", filter)
+
+			if cursor == "" {
+				assert.Equal(t, want.Total, page.Total)
+			}
+
+			keys = append(keys, kbKeys(page.KBs)...)
+
+			if page.Next == "" {
+				break
+			}
+
+			cursor = page.Next
+		}
+
+		wantKeys := make([]string, 0, len(want.Items))
+		for _, item := range want.Items {
+			wantKeys = append(wantKeys, item.Key)
+		}
+
+		assert.NotEmpty(t, keys)
+		assert.Equal(t, wantKeys, keys, "%+v", filter)
+	}
+}
+
+func TestGetAllAfterInvalidCursor(t *testing.T) {
+	storage := newTestDB(t)
+
+	_, err := storage.GetAllAfter(context.Background(), kbs.KBQueryFilter{Limit: 2}, "page-2")
+	assert.Error(t, err)
+
+	// the cursor of another sort order has other values.
+	_, err = storage.GetAllAfter(context.Background(), kbs.KBQueryFilter{SortBy: kbs.SortByAlphabetical, Limit: 2}, "[7]")
+	assert.Error(t, err)
+}
+
+func kbKeys(kbItems []kbs.KB) []string {
+	keys := make([]string, 0, len(kbItems))
+	for _, kb := range kbItems {
+		keys = append(keys, kb.Key)
+	}
+
+	return keys
+}
+
+func TestSearchByQuerySorted(t *testing.T) {
+	storage := newTestDB(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"bravo", "alpha"} {
+		kb := makeTestKB()
+		kb.ID = key + "-id"
+		kb.Key = key
+		_, err := storage.Create(ctx, kb)
+		require.NoError(t, err)
+	}
+
+	result, err := storage.Search(ctx, kbs.KBQueryFilter{Query: "bitcoins", SortBy: kbs.SortByAlphabetical, Limit: 10})
+
+	require.NoError(t, err)
+	require.Len(t, result.Items, 2)
+	assert.Equal(t, "alpha", result.Items[0].Key)
+	assert.Equal(t, "bravo", result.Items[1].Key)
+}
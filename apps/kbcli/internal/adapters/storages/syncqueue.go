@@ -0,0 +1,157 @@
+package storages
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+)
+
+type syncQueueEntry struct {
+	ID            int64
+	Operation     string
+	RemoteID      sql.NullString
+	Key           string
+	Payload       string
+	Attempts      int
+	LastError     sql.NullString
+	NextAttemptOn time.Time
+	CreatedOn     time.Time
+}
+
+const (
+	enqueueSQL = `INSERT INTO sync_queue
+	(OPERATION, REMOTE_ID, KB_KEY, PAYLOAD, NEXT_ATTEMPT_ON, CREATED_ON)
+VALUES
+	(?, ?, ?, ?, ?, ?)`
+	// kbs moved to the trash that exist on the server must be deleted there too.
+	enqueueDeletedKBSQL = `INSERT INTO sync_queue
+	(OPERATION, REMOTE_ID, KB_KEY, PAYLOAD, NEXT_ATTEMPT_ON, CREATED_ON)
+SELECT ?, REMOTE_ID, KB_KEY, json_object('key', KB_KEY), ?, ?
+FROM kbs
+WHERE KB_ID = ? AND DELETED_ON IS NULL AND REMOTE_ID IS NOT NULL`
+	// purged kbs whose deletion is not queued yet, e.g. they were moved to the
+	// trash by previous versions.
+	enqueuePurgedKBsSQL = `INSERT INTO sync_queue
+	(OPERATION, REMOTE_ID, KB_KEY, PAYLOAD, NEXT_ATTEMPT_ON, CREATED_ON)
+SELECT ?, REMOTE_ID, KB_KEY, json_object('key', KB_KEY), ?, ?
+FROM kbs
+WHERE DELETED_ON IS NOT NULL AND REMOTE_ID IS NOT NULL
+AND REMOTE_ID NOT IN (SELECT REMOTE_ID FROM sync_queue WHERE OPERATION = ? AND REMOTE_ID IS NOT NULL)`
+	enqueuePurgedKBSQL = enqueuePurgedKBsSQL + " AND KB_ID = ?"
+	// a restored kb must not be deleted on the server anymore.
+	dequeueRestoredKBSQL = `DELETE FROM sync_queue
+WHERE OPERATION = ? AND REMOTE_ID IN (SELECT REMOTE_ID FROM kbs WHERE KB_ID = ? AND DELETED_ON IS NOT NULL)`
+	querySyncQueueSQL = "SELECT ENTRY_ID, OPERATION, REMOTE_ID, KB_KEY, PAYLOAD, ATTEMPTS, LAST_ERROR, NEXT_ATTEMPT_ON, CREATED_ON FROM sync_queue ORDER BY ENTRY_ID"
+	dequeueSQL        = "DELETE FROM sync_queue WHERE ENTRY_ID = ?"
+	postponeEntrySQL  = "UPDATE sync_queue SET ATTEMPTS = ATTEMPTS + 1, LAST_ERROR = ?, NEXT_ATTEMPT_ON = ? WHERE ENTRY_ID = ?"
+)
+
+// Enqueue adds the given entries to the sync queue in one transaction, so all or none are queued.
+func (s *SQLite) Enqueue(ctx context.Context, entries ...kbs.SyncQueueEntry) error {
+	return s.inTransaction(ctx, func(tx *SQLite) error {
+		now := time.Now().UTC()
+
+		for _, entry := range entries {
+			payload, err := json.Marshal(entry.KB)
+			if err != nil {
+				return fmt.Errorf("unable to marshal kb %q: %w", entry.KB.Key, err)
+			}
+
+			remoteID := sql.NullString{
+				String: entry.RemoteID,
+				Valid:  entry.RemoteID != "",
+			}
+
+			_, err = tx.conn.ExecContext(ctx, enqueueSQL, string(entry.Operation), remoteID, entry.KB.Key, string(payload), now, now)
+			if err != nil {
+				return fmt.Errorf("unable to enqueue %s of kb %q: %w", entry.Operation, entry.KB.Key, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetSyncQueue gets all entries in the sync queue, in the order they were queued.
+func (s *SQLite) GetSyncQueue(ctx context.Context) ([]kbs.SyncQueueEntry, error) {
+	rows, err := s.conn.QueryContext(ctx, querySyncQueueSQL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query sync queue: %w", err)
+	}
+
+	defer rows.Close()
+
+	entries := make([]syncQueueEntry, 0)
+
+	for rows.Next() {
+		var entry syncQueueEntry
+
+		err := rows.Scan(&entry.ID, &entry.Operation, &entry.RemoteID, &entry.Key, &entry.Payload, &entry.Attempts, &entry.LastError, &entry.NextAttemptOn, &entry.CreatedOn)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan sync queue entry: %w", err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sync queue query had some errors: %w", err)
+	}
+
+	result := make([]kbs.SyncQueueEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		queueEntry, err := entry.toSyncQueueEntry()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read sync queue entry %d: %w", entry.ID, err)
+		}
+
+		result = append(result, queueEntry)
+	}
+
+	return result, nil
+}
+
+// Dequeue removes the given entry from the sync queue.
+func (s *SQLite) Dequeue(ctx context.Context, id int64) error {
+	err := s.execByID(ctx, dequeueSQL, id)
+	if err != nil {
+		return fmt.Errorf("unable to dequeue sync entry %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// PostponeEntry records a failed attempt of the given entry and when it must be tried again.
+func (s *SQLite) PostponeEntry(ctx context.Context, id int64, lastError string, nextAttemptOn time.Time) error {
+	err := s.execByID(ctx, postponeEntrySQL, lastError, nextAttemptOn.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("unable to postpone sync entry %d: %w", id, err)
+	}
+
+	return nil
+}
+
+func (e syncQueueEntry) toSyncQueueEntry() (kbs.SyncQueueEntry, error) {
+	var newKB kbs.NewKB
+
+	err := json.Unmarshal([]byte(e.Payload), &newKB)
+	if err != nil {
+		return kbs.SyncQueueEntry{}, fmt.Errorf("unable to unmarshal payload: %w", err)
+	}
+
+	return kbs.SyncQueueEntry{
+		ID:            e.ID,
+		Operation:     kbs.SyncOperation(e.Operation),
+		RemoteID:      e.RemoteID.String,
+		KB:            newKB,
+		Attempts:      e.Attempts,
+		LastError:     e.LastError.String,
+		NextAttemptOn: e.NextAttemptOn,
+		CreatedOn:     e.CreatedOn,
+	}, nil
+}
@@ -0,0 +1,226 @@
+package storages
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+)
+
+const (
+	queryTagCountsSQL = `SELECT t.NAME, COUNT(*) FROM tags t
+JOIN kb_tags kt ON (kt.TAG_ID = t.TAG_ID)
+JOIN kbs k ON (k.INTERNAL_ID = kt.KB_INTERNAL_ID)
+WHERE k.DELETED_ON IS NULL
+GROUP BY t.TAG_ID
+ORDER BY COUNT(*) DESC, t.NAME`
+	queryKBsWithTagsSQL = `SELECT DISTINCT k.KB_ID, k.TAG_VALUES FROM kbs k
+JOIN kb_tags kt ON (kt.KB_INTERNAL_ID = k.INTERNAL_ID)
+JOIN tags t ON (t.TAG_ID = kt.TAG_ID)
+WHERE t.NAME IN (SELECT value FROM json_each(?)) AND k.DELETED_ON IS NULL`
+	// changing the tags of a synced kb means it has local changes that must be pushed.
+	updateTagValuesSQL = `UPDATE kbs
+SET TAG_VALUES = ?, UPDATED_ON = ?,
+	SYNC_STATE = CASE SYNC_STATE WHEN 'synced' THEN 'modified' ELSE SYNC_STATE END
+WHERE KB_ID = ?`
+
+	// tags are given as a json array, so each statement takes a fixed number of arguments.
+	deleteKBTagsSQL = `DELETE FROM kb_tags
+WHERE KB_INTERNAL_ID = (SELECT INTERNAL_ID FROM kbs WHERE KB_ID = ?)
+AND TAG_ID NOT IN (SELECT t.TAG_ID FROM tags t WHERE t.NAME IN (SELECT value FROM json_each(?)))`
+	insertTagsSQL   = "INSERT OR IGNORE INTO tags (NAME) SELECT value FROM json_each(?)"
+	insertKBTagsSQL = `INSERT OR IGNORE INTO kb_tags (KB_INTERNAL_ID, TAG_ID)
+SELECT k.INTERNAL_ID, t.TAG_ID FROM kbs k, tags t
+WHERE k.KB_ID = ? AND t.NAME IN (SELECT value FROM json_each(?))`
+
+	// kbs with any of the given tags.
+	anyTagsSubquerySQL = `SELECT kt.KB_INTERNAL_ID FROM kb_tags kt
+JOIN tags tg ON (tg.TAG_ID = kt.TAG_ID)
+WHERE tg.NAME IN (SELECT value FROM json_each(%[1]s))`
+	// kbs with all the given tags, which must not be repeated.
+	allTagsSubquerySQL = anyTagsSubquerySQL + `
+GROUP BY kt.KB_INTERNAL_ID
+HAVING COUNT(*) = json_array_length(%[1]s)`
+)
+
+// GetTagCounts gets every tag of active kbs with the number of kbs that have it,
+// the most used first.
+func (s *SQLite) GetTagCounts(ctx context.Context) ([]kbs.TagCount, error) {
+	rows, err := s.conn.QueryContext(ctx, queryTagCountsSQL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query tags: %w", err)
+	}
+
+	defer rows.Close()
+
+	result := make([]kbs.TagCount, 0)
+
+	for rows.Next() {
+		var tagCount kbs.TagCount
+
+		err := rows.Scan(&tagCount.Tag, &tagCount.Count)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read tags: %w", err)
+		}
+
+		result = append(result, tagCount)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read tags: %w", err)
+	}
+
+	return result, nil
+}
+
+// ReplaceTags replaces the old tags with the new one in every kb that has any
+// of them, in one transaction. Kbs in the trash are left as they are. It
+// returns the number of kbs that were changed.
+func (s *SQLite) ReplaceTags(ctx context.Context, oldTags []string, newTag string) (int64, error) {
+	var changed int64
+
+	err := s.inTransaction(ctx, func(tx *SQLite) error {
+		tagValues, err := tx.collectKBsWithTags(ctx, oldTags)
+		if err != nil {
+			return err
+		}
+
+		for id, values := range tagValues {
+			newTags := replaceTags(strings.Fields(values), oldTags, newTag)
+
+			_, err := tx.conn.ExecContext(ctx, updateTagValuesSQL, strings.Join(newTags, aSpace), time.Now().UTC(), id)
+			if err != nil {
+				return fmt.Errorf("unable to update tags of kb %q: %w", id, err)
+			}
+
+			err = tx.saveKBTags(ctx, id, newTags)
+			if err != nil {
+				return err
+			}
+
+			changed++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to replace tags: %w", err)
+	}
+
+	return changed, nil
+}
+
+// collectKBsWithTags returns the tag values of the kbs with any of the given tags, by kb id.
+func (s *SQLite) collectKBsWithTags(ctx context.Context, tags []string) (map[string]string, error) {
+	tagsParam, err := toJSONArray(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn.QueryContext(ctx, queryKBsWithTagsSQL, tagsParam)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query kbs with tags %q: %w", tags, err)
+	}
+
+	defer rows.Close()
+
+	tagValues := make(map[string]string)
+
+	for rows.Next() {
+		var id, values string
+
+		err := rows.Scan(&id, &values)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read kbs with tags %q: %w", tags, err)
+		}
+
+		tagValues[id] = values
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read kbs with tags %q: %w", tags, err)
+	}
+
+	return tagValues, nil
+}
+
+// saveKBTags makes the given tags the only ones of the kb in kb_tags. It must run in a transaction.
+func (s *SQLite) saveKBTags(ctx context.Context, kbID string, tags []string) error {
+	// tag values are split on spaces, so kb_tags must split them the same way.
+	tagsParam, err := toJSONArray(strings.Fields(strings.Join(tags, aSpace)))
+	if err != nil {
+		return err
+	}
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{query: deleteKBTagsSQL, args: []any{kbID, tagsParam}},
+		{query: insertTagsSQL, args: []any{tagsParam}},
+		{query: insertKBTagsSQL, args: []any{kbID, tagsParam}},
+	}
+
+	for _, statement := range statements {
+		stmt, release, err := s.prepare(ctx, statement.query)
+		if err != nil {
+			return fmt.Errorf("unable to save tags of kb %q: %w", kbID, err)
+		}
+
+		_, err = stmt.ExecContext(ctx, statement.args...)
+
+		release()
+
+		if err != nil {
+			return fmt.Errorf("unable to save tags of kb %q: %w", kbID, err)
+		}
+	}
+
+	return nil
+}
+
+// tagsFilterParam returns the tags of a filter as the argument of the tag subqueries.
+func tagsFilterParam(tags []string) string {
+	uniqueTags := slices.Clone(tags)
+	slices.Sort(uniqueTags)
+
+	// the tags are plain strings, so they are always encoded.
+	param, _ := toJSONArray(slices.Compact(uniqueTags))
+
+	return param
+}
+
+// toJSONArray encodes the tags as a json array, the parameter expected by json_each.
+func toJSONArray(tags []string) (string, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+
+	result, err := json.Marshal(tags)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode tags: %w", err)
+	}
+
+	return string(result), nil
+}
+
+// replaceTags returns the tags with the old ones replaced by the new tag, sorted and without duplicates.
+func replaceTags(tags, oldTags []string, newTag string) []string {
+	result := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		if slices.Contains(oldTags, tag) {
+			tag = newTag
+		}
+
+		result = append(result, tag)
+	}
+
+	slices.Sort(result)
+
+	return slices.Compact(result)
+}
@@ -24,6 +24,7 @@ type addKBParams struct {
 	mediaType   string
 	rawTags     string
 	interactive bool
+	editor      bool
 	tags        []string
 }
 
@@ -58,6 +59,9 @@ const (
 var addKBData addKBParams
 var exitGUI bool
 
+// categories registered categories, used to know which ones hold media files.
+var categories kbs.CategoryRegistry
+
 func MakeAddCommand(service *kbs.Service) *cobra.Command {
 	newCmd := cobra.Command{
 		Use:   "add",
@@ -70,17 +74,28 @@ func MakeAddCommand(service *kbs.Service) *cobra.Command {
 	newCmd.PersistentFlags().StringVarP(&addKBData.value, "value", "v", "", "knowledge base value")
 	newCmd.PersistentFlags().StringVarP(&addKBData.notes, "notes", "o", "", "knowledge base notes")
 	newCmd.PersistentFlags().StringVarP(&addKBData.category, "category", "c", "", "category of knowledge base")
-	newCmd.PersistentFlags().StringVarP(&addKBData.namespace, "namespace", "n", "default", "namespace of knowledge base")
+	newCmd.PersistentFlags().StringVarP(&addKBData.namespace, "namespace", "n", kbs.DefaultNamespace, "namespace of knowledge base")
 	newCmd.PersistentFlags().StringVarP(&addKBData.reference, "reference", "r", "", "author or refence of this kb")
 	newCmd.PersistentFlags().StringSliceVarP(&addKBData.tags, "tags", "t", []string{}, "comma separated tags for this kb")
 	newCmd.PersistentFlags().BoolVarP(&addKBData.interactive, "ux", "u", false, "add KB in interactive mode")
+	newCmd.PersistentFlags().BoolVarP(&addKBData.editor, "editor", "e", false, cmds.EditorUsage)
 
 	return &newCmd
 }
 
 func makeRunAddKBCommand(service *kbs.Service) func(_ *cobra.Command, _ []string) {
 	return func(_ *cobra.Command, _ []string) {
-		err := collectData()
+		ctx := context.Background()
+
+		var err error
+
+		categories, err = service.Categories(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "getting categories", err)
+			os.Exit(1)
+		}
+
+		err = collectData(ctx, service)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "collecting data", err)
 			os.Exit(1)
@@ -90,8 +105,6 @@ func makeRunAddKBCommand(service *kbs.Service) func(_ *cobra.Command, _ []string
 			os.Exit(0)
 		}
 
-		ctx := context.Background()
-
 		for {
 			newKBToSave := addKBData.toNewKB()
 			if !confirmKBData(&newKBToSave) {
@@ -102,7 +115,7 @@ func makeRunAddKBCommand(service *kbs.Service) func(_ *cobra.Command, _ []string
 			newKB, err := service.Add(ctx, newKBToSave)
 			if errors.As(err, &kbs.DataError{}) {
 				printAddingKBError(newKBToSave, err)
-				if retry() {
+				if retry(ctx, service) {
 					continue
 				}
 
@@ -146,7 +159,18 @@ func makeRunAddKBCommand(service *kbs.Service) func(_ *cobra.Command, _ []string
 	}
 }
 
-func collectData() error {
+func collectData(ctx context.Context, service *kbs.Service) error {
+	if addKBData.editor {
+		err := editKB(ctx, service)
+		if err != nil {
+			return fmt.Errorf("unable to collect parameters: %w", err)
+		}
+
+		checkMediaType()
+
+		return nil
+	}
+
 	if addKBData.interactive {
 		err := runInteractive()
 		if err != nil {
@@ -163,6 +187,26 @@ func collectData() error {
 	return nil
 }
 
+// editKB lets the user write the kb in the editor, starting with the values of
+// the flags.
+func editKB(ctx context.Context, service *kbs.Service) error {
+	newKB, err := cmds.EditKB(addKBData.toKB(), func(newKB kbs.NewKB) error {
+		return service.Validate(ctx, newKB)
+	})
+	if errors.Is(err, cmds.ErrEditCanceled) {
+		exitGUI = true
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	addKBData.fromNewKB(newKB)
+
+	return nil
+}
+
 func printAddingKBError(newKBToSave kbs.NewKB, err error) {
 	fmt.Fprintln(os.Stderr, "unable to add new kb:", err)
 	fmt.Println()
@@ -170,12 +214,24 @@ func printAddingKBError(newKBToSave kbs.NewKB, err error) {
 	fmt.Println()
 }
 
-func retry() bool {
-	if wantToRetry() {
-		fillExistingFields()
-		return true
+func retry(ctx context.Context, service *kbs.Service) bool {
+	if !wantToRetry() {
+		return false
 	}
-	return false
+
+	if addKBData.editor {
+		err := editKB(ctx, service)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "editing kb:", err)
+			return false
+		}
+
+		return !exitGUI
+	}
+
+	fillExistingFields()
+
+	return true
 }
 
 func confirmKBData(newKB *kbs.NewKB) bool {
@@ -267,7 +323,7 @@ func isMediaTypeEmpty() bool {
 }
 
 func isMediaType() bool {
-	return strings.EqualFold(addKBData.category, kbs.MediaCategory)
+	return categories.IsMedia(addKBData.category)
 }
 
 func requestMediaTypeValue() string {
@@ -329,6 +385,31 @@ func (a addKBParams) toNewKB() kbs.NewKB {
 	return newKB
 }
 
+// toKB returns the values to fill the kb template of the editor.
+func (a addKBParams) toKB() kbs.KB {
+	return kbs.KB{
+		Key:       a.key,
+		Value:     a.value,
+		Notes:     a.notes,
+		Category:  a.category,
+		Namespace: a.namespace,
+		Reference: a.reference,
+		Tags:      slices.Clone(a.tags),
+	}
+}
+
+// fromNewKB takes the values of the kb written in the editor.
+func (a *addKBParams) fromNewKB(newKB kbs.NewKB) {
+	a.key = strings.ToLower(newKB.Key)
+	a.value = newKB.Value
+	a.notes = newKB.Notes
+	a.category = strings.ToLower(newKB.Category)
+	a.namespace = kbs.CleanNamespace(newKB.Namespace)
+	a.reference = newKB.Reference
+	a.tags = slices.Clone(newKB.Tags)
+	a.rawTags = strings.Join(newKB.Tags, " ")
+}
+
 func (a *addKBParams) buildTags() {
 	a.tags = strings.Split(strings.ToLower(a.rawTags), " ")
 }
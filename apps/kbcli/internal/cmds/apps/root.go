@@ -1,6 +1,7 @@
 package apps
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -9,11 +10,18 @@ import (
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/adapters/storages"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/adds"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/categories"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/dbs"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/deletes"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/exports"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/gets"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/imports"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/namespaces"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/revisions"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/setups"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/syncs"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/tags"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/trashes"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/updates"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/versions"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
@@ -121,6 +129,11 @@ func (a *Application) initializeStorage() error {
 
 	a.storage = storage
 
+	err = a.storage.InitializeDB(context.Background())
+	if err != nil {
+		return fmt.Errorf("unable to migrate database: %w", err)
+	}
+
 	return nil
 }
 
@@ -144,6 +157,7 @@ func (a *Application) initializeService() error {
 	serviceSetup := kbs.ServiceSetup{
 		KBClient:        a.kbkitClient,
 		KBStorage:       a.storage,
+		SyncQueue:       a.storage,
 		FileForSyncPath: a.configuration.FileForSyncPath,
 		DirForMediaPath: a.configuration.DirForMediaPath,
 	}
@@ -164,6 +178,15 @@ func (a *Application) initializeRootCommand() {
 	a.rootCommand.AddCommand(gets.MakeGetCommand(a.service))
 	a.rootCommand.AddCommand(syncs.MakeSyncCommand(a.service))
 	a.rootCommand.AddCommand(updates.MakeUpdateCommand(a.service))
+	a.rootCommand.AddCommand(deletes.MakeDeleteCommand(a.service))
+	a.rootCommand.AddCommand(revisions.MakeHistoryCommand(a.service))
+	a.rootCommand.AddCommand(revisions.MakeDiffCommand(a.service))
+	a.rootCommand.AddCommand(revisions.MakeRevertCommand(a.service))
+	a.rootCommand.AddCommand(trashes.MakeTrashCommand(a.service))
+	a.rootCommand.AddCommand(tags.MakeTagsCommand(a.service))
+	a.rootCommand.AddCommand(namespaces.MakeNamespacesCommand(a.service))
+	a.rootCommand.AddCommand(categories.MakeCategoriesCommand(a.service))
+	a.rootCommand.AddCommand(dbs.MakeDBCommand(a.storage))
 }
 
 func (a *Application) itIsSet() bool {
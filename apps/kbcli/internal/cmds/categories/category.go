@@ -0,0 +1,167 @@
+package categories
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+	"github.com/spf13/cobra"
+)
+
+// categoryParams contains parameters required by category commands.
+type categoryParams struct {
+	description    string
+	requiredFields []string
+	valueFormat    string
+}
+
+// field labels
+const (
+	categoriesLabel      = "Categories"
+	totalCategoriesLabel = "Total:"
+	nameCol              = "NAME"
+	nameColSeparator     = "----"
+	formatCol            = "VALUE"
+	formatColSeparator   = "-----"
+	requiredCol          = "REQUIRED"
+	requiredColSeparator = "--------"
+	descriptionCol       = "DESCRIPTION"
+	descriptionSeparator = "-----------"
+	noCategoriesMessage  = "there are no categories"
+	noRequiredFields     = "-"
+	categoryAddedMessage = "category %q was saved\n"
+)
+
+var categoryData categoryParams
+
+func MakeCategoriesCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "categories",
+		Short: "manage kb categories",
+		Long:  "list the categories kbs can have and add new ones with the fields their kbs require",
+		Run: func(cmd *cobra.Command, _ []string) {
+			if err := cmd.Help(); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+
+	newCmd.AddCommand(makeListCommand(service))
+	newCmd.AddCommand(makeAddCommand(service))
+
+	return &newCmd
+}
+
+func makeListCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "list",
+		Short: "list categories and their rules",
+		Long:  "list the categories kbs can have, with the format of their value and the fields they require",
+		Args:  cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			err := listCategories(context.Background(), service)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "listing categories:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	return &newCmd
+}
+
+func makeAddCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "add name",
+		Short: "add a category or change its rules",
+		Long: `add a category kbs can have. If the category exists, its rules are replaced.
+kbs of the category are validated with these rules when they are added, updated or imported.`,
+		Example: "kb categories add book --description \"books to read\" --required reference,notes",
+		Args:    cobra.ExactArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			err := addCategory(context.Background(), service, args[0])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "adding category:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	newCmd.Flags().StringVarP(&categoryData.description, "description", "d", "", "what kbs of this category are about")
+	newCmd.Flags().StringSliceVarP(&categoryData.requiredFields, "required", "", nil, "comma separated fields kbs of this category must have: "+kbs.NotesField+", "+kbs.ReferenceField)
+	newCmd.Flags().StringVarP(&categoryData.valueFormat, "value-format", "", string(kbs.ValueFormatText),
+		"format of the kb value: "+string(kbs.ValueFormatText)+", "+string(kbs.ValueFormatURL)+" or "+string(kbs.ValueFormatMedia))
+
+	return &newCmd
+}
+
+func listCategories(ctx context.Context, service *kbs.Service) error {
+	categories, err := service.ListCategories(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list categories: %w", err)
+	}
+
+	if len(categories) == 0 {
+		fmt.Println(noCategoriesMessage)
+		return nil
+	}
+
+	printCategories(categories)
+
+	return nil
+}
+
+func addCategory(ctx context.Context, service *kbs.Service, name string) error {
+	category, err := service.AddCategory(ctx, categoryData.toCategory(name))
+	if err != nil {
+		return fmt.Errorf("unable to add category %q: %w", name, err)
+	}
+
+	fmt.Printf(categoryAddedMessage, category.Name)
+
+	return nil
+}
+
+func (c categoryParams) toCategory(name string) kbs.Category {
+	return kbs.Category{
+		Name:           name,
+		Description:    c.description,
+		RequiredFields: c.requiredFields,
+		ValueFormat:    kbs.ValueFormat(c.valueFormat),
+	}
+}
+
+func printCategories(categories []kbs.Category) {
+	nameLength, requiredLength := len(nameCol), len(requiredCol)
+	for _, category := range categories {
+		nameLength = max(nameLength, len(category.Name))
+		requiredLength = max(requiredLength, len(requiredFields(category)))
+	}
+
+	formatLength := len(string(kbs.ValueFormatMedia))
+
+	fmt.Println()
+	fmt.Println(categoriesLabel)
+	fmt.Println(cmds.TitleSeparator)
+	fmt.Println(totalCategoriesLabel, len(categories))
+	fmt.Println()
+	fmt.Println(fmt.Sprintf("%-*s", nameLength, nameCol), fmt.Sprintf("%-*s", formatLength, formatCol),
+		fmt.Sprintf("%-*s", requiredLength, requiredCol), descriptionCol)
+	fmt.Println(fmt.Sprintf("%-*s", nameLength, nameColSeparator), fmt.Sprintf("%-*s", formatLength, formatColSeparator),
+		fmt.Sprintf("%-*s", requiredLength, requiredColSeparator), descriptionSeparator)
+	for _, category := range categories {
+		fmt.Println(fmt.Sprintf("%-*s", nameLength, category.Name), fmt.Sprintf("%-*s", formatLength, category.ValueFormat),
+			fmt.Sprintf("%-*s", requiredLength, requiredFields(category)), category.Description)
+	}
+}
+
+func requiredFields(category kbs.Category) string {
+	if len(category.RequiredFields) == 0 {
+		return noRequiredFields
+	}
+
+	return strings.Join(category.RequiredFields, ",")
+}
@@ -0,0 +1,144 @@
+package dbs
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/adapters/storages"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
+	"github.com/spf13/cobra"
+)
+
+// field labels
+const (
+	upToDateMessage          = "database is up to date"
+	appliedMigrationsLabel   = "Applied migrations"
+	migrationStatusLabel     = "Database migrations"
+	currentVersionLabel      = "Current version:"
+	latestVersionLabel       = "Latest version:"
+	versionCol               = "VERSION"
+	versionColSeparator      = "-------"
+	nameCol                  = "NAME"
+	nameColSeparator         = "----"
+	appliedOnCol             = "APPLIED ON"
+	appliedOnColSeparator    = "----------"
+	pendingMigrationLabel    = "pending"
+	appliedOnFormat          = "2006-01-02 15:04:05"
+	pendingMigrationsMessage = "%d pending migrations, run 'kb db migrate' to apply them\n"
+)
+
+func MakeDBCommand(storage *storages.SQLite) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "db",
+		Short: "manage the local database",
+		Long:  "check and apply schema migrations of the local kbkitt database",
+		Run: func(cmd *cobra.Command, _ []string) {
+			if err := cmd.Help(); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+
+	newCmd.AddCommand(makeMigrateCommand(storage))
+	newCmd.AddCommand(makeStatusCommand(storage))
+
+	return &newCmd
+}
+
+func makeMigrateCommand(storage *storages.SQLite) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "migrate",
+		Short: "apply pending schema migrations",
+		Long:  "apply in order the schema migrations that have not been applied to the local database",
+		Run: func(_ *cobra.Command, _ []string) {
+			err := migrate(context.Background(), storage)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "migrating database:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	return &newCmd
+}
+
+func makeStatusCommand(storage *storages.SQLite) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "status",
+		Short: "show schema migrations status",
+		Long:  "show the schema version of the local database and its pending migrations",
+		Run: func(_ *cobra.Command, _ []string) {
+			err := showStatus(context.Background(), storage)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "checking database status:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	return &newCmd
+}
+
+func migrate(ctx context.Context, storage *storages.SQLite) error {
+	applied, err := storage.Migrate(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to migrate: %w", err)
+	}
+
+	if len(applied) == 0 {
+		fmt.Println(upToDateMessage)
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Println(appliedMigrationsLabel)
+	fmt.Println(cmds.TitleSeparator)
+	printMigrations(applied)
+
+	return nil
+}
+
+func showStatus(ctx context.Context, storage *storages.SQLite) error {
+	status, err := storage.MigrationStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get status: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println(migrationStatusLabel)
+	fmt.Println(cmds.TitleSeparator)
+	fmt.Println(currentVersionLabel, status.Current)
+	fmt.Println(latestVersionLabel, status.Latest)
+	fmt.Println()
+	printMigrations(status.Migrations)
+	fmt.Println()
+
+	if status.UpToDate() {
+		fmt.Println(upToDateMessage)
+		return nil
+	}
+
+	fmt.Printf(pendingMigrationsMessage, len(status.Pending()))
+
+	return nil
+}
+
+func printMigrations(migrations []storages.Migration) {
+	length := len(nameCol)
+	for _, v := range migrations {
+		if len(v.Name) > length {
+			length = len(v.Name)
+		}
+	}
+
+	fmt.Println(fmt.Sprintf("%-7s", versionCol), fmt.Sprintf("%-*s", length, nameCol), appliedOnCol)
+	fmt.Println(fmt.Sprintf("%-7s", versionColSeparator), fmt.Sprintf("%-*s", length, nameColSeparator), appliedOnColSeparator)
+	for _, v := range migrations {
+		appliedOn := pendingMigrationLabel
+		if v.Applied() {
+			appliedOn = v.AppliedOn.Local().Format(appliedOnFormat)
+		}
+		fmt.Println(fmt.Sprintf("%-7d", v.Version), fmt.Sprintf("%-*s", length, v.Name), appliedOn)
+	}
+}
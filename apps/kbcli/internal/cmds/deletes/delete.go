@@ -0,0 +1,104 @@
+package deletes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+	"github.com/spf13/cobra"
+)
+
+// deleteKBParams contains parameters required by delete command.
+type deleteKBParams struct {
+	id  string
+	key string
+	yes bool
+}
+
+const (
+	deleteQuestionLabel   = "> do you want to move this kb to the trash? [y/n]: "
+	kbDeletedSuccessfully = "kb was moved to the trash, run 'kb trash restore --id %s' to undo it\n"
+	kbKeyLabel            = "key: "
+)
+
+var deleteKBData deleteKBParams
+
+func MakeDeleteCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "delete",
+		Short: "delete a kb",
+		Long:  "move a kb with the given id or key to the trash, it can be restored with the trash command",
+		Run:   makeRunDeleteCommand(service),
+	}
+
+	newCmd.PersistentFlags().StringVarP(&deleteKBData.id, "id", "i", "", "knowledge base id")
+	newCmd.PersistentFlags().StringVarP(&deleteKBData.key, "key", "k", "", "knowledge base key")
+	newCmd.PersistentFlags().BoolVarP(&deleteKBData.yes, "yes", "y", false, "do not ask for confirmation")
+
+	return &newCmd
+}
+
+func makeRunDeleteCommand(service *kbs.Service) func(_ *cobra.Command, _ []string) {
+	return func(_ *cobra.Command, _ []string) {
+		fillMissingDeleteFields()
+
+		ctx := context.Background()
+
+		err := deleteKB(ctx, service)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "deleting kb:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func deleteKB(ctx context.Context, service *kbs.Service) error {
+	kbToDelete, err := getKBToDelete(ctx, service)
+	if err != nil {
+		return fmt.Errorf("unable to delete kb: %w", err)
+	}
+
+	if kbToDelete == nil {
+		return errors.New("kb with given id or key does not exist")
+	}
+
+	fmt.Println()
+	fmt.Println(kbToDelete)
+	fmt.Println()
+
+	if !deleteKBData.yes && !cmds.AreYouSure(deleteQuestionLabel) {
+		fmt.Println("bye")
+		return nil
+	}
+
+	err = service.Delete(ctx, kbToDelete.ID)
+	if err != nil {
+		return fmt.Errorf("unable to delete kb: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Printf(kbDeletedSuccessfully, kbToDelete.ID)
+
+	return nil
+}
+
+func getKBToDelete(ctx context.Context, service *kbs.Service) (*kbs.KB, error) {
+	if !kbs.IsStringEmpty(deleteKBData.id) {
+		return service.GetByID(ctx, deleteKBData.id)
+	}
+
+	return service.GetByKey(ctx, deleteKBData.key)
+}
+
+func fillMissingDeleteFields() {
+	if !kbs.IsStringEmpty(deleteKBData.id) || !kbs.IsStringEmpty(deleteKBData.key) {
+		return
+	}
+
+	deleteKBData.key = cmds.RequestStringValue(kbKeyLabel)
+
+	fillMissingDeleteFields()
+}
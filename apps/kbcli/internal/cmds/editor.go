@@ -0,0 +1,115 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+)
+
+// editor values
+const (
+	defaultEditor     = "vi"
+	editorFilePattern = "kb-*.md"
+	editAgainLabel    = "> do you want to edit it again? [y/n]: "
+)
+
+// editorVariables environment variables that may name the editor, the first
+// one that is set wins.
+var editorVariables = []string{"VISUAL", "EDITOR"}
+
+// ErrEditCanceled is returned when the user does not want to fix an invalid kb.
+var ErrEditCanceled = errors.New("the kb was not edited")
+
+// EditKB opens the kb in the editor of the user as markdown, see kbs.KB.ToMarkdown,
+// and reads it back once the editor is closed. If it cannot be read or validate
+// says it is not valid, the user can edit it again without losing the changes.
+func EditKB(kb kbs.KB, validate func(newKB kbs.NewKB) error) (kbs.NewKB, error) {
+	content, err := kb.ToMarkdown()
+	if err != nil {
+		return kbs.NewKB{}, fmt.Errorf("unable to edit kb: %w", err)
+	}
+
+	for {
+		content, err = Edit(content)
+		if err != nil {
+			return kbs.NewKB{}, fmt.Errorf("unable to edit kb: %w", err)
+		}
+
+		newKB, err := kbs.ParseMarkdown(content)
+		if err == nil {
+			err = validate(newKB)
+			if err == nil {
+				return newKB, nil
+			}
+
+			if !errors.As(err, &kbs.DataError{}) {
+				return kbs.NewKB{}, fmt.Errorf("unable to edit kb: %w", err)
+			}
+		}
+
+		fmt.Fprintln(os.Stderr, "invalid kb:", err)
+		fmt.Println()
+
+		if !AreYouSure(editAgainLabel) {
+			return kbs.NewKB{}, ErrEditCanceled
+		}
+	}
+}
+
+// Edit writes the content in a temporary file, opens it in the editor of the
+// user and returns the content of the file once the editor is closed.
+func Edit(content []byte) ([]byte, error) {
+	file, err := os.CreateTemp("", editorFilePattern)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create file to edit: %w", err)
+	}
+
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(content)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("unable to write file to edit: %w", err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("unable to write file to edit: %w", err)
+	}
+
+	args := append(strings.Fields(editor()), file.Name())
+
+	//nolint:gosec // the editor is chosen by the user on purpose
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("unable to run editor %q: %w", args[0], err)
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("unable to read edited file: %w", err)
+	}
+
+	return edited, nil
+}
+
+// editor gets the editor command of the user, it may have arguments, e.g. "code --wait".
+func editor() string {
+	for _, variable := range editorVariables {
+		value := strings.TrimSpace(os.Getenv(variable))
+		if value != "" {
+			return value
+		}
+	}
+
+	return defaultEditor
+}
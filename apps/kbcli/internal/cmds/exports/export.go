@@ -2,9 +2,12 @@ package exports
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
 	"github.com/spf13/cobra"
 )
@@ -12,7 +15,12 @@ import (
 // exportKBParams contains parameters required by export command.
 type exportKBParams struct {
 	namespace string
+	recursive bool
 	category  string
+	format    string
+	dir       string
+	sort      string
+	order     string
 }
 
 // field labels
@@ -20,6 +28,8 @@ const (
 	namespaceLabel     = "namespace: "
 	categoryLabel      = "category: "
 	totalExportedLabel = "Total:"
+	exportingLabel     = "exporting"
+	canceledLabel      = "export was canceled, only these kbs were exported"
 )
 
 var exportKBData exportKBParams
@@ -27,13 +37,20 @@ var exportKBData exportKBParams
 func MakeExportCommand(service *kbs.Service) *cobra.Command {
 	newCmd := cobra.Command{
 		Use:   "export",
-		Short: "get knowledge bases in yaml format",
-		Long:  `get all knowledge bases in yaml format based on a given criteria`,
-		Run:   makeRunExportedKBCommand(service),
+		Short: "get knowledge bases in yaml, json, jsonl, csv or markdown format",
+		Long: `get all knowledge bases based on a given criteria in yaml, json, jsonl (one json object per line) or csv format.
+With markdown format, every kb is written in its own namespace/category/key.md file in the --dir folder.
+Ctrl-C stops the export after the current page, leaving the kbs exported so far in a valid file.`,
+		Run: makeRunExportedKBCommand(service),
 	}
 
 	newCmd.PersistentFlags().StringVarP(&exportKBData.namespace, "namespace", "n", "", "get all kbs with this namespace")
+	newCmd.PersistentFlags().BoolVarP(&exportKBData.recursive, "recursive", "r", false, "include kbs of the sub namespaces of the namespace")
 	newCmd.PersistentFlags().StringVarP(&exportKBData.category, "category", "c", "", "get all kbs with this category")
+	newCmd.PersistentFlags().StringVarP(&exportKBData.format, "format", "", string(kbs.DefaultFormat), "output format: "+strings.Join(exportFormats(), ", "))
+	newCmd.PersistentFlags().StringVarP(&exportKBData.dir, "dir", "", "", "folder where kbs are written with markdown format")
+	newCmd.PersistentFlags().StringVarP(&exportKBData.sort, "sort", "s", "", cmds.SortUsage)
+	newCmd.PersistentFlags().StringVarP(&exportKBData.order, "order", "", "", cmds.SortOrderUsage)
 
 	return &newCmd
 }
@@ -42,52 +59,122 @@ func makeRunExportedKBCommand(service *kbs.Service) func(_ *cobra.Command, _ []s
 	return func(_ *cobra.Command, _ []string) {
 		err := exportData(service)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, "failed to process import:", err)
+			fmt.Fprintln(os.Stderr, "failed to process export:", err)
 			os.Exit(1)
 		}
 	}
 }
 
 func exportData(service *kbs.Service) error {
-	ctx := context.Background()
+	filter, err := exportKBData.toGetAllKBFilter()
+	if err != nil {
+		return fmt.Errorf("unable to export kbs: %w", err)
+	}
 
-	total := 1 // hypotetical number
-	filter := exportKBData.toGetAllKBFilter()
+	encoder, err := exportKBData.newEncoder()
+	if err != nil {
+		return fmt.Errorf("unable to export kbs: %w", err)
+	}
 
-	for int(filter.Offset) <= total {
-		result, err := service.GetAllKBs(ctx, filter)
-		if err != nil {
-			return fmt.Errorf("unable to export kbs: %w", err)
-		}
+	options := kbs.ExportOptions{
+		Filter: filter,
+	}
+
+	var total int
+
+	export := func(ctx context.Context, bar *cmds.ProgressBar) error {
+		options.Progress = bar.Report
+
+		var err error
+		total, err = service.Export(ctx, encoder, options)
+
+		return err
+	}
+
+	// the progress bar would be mixed with the kbs written in the terminal.
+	if cmds.IsTerminal(os.Stdout) && !exportKBData.toFiles() {
+		err = cmds.RunCancelable(func(ctx context.Context) error {
+			return export(ctx, nil)
+		})
+	} else {
+		err = cmds.RunWithProgress(exportingLabel, export)
+	}
+
+	canceled := errors.Is(err, context.Canceled)
+	if err != nil && !canceled {
+		return fmt.Errorf("unable to export kbs: %w", err)
+	}
+
+	// the exported kbs are closed properly even if the export was canceled.
+	closeErr := encoder.Close()
+	if closeErr != nil {
+		return fmt.Errorf("unable to export kbs: %w", closeErr)
+	}
+
+	if canceled {
+		fmt.Fprintln(os.Stderr, canceledLabel)
+	}
+
+	if total == 0 && !canceled {
+		fmt.Fprintln(os.Stderr, "no records were found")
+		return nil
+	}
+
+	printExportedKBs(total)
+
+	return nil
+}
 
-		if result == nil {
-			fmt.Println("no records were found")
-			return nil
+// newEncoder creates the encoder of the given format. Markdown kbs are saved in
+// files, so nothing is written to stdout.
+func (e exportKBParams) newEncoder() (kbs.Encoder, error) {
+	if !e.toFiles() {
+		if !kbs.IsStringEmpty(e.dir) {
+			return nil, fmt.Errorf("--dir is only supported with %s format", kbs.FormatMarkdown)
 		}
 
-		kbData, err := toYAMLDocuments(result.KBs, filter.Offset == 0)
+		format, err := kbs.ParseFormat(e.format)
 		if err != nil {
-			return fmt.Errorf("unable to export kbs: %w", err)
+			return nil, err
 		}
 
-		fmt.Println(string(kbData))
+		return kbs.NewEncoder(format, os.Stdout)
+	}
 
-		total = result.Total
-		filter.Offset += filter.Limit
+	if kbs.IsStringEmpty(e.dir) {
+		return nil, fmt.Errorf("%s format requires --dir", kbs.FormatMarkdown)
 	}
 
-	printExportedKBs(total)
+	return &vaultEncoder{writer: kbs.NewVaultWriter(e.dir)}, nil
+}
 
-	return nil
+// toFiles indicates if kbs are written in files instead of stdout.
+func (e exportKBParams) toFiles() bool {
+	return kbs.Format(strings.ToLower(e.format)) == kbs.FormatMarkdown
 }
 
-func (e exportKBParams) toGetAllKBFilter() kbs.KBQueryFilter {
-	return kbs.KBQueryFilter{
-		Namespace: e.namespace,
-		Category:  e.category,
-		Limit:     20,
-		Offset:    0,
+func (e exportKBParams) toGetAllKBFilter() (kbs.KBQueryFilter, error) {
+	sortBy, err := kbs.ParseSortBy(e.sort)
+	if err != nil {
+		return kbs.KBQueryFilter{}, err
 	}
+
+	order, err := kbs.ParseSortOrder(e.order)
+	if err != nil {
+		return kbs.KBQueryFilter{}, err
+	}
+
+	filter := kbs.KBQueryFilter{
+		Namespace:            kbs.CleanNamespace(e.namespace),
+		IncludeSubNamespaces: e.recursive,
+		Category:             e.category,
+		SortBy:               sortBy,
+		SortOrder:            order,
+		Limit:                20,
+		Offset:               0,
+	}
+
+	return filter, nil
 }
 
 func printExportedKBs(total int) {
@@ -96,22 +183,19 @@ func printExportedKBs(total int) {
 	fmt.Fprintln(os.Stderr)
 }
 
-func toYAMLDocuments(kbs []kbs.KB, firstBlock bool) ([]byte, error) {
-	var content []byte
+// vaultEncoder saves the kbs in a markdown vault.
+type vaultEncoder struct {
+	writer *kbs.VaultWriter
+}
 
-	for _, kb := range kbs {
-		newKBYAML, err := kb.ToYAML()
-		if err != nil {
-			return nil, fmt.Errorf("unable to save new kb for later sync: %w", err)
-		}
+func (v *vaultEncoder) Encode(kbItems ...kbs.KB) error {
+	return v.writer.Save(kbItems...)
+}
 
-		if firstBlock {
-			firstBlock = false
-		} else {
-			content = append(content, []byte("---\n")...)
-		}
-		content = append(content, newKBYAML...)
-	}
+func (v *vaultEncoder) Close() error {
+	return nil
+}
 
-	return content, nil
+func exportFormats() []string {
+	return append(kbs.Formats(), string(kbs.FormatMarkdown))
 }
@@ -0,0 +1,265 @@
+package gets
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/updates"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+)
+
+// labels of the actions on the selected kb
+const (
+	kbUpdatedLabel     = "kb was updated"
+	kbDuplicatedLabel  = "kb was duplicated as %s"
+	kbDeletedLabel     = "kb was moved to the trash, run 'kb trash restore --id %s' to undo it"
+	deleteQuestion     = "  move this kb to the trash? [y/n]"
+	duplicateHelpLabel = "• enter: duplicate • esc: cancel"
+)
+
+// editKB opens the update form on the selected kb.
+func (m *model) editKB() tea.Cmd {
+	if m.itemView.selectedItem == nil {
+		return nil
+	}
+
+	m.editForm = updates.NewForm(*m.itemView.selectedItem)
+	m.mode = editMode
+
+	return m.editForm.Init()
+}
+
+// updateEditForm passes the message to the update form, and saves the kb once
+// the form is done.
+func (m *model) updateEditForm(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case updates.SavedMsg:
+		err := m.service.Update(m.ctx, msg.KB)
+		if err != nil {
+			m.status = fmt.Sprintf("unable to update kb: %s", err)
+			return nil
+		}
+
+		m.closeEditForm()
+
+		err = m.refreshKeys()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "unable to get kb keys:", err)
+			return tea.Quit
+		}
+
+		return m.reloadKBItem(msg.KB.ID, kbUpdatedLabel)
+	case updates.CanceledMsg:
+		m.closeEditForm()
+		return nil
+	}
+
+	return m.editForm.Update(msg)
+}
+
+func (m *model) closeEditForm() {
+	m.editForm = nil
+	m.mode = itemMode
+}
+
+// askToDelete asks for confirmation before deleting the selected kb.
+func (m *model) askToDelete() {
+	if m.itemView.selectedItem == nil {
+		return
+	}
+
+	m.itemView.confirmDelete = true
+}
+
+// confirmDelete moves the selected kb to the trash if the user said yes.
+func (m *model) confirmDelete(msg tea.KeyPressMsg) tea.Cmd {
+	m.itemView.confirmDelete = false
+
+	if !strings.EqualFold(msg.String(), "y") {
+		return nil
+	}
+
+	id := m.itemView.selectedItem.ID
+
+	err := m.service.Delete(m.ctx, id)
+	if err != nil {
+		m.status = fmt.Sprintf("unable to delete kb: %s", err)
+		return nil
+	}
+
+	m.itemView.selectedItem = nil
+	m.mode = searchMode
+
+	err = m.refreshResults()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unable to search:", err)
+		return tea.Quit
+	}
+
+	err = m.refreshKeys()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unable to get kb keys:", err)
+		return tea.Quit
+	}
+
+	m.status = fmt.Sprintf(kbDeletedLabel, id)
+
+	return nil
+}
+
+// askDuplicateKey asks for the key of the copy of the selected kb, it suggests
+// one that is not taken.
+func (m *model) askDuplicateKey() tea.Cmd {
+	if m.itemView.selectedItem == nil {
+		return nil
+	}
+
+	duplicateKey, err := m.service.DuplicateKey(m.ctx, m.itemView.selectedItem.Key)
+	if err != nil {
+		m.status = err.Error()
+		return nil
+	}
+
+	keyInput := textinput.New()
+	keyInput.Placeholder = "key"
+	keyInput.CharLimit = 64
+	keyInput.SetWidth(70)
+	keyInput.Prompt = ""
+	keyInput.SetValue(duplicateKey)
+
+	m.duplicateInput = &keyInput
+	m.mode = duplicateMode
+
+	return m.duplicateInput.Focus()
+}
+
+// updateDuplicateInput edits the key of the copy, and duplicates the kb once
+// it is entered.
+func (m *model) updateDuplicateInput(msg tea.Msg) tea.Cmd {
+	if msg, ok := msg.(tea.KeyPressMsg); ok {
+		switch msg.String() {
+		case "esc", "ctrl+c":
+			m.closeDuplicateInput()
+			return nil
+		case "enter":
+			return m.duplicateKB()
+		}
+	}
+
+	keyInput, cmd := m.duplicateInput.Update(msg)
+	m.duplicateInput = &keyInput
+
+	return cmd
+}
+
+func (m *model) duplicateKB() tea.Cmd {
+	key := strings.ToLower(strings.TrimSpace(m.duplicateInput.Value()))
+
+	newKB, err := m.service.Duplicate(m.ctx, m.itemView.selectedItem.ID, key)
+	if err != nil {
+		m.status = fmt.Sprintf("unable to duplicate kb: %s", err)
+		return nil
+	}
+
+	m.closeDuplicateInput()
+
+	err = m.refreshKeys()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unable to get kb keys:", err)
+		return tea.Quit
+	}
+
+	return m.reloadKBItem(newKB.ID, fmt.Sprintf(kbDuplicatedLabel, newKB.Key))
+}
+
+func (m *model) closeDuplicateInput() {
+	m.duplicateInput = nil
+	m.mode = itemMode
+}
+
+// reloadKBItem shows the kb with the given id after it changed, and refreshes
+// the results so they show the change too.
+func (m *model) reloadKBItem(id, status string) tea.Cmd {
+	kb, err := m.service.GetByID(m.ctx, id)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unable to get kb:", err)
+		return tea.Quit
+	}
+
+	m.itemView.selectedItem = kb
+	m.itemView.itemViewport.SetContent(m.content())
+	m.itemView.itemViewport.GotoTop()
+
+	err = m.refreshResults()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unable to search:", err)
+		return tea.Quit
+	}
+
+	m.status = status
+
+	return nil
+}
+
+// refreshResults searches again the current page of results, or the previous
+// one if the current page is empty now.
+func (m *model) refreshResults() error {
+	err := m.searchKBItems()
+	if err != nil {
+		return err
+	}
+
+	if len(m.searchView.result.Items) == 0 && getKBData.offset >= getKBData.limit {
+		getKBData.offset -= getKBData.limit
+
+		err = m.searchKBItems()
+		if err != nil {
+			return err
+		}
+
+		m.searchView.paginator.PrevPage()
+	}
+
+	m.searchView.paginator.SetTotalPages(m.searchView.result.Total)
+	m.message = fmt.Sprintf("%d", m.searchView.result.Total)
+	m.updateTable()
+	m.searchView.table.UpdateViewport()
+
+	return nil
+}
+
+// refreshKeys loads again the kb keys the filter suggests, so they include the
+// keys added, changed or deleted since they were loaded.
+func (m *model) refreshKeys() error {
+	keys, err := m.service.Keys(m.ctx)
+	if err != nil {
+		return err
+	}
+
+	m.filterView.keys = keys
+	m.filterView.suggestKeys()
+
+	return nil
+}
+
+func (m *model) renderDuplicateInput() string {
+	return fmt.Sprintf(
+		" Duplicating KB %s:\n\n%s\n%s\n\n%s\n%s\n",
+		m.itemView.selectedItem.Key,
+		inputStyle.Width(6).Render(kbs.KeyLabel),
+		m.duplicateInput.View(),
+		m.renderStatus(),
+		helpStyle(duplicateHelpLabel),
+	)
+}
+
+func (m *model) renderStatus() string {
+	if m.status == "" {
+		return ""
+	}
+
+	return continueStyle.Render("  "+m.status) + "\n"
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
 	"github.com/spf13/cobra"
 )
@@ -16,8 +18,15 @@ type getKBParams struct {
 	category    string
 	namespace   string
 	keyword     string
+	query       string
+	recursive   bool
+	allTags     []string
+	anyTags     []string
 	limit       uint32
 	offset      uint32
+	sort        string
+	order       string
+	output      string
 	randomQuote bool
 }
 
@@ -27,17 +36,26 @@ func MakeGetCommand(service *kbs.Service) *cobra.Command {
 	newCmd := cobra.Command{
 		Use:   "get",
 		Short: "get knowledge base content",
-		Long:  `get a kb with id or key or other filter criteria based on tags`,
-		Run:   makeGetKBCommand(service),
+		Long: `get a kb with id or key or other filter criteria based on tags or full text.
+The kbs are shown in an interactive view, unless --output is given or stdout is not a terminal, e.g. in a pipeline.`,
+		Example: "kb get -k docker-prune -o value | sh",
+		Run:     makeGetKBCommand(service),
 	}
 
 	newCmd.PersistentFlags().StringVarP(&getKBData.id, "id", "i", "", "knowledge base id")
 	newCmd.PersistentFlags().StringVarP(&getKBData.key, "key", "k", "", "knowledge base key")
 	newCmd.PersistentFlags().StringVarP(&getKBData.category, "category", "c", "", "knowledge base category. e.g bookmark, quote, etc")
 	newCmd.PersistentFlags().StringVarP(&getKBData.namespace, "namespace", "n", "", "knowledge base namespace")
+	newCmd.PersistentFlags().BoolVarP(&getKBData.recursive, "recursive", "r", false, "include kbs of the sub namespaces of the namespace. e.g. team/backend/payments for team/backend")
 	newCmd.PersistentFlags().StringVarP(&getKBData.keyword, "keyword", "w", "", "knowledge base keyword to search based on tags")
+	newCmd.PersistentFlags().StringVarP(&getKBData.query, "query", "q", "", "full text search on key, value, notes, reference and tags. e.g. 'docker AND \"compose up\"'")
+	newCmd.PersistentFlags().StringSliceVarP(&getKBData.allTags, "all-tags", "", nil, "comma separated tags a kb must all have. e.g. docker,linux")
+	newCmd.PersistentFlags().StringSliceVarP(&getKBData.anyTags, "any-tags", "", nil, "comma separated tags a kb must have at least one of. e.g. docker,podman")
 	newCmd.PersistentFlags().Uint32VarP(&getKBData.limit, "limit", "l", 5, "number of rows you want to retrieve")
-	newCmd.PersistentFlags().Uint32VarP(&getKBData.offset, "offset", "o", 0, "number of rows to skip before starting to return result rows")
+	newCmd.PersistentFlags().Uint32VarP(&getKBData.offset, "offset", "", 0, "number of rows to skip before starting to return result rows")
+	newCmd.PersistentFlags().StringVarP(&getKBData.sort, "sort", "s", "", cmds.SortUsage)
+	newCmd.PersistentFlags().StringVarP(&getKBData.order, "order", "", "", cmds.SortOrderUsage)
+	newCmd.PersistentFlags().StringVarP(&getKBData.output, "output", "o", "", "print the kbs found and exit: "+strings.Join(outputs(), ", ")+". By default table if stdout is not a terminal")
 	newCmd.PersistentFlags().BoolVarP(&getKBData.randomQuote, "random-quote", "", false, "get a random kb in the quote category")
 
 	return &newCmd
@@ -50,10 +68,9 @@ func makeGetKBCommand(service *kbs.Service) func(cmd *cobra.Command, args []stri
 		err := search(ctx, service)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "searching:", err)
-			fmt.Println()
+			fmt.Fprintln(os.Stderr)
 			os.Exit(1)
 		}
-		fmt.Println()
 	}
 }
 
@@ -64,13 +81,39 @@ func search(ctx context.Context, service *kbs.Service) error {
 			return fmt.Errorf("unable to search: %w", err)
 		}
 
+		fmt.Println()
+
 		return nil
 	}
 
-	err := runInteractive(ctx, service)
+	sortBy, err := kbs.ParseSortBy(getKBData.sort)
+	if err != nil {
+		return fmt.Errorf("unable to search: %w", err)
+	}
+
+	order, err := kbs.ParseSortOrder(getKBData.order)
+	if err != nil {
+		return fmt.Errorf("unable to search: %w", err)
+	}
+
+	getKBData.sort, getKBData.order = string(sortBy), string(order)
+
+	out, err := parseOutput(getKBData.output)
+	if err != nil {
+		return fmt.Errorf("unable to search: %w", err)
+	}
+
+	if out != "" {
+		return printKBs(ctx, service, out)
+	}
+
+	err = runInteractive(ctx, service)
 	if err != nil {
 		return fmt.Errorf("unable to run interactive mode: %w", err)
 	}
+
+	fmt.Println()
+
 	return nil
 }
 
@@ -92,11 +135,17 @@ func printRandomQuote(ctx context.Context, service *kbs.Service) error {
 
 func (g *getKBParams) toKBQueryFilter() kbs.KBQueryFilter {
 	return kbs.KBQueryFilter{
-		Keyword:   getKBData.keyword,
-		Key:       getKBData.key,
-		Category:  getKBData.category,
-		Namespace: getKBData.namespace,
-		Limit:     getKBData.limit,
-		Offset:    getKBData.offset,
+		Query:                getKBData.query,
+		Keyword:              getKBData.keyword,
+		Key:                  getKBData.key,
+		Category:             getKBData.category,
+		Namespace:            kbs.CleanNamespace(getKBData.namespace),
+		IncludeSubNamespaces: getKBData.recursive,
+		AllTags:              getKBData.allTags,
+		AnyTags:              getKBData.anyTags,
+		SortBy:               kbs.SortBy(getKBData.sort),
+		SortOrder:            kbs.SortOrder(getKBData.order),
+		Limit:                getKBData.limit,
+		Offset:               getKBData.offset,
 	}
 }
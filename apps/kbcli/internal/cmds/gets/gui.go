@@ -7,7 +7,9 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"slices"
 	"strings"
+	"time"
 
 	"charm.land/bubbles/v2/paginator"
 	"charm.land/bubbles/v2/table"
@@ -18,6 +20,7 @@ import (
 	lipglosscompat "charm.land/lipgloss/v2/compat"
 	"github.com/charmbracelet/glamour"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds/updates"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
 	"golang.design/x/clipboard"
 )
@@ -26,13 +29,16 @@ import (
 type mode int
 
 type filterView struct {
-	inputs  [4]cmds.InputComponent
-	focused int
+	inputs      [7]cmds.InputComponent
+	focused     int
+	keys        []string
+	suggestions []string
 }
 
 type itemView struct {
-	selectedItem *kbs.KB
-	itemViewport *viewport.Model
+	selectedItem  *kbs.KB
+	itemViewport  *viewport.Model
+	confirmDelete bool
 }
 
 type searchView struct {
@@ -42,19 +48,26 @@ type searchView struct {
 }
 
 type model struct {
-	mode       mode
-	searchView *searchView
-	itemView   *itemView
-	filterView *filterView
-	service    *kbs.Service
-	ctx        context.Context
-	message    string
+	mode           mode
+	searchView     *searchView
+	itemView       *itemView
+	filterView     *filterView
+	liveSearch     *liveSearch
+	editForm       *updates.Form
+	duplicateInput *textinput.Model
+	service        *kbs.Service
+	ctx            context.Context
+	message        string
+	// status tells the result of the last action until the next key is pressed.
+	status string
 }
 
 const (
 	searchMode mode = iota
 	filterMode
 	itemMode
+	editMode
+	duplicateMode
 )
 
 // ui form fields for filter view
@@ -63,6 +76,9 @@ const (
 	namespace
 	key
 	keyword
+	query
+	allTags
+	anyTags
 )
 
 var (
@@ -81,6 +97,14 @@ func runInteractive(ctx context.Context, service *kbs.Service) error {
 
 	model.filterView = newFilterViewModel()
 
+	keys, err := service.Keys(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to run interactive: %w", err)
+	}
+
+	model.filterView.keys = keys
+	model.filterView.suggestKeys()
+
 	itemViewPort, err := newItemViewport()
 	if err != nil {
 		return fmt.Errorf("unable to run interactive: %w", err)
@@ -105,6 +129,7 @@ func newModel(ctx context.Context, service *kbs.Service) *model {
 		service:    service,
 		searchView: &searchView{},
 		itemView:   &itemView{},
+		liveSearch: &liveSearch{},
 		ctx:        ctx,
 		mode:       filterMode,
 	}
@@ -151,7 +176,7 @@ func newPaginator(limit uint32, total int) *paginator.Model {
 }
 
 func newFilterViewModel() *filterView {
-	var inputs [4]cmds.InputComponent
+	var inputs [7]cmds.InputComponent
 
 	categoryInput := textinput.New()
 	categoryInput.Placeholder = "category"
@@ -186,6 +211,30 @@ func newFilterViewModel() *filterView {
 	keywordInput.SetValue(getKBData.keyword)
 	inputs[keyword].TextInput = &keywordInput
 
+	queryInput := textinput.New()
+	queryInput.Placeholder = `docker AND "compose up"`
+	queryInput.CharLimit = 128
+	queryInput.SetWidth(70)
+	queryInput.Prompt = ""
+	queryInput.SetValue(getKBData.query)
+	inputs[query].TextInput = &queryInput
+
+	allTagsInput := textinput.New()
+	allTagsInput.Placeholder = "docker linux"
+	allTagsInput.CharLimit = 128
+	allTagsInput.SetWidth(70)
+	allTagsInput.Prompt = ""
+	allTagsInput.SetValue(strings.Join(getKBData.allTags, " "))
+	inputs[allTags].TextInput = &allTagsInput
+
+	anyTagsInput := textinput.New()
+	anyTagsInput.Placeholder = "docker podman"
+	anyTagsInput.CharLimit = 128
+	anyTagsInput.SetWidth(70)
+	anyTagsInput.Prompt = ""
+	anyTagsInput.SetValue(strings.Join(getKBData.anyTags, " "))
+	inputs[anyTags].TextInput = &anyTagsInput
+
 	filterView := filterView{
 		inputs:  inputs,
 		focused: 0,
@@ -207,6 +256,12 @@ func (m *model) updateTable() {
 		{Title: cmds.TagCol, Width: tagLength},
 	}
 
+	withSnippets := m.searchView.result.HasSnippets()
+	if withSnippets {
+		snippetLength := kbs.GetLongerText(cmds.SnippetCol, m.searchView.result.Snippets())
+		columns = append(columns, table.Column{Title: cmds.SnippetCol, Width: snippetLength})
+	}
+
 	s := table.DefaultStyles()
 	s.Header = s.Header.
 		BorderStyle(lipgloss.NormalBorder()).
@@ -220,15 +275,27 @@ func (m *model) updateTable() {
 
 	t := table.New(
 		table.WithColumns(columns),
-		table.WithRows(toTableRow(m.searchView.result.Items)),
+		table.WithRows(toTableRow(m.searchView.result.Items, withSnippets)),
 		table.WithFocused(true),
 		table.WithHeight(7),
+		table.WithWidth(tableWidth(columns, s)),
 	)
 	t.SetStyles(s)
 
 	m.searchView.table = t
 }
 
+// tableWidth is the width of the columns with their padding, the table shows
+// no rows without it.
+func tableWidth(columns []table.Column, styles table.Styles) int {
+	width := 0
+	for _, column := range columns {
+		width += column.Width + styles.Cell.GetHorizontalFrameSize()
+	}
+
+	return width
+}
+
 func (m *model) Init() tea.Cmd {
 	return nil
 }
@@ -237,6 +304,22 @@ func (m *model) Init() tea.Cmd {
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	cmds := make([]tea.Cmd, len(m.filterView.inputs))
+
+	if _, ok := msg.(tea.KeyPressMsg); ok {
+		m.status = ""
+	}
+
+	switch m.mode {
+	case editMode:
+		return m, m.updateEditForm(msg)
+	case duplicateMode:
+		return m, m.updateDuplicateInput(msg)
+	}
+
+	if msg, ok := msg.(tea.KeyPressMsg); ok && m.itemView.confirmDelete {
+		return m, m.confirmDelete(msg)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyPressMsg:
 		switch msg.String() {
@@ -262,6 +345,44 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "ctrl+o":
 			m.openBrowser()
 			return m, cmd
+		case "ctrl+e":
+			if m.mode != itemMode {
+				break
+			}
+			return m, m.editKB()
+		case "ctrl+x":
+			if m.mode != itemMode {
+				break
+			}
+			m.askToDelete()
+			return m, cmd
+		case "ctrl+d":
+			if m.mode != itemMode {
+				break
+			}
+			return m, m.askDuplicateKey()
+		case "s":
+			if m.mode != searchMode {
+				break
+			}
+			getKBData.sort = string(nextSortBy(getKBData.toKBQueryFilter()))
+			getKBData.order = ""
+			err := m.sortKBItems()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "sorting kbs: %w", err)
+				return m, tea.Quit
+			}
+		case "o":
+			if m.mode != searchMode {
+				break
+			}
+			_, order := getKBData.toKBQueryFilter().Sorting()
+			getKBData.order = string(order.Reverse())
+			err := m.sortKBItems()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "sorting kbs: %w", err)
+				return m, tea.Quit
+			}
 		case "left":
 			if (int(getKBData.offset) - int(getKBData.limit)) < 0 {
 				return m, cmd
@@ -330,6 +451,11 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.itemView.itemViewport = &newItemViewport
 			return m, cmd
 		}
+	case liveSearchTickMsg:
+		return m, m.runSearch(msg)
+	case liveSearchResultMsg:
+		m.showSearchResult(msg)
+		return m, nil
 	default:
 		return m, nil
 	}
@@ -348,6 +474,8 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.filterView.inputs[m.filterView.focused].Focus()
 
+		values := m.filterView.liveSearchValues()
+
 		for i := range m.filterView.inputs {
 			if m.filterView.inputs[i].TextInput != nil {
 				textInputModel, textInputCmd := m.filterView.inputs[i].TextInput.Update(msg)
@@ -355,6 +483,13 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.filterView.inputs[i].TextInput, cmds[i] = &textInputModel, textInputCmd
 			}
 		}
+
+		m.filterView.suggestKeys()
+
+		if !slices.Equal(values, m.filterView.liveSearchValues()) {
+			cmds = append(cmds, m.scheduleSearch())
+		}
+
 		return m, tea.Batch(cmds...)
 	default:
 		return m, cmd
@@ -367,6 +502,10 @@ func (m *model) View() tea.View {
 		return tea.NewView(m.drawKBViewer())
 	case filterMode:
 		return tea.NewView(m.renderFilters())
+	case editMode:
+		return tea.NewView(m.editForm.View() + m.renderStatus())
+	case duplicateMode:
+		return tea.NewView(m.renderDuplicateInput())
 	case searchMode:
 		fallthrough
 	default:
@@ -375,7 +514,7 @@ func (m *model) View() tea.View {
 }
 
 func (m *model) drawKBViewer() string {
-	return m.itemView.itemViewport.View() + m.helpView()
+	return m.itemView.itemViewport.View() + "\n" + m.renderStatus() + m.helpView()
 }
 
 func (m *model) helpView() string {
@@ -383,11 +522,15 @@ func (m *model) helpView() string {
 		return helpStyle("\n  • Ctrl+R: Back • q: Quit\n")
 	}
 
+	if m.itemView.confirmDelete {
+		return helpStyle("\n" + deleteQuestion + "\n")
+	}
+
 	if m.itemView.selectedItem.Category == kbs.BookmarkCategory {
-		return helpStyle("\n  ↑/↓: Navigate • Ctrl+R: Back • Ctrl+c: Copy • Ctrl+o: Open • Esc: Quit\n")
+		return helpStyle("\n  ↑/↓: Navigate • Ctrl+R: Back • Ctrl+c: Copy • Ctrl+o: Open • Ctrl+E: Edit • Ctrl+D: Duplicate • Ctrl+X: Delete • Esc: Quit\n")
 	}
 
-	return helpStyle("\n  ↑/↓: Navigate • Ctrl+R: Back • Ctrl+c: Copy • Esc: Quit\n")
+	return helpStyle("\n  ↑/↓: Navigate • Ctrl+R: Back • Ctrl+c: Copy • Ctrl+E: Edit • Ctrl+D: Duplicate • Ctrl+X: Delete • Esc: Quit\n")
 }
 
 func (m *model) drawTable() string {
@@ -397,15 +540,19 @@ func (m *model) drawTable() string {
 	var b strings.Builder
 	b.WriteString("\n  Knowledge Base Results: ")
 	b.WriteString(m.message)
+	b.WriteString(sortingLabel(getKBData.toKBQueryFilter()))
 	b.WriteString("\n\n")
 	b.WriteString(baseStyle.Render(m.searchView.table.View()))
 	b.WriteString("\n\n")
 	b.WriteString("  " + m.searchView.paginator.View())
-	b.WriteString("\n\n  ←/→ page • Ctrl+F: filters • Esc: quit\n")
+	b.WriteString("\n\n")
+	b.WriteString(m.renderStatus())
+	b.WriteString("  ←/→ page • s: sort • o: order • Ctrl+F: filters • Esc: quit\n")
 	return b.String()
 }
 
 func (m *model) search() error {
+	m.stopSearch()
 	m.toGetKBParams()
 	m.itemView.selectedItem = nil
 
@@ -419,6 +566,43 @@ func (m *model) search() error {
 	return nil
 }
 
+// sortKBItems searches the kbs again from the first page after the sorting changed.
+func (m *model) sortKBItems() error {
+	getKBData.offset = 0
+
+	err := m.search()
+	if err != nil {
+		return fmt.Errorf("unable to sort: %w", err)
+	}
+
+	m.message = fmt.Sprintf("%d", m.searchView.result.Total)
+
+	return nil
+}
+
+// nextSortBy returns the sort field after the one of the filter. Relevance is
+// skipped if the filter is not a full text search.
+func nextSortBy(filter kbs.KBQueryFilter) kbs.SortBy {
+	current, _ := filter.Sorting()
+	fields := kbs.SortFields()
+	index := slices.Index(fields, current)
+
+	for i := 1; i < len(fields); i++ {
+		next := fields[(index+i)%len(fields)]
+		if next != kbs.SortByRelevance || filter.IsFullTextSearch() {
+			return next
+		}
+	}
+
+	return current
+}
+
+func sortingLabel(filter kbs.KBQueryFilter) string {
+	sortBy, order := filter.Sorting()
+
+	return fmt.Sprintf(" • sorted by %s %s", sortBy, order)
+}
+
 func (m *model) searchKBItems() error {
 	result, err := m.service.Search(m.ctx, getKBData.toKBQueryFilter())
 	if err != nil {
@@ -446,11 +630,16 @@ func (m *model) loadKBItem(kbKey string) error {
 	return nil
 }
 
-func toTableRow(items []kbs.KBItem) []table.Row {
+func toTableRow(items []kbs.KBItem, withSnippets bool) []table.Row {
 	result := make([]table.Row, 0, len(items))
 
 	for _, v := range items {
-		result = append(result, v.ToArray())
+		row := v.ToArray()
+		if withSnippets {
+			row = append(row, v.Snippet)
+		}
+
+		result = append(result, row)
 	}
 	return result
 }
@@ -476,6 +665,9 @@ func renderKBItem(k *kbs.KB) string {
 %s
 %s
 %+v
+
+%s
+%s
 `,
 		inputStyle.Width(30).Render("ID"), k.ID,
 		inputStyle.Width(30).Render("Key"), k.Key,
@@ -484,7 +676,22 @@ func renderKBItem(k *kbs.KB) string {
 		inputStyle.Width(30).Render("Value"), k.Value,
 		inputStyle.Width(30).Render("Notes"), k.Notes,
 		inputStyle.Width(30).Render("Reference"), k.Reference,
-		inputStyle.Width(30).Render("Tags"), k.Tags)
+		inputStyle.Width(30).Render("Tags"), k.Tags,
+		inputStyle.Width(30).Render("Activity"), renderActivity(k))
+}
+
+// renderActivity describes when the kb was created, changed and opened.
+func renderActivity(k *kbs.KB) string {
+	return fmt.Sprintf("created %s, updated %s, opened %d times, last opened %s",
+		renderDate(&k.CreatedOn), renderDate(k.UpdatedOn), k.AccessCount, renderDate(k.LastAccessedOn))
+}
+
+func renderDate(date *time.Time) string {
+	if date == nil || date.IsZero() {
+		return "never"
+	}
+
+	return date.Local().Format(time.DateTime)
 }
 
 func (m model) renderFilters() string {
@@ -497,6 +704,15 @@ func (m model) renderFilters() string {
 %s
 %s
 
+%s
+%s%s
+
+%s
+%s
+
+%s
+%s
+
 %s
 %s
 
@@ -515,10 +731,17 @@ func (m model) renderFilters() string {
 		m.filterView.inputs[namespace].View(),
 		inputStyle.Width(6).Render(kbs.KeyLabel),
 		m.filterView.inputs[key].View(),
+		m.filterView.renderSuggestions(),
 		inputStyle.Width(9).Render(kbs.KeywordLabel),
 		m.filterView.inputs[keyword].View(),
+		inputStyle.Width(4).Render(kbs.QueryLabel),
+		m.filterView.inputs[query].View(),
+		inputStyle.Width(8).Render(kbs.AllTagsLabel),
+		m.filterView.inputs[allTags].View(),
+		inputStyle.Width(8).Render(kbs.AnyTagsLabel),
+		m.filterView.inputs[anyTags].View(),
 		continueStyle.Render("Continue ->"),
-	) + "\n"
+	) + m.renderLiveResults() + "\n"
 }
 
 func (m *model) copyToClipboard() {
@@ -570,6 +793,19 @@ func (m *model) openBrowser() {
 	}
 }
 
+// suggestKeys ranks the kb keys that look like the key being typed.
+func (f *filterView) suggestKeys() {
+	f.suggestions = kbs.FuzzyMatch(f.inputs[key].Value(), f.keys, maxSuggestions)
+}
+
+func (f *filterView) renderSuggestions() string {
+	if len(f.suggestions) == 0 {
+		return ""
+	}
+
+	return "\n" + continueStyle.Render("did you mean: "+strings.Join(f.suggestions, ", "))
+}
+
 // nextInput focuses the next input field
 func (f *filterView) nextInput() {
 	f.focused = (f.focused + 1) % len(f.inputs)
@@ -586,7 +822,11 @@ func (f *filterView) prevInput() {
 
 func (m *model) toGetKBParams() {
 	getKBData.category = strings.ToLower(m.filterView.inputs[category].Value())
-	getKBData.namespace = strings.ToLower(m.filterView.inputs[namespace].Value())
+	getKBData.namespace = kbs.CleanNamespace(m.filterView.inputs[namespace].Value())
 	getKBData.key = strings.ToLower(m.filterView.inputs[key].Value())
 	getKBData.keyword = strings.ToLower(m.filterView.inputs[keyword].Value())
+	// fts5 operators such as AND, OR and NOT are case sensitive.
+	getKBData.query = strings.TrimSpace(m.filterView.inputs[query].Value())
+	getKBData.allTags = strings.Fields(strings.ToLower(m.filterView.inputs[allTags].Value()))
+	getKBData.anyTags = strings.Fields(strings.ToLower(m.filterView.inputs[anyTags].Value()))
 }
@@ -0,0 +1,145 @@
+package gets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+)
+
+// searchDebounce time to wait after the last keystroke before searching, so
+// typing a word runs one search instead of one per character.
+const searchDebounce = 300 * time.Millisecond
+
+// liveSearch searches kbs while the filters are being typed. Every search has
+// a sequence number, only the result of the latest one is shown.
+type liveSearch struct {
+	seq    int
+	cancel context.CancelFunc
+	err    error
+}
+
+// liveSearchTickMsg is sent once the debounce time of a search has passed.
+type liveSearchTickMsg struct {
+	seq int
+}
+
+// liveSearchResultMsg carries the result of a live search.
+type liveSearchResultMsg struct {
+	seq    int
+	result *kbs.SearchResult
+	err    error
+}
+
+// liveSearchInputs filters that trigger a live search when they change.
+var liveSearchInputs = []int{key, keyword}
+
+// liveSearchValues gets the current values of the inputs that trigger a live search.
+func (f *filterView) liveSearchValues() []string {
+	values := make([]string, 0, len(liveSearchInputs))
+
+	for _, input := range liveSearchInputs {
+		values = append(values, f.inputs[input].Value())
+	}
+
+	return values
+}
+
+// scheduleSearch discards any pending search and waits for the debounce time
+// to run a new one.
+func (m *model) scheduleSearch() tea.Cmd {
+	m.stopSearch()
+
+	seq := m.liveSearch.seq
+
+	return tea.Tick(searchDebounce, func(time.Time) tea.Msg {
+		return liveSearchTickMsg{seq: seq}
+	})
+}
+
+// stopSearch cancels the running search, if any, and makes any result still
+// to come out of date.
+func (m *model) stopSearch() {
+	if m.liveSearch.cancel != nil {
+		m.liveSearch.cancel()
+		m.liveSearch.cancel = nil
+	}
+
+	m.liveSearch.seq++
+}
+
+// runSearch searches the kbs with the current filters without blocking the ui.
+func (m *model) runSearch(msg liveSearchTickMsg) tea.Cmd {
+	if msg.seq != m.liveSearch.seq {
+		return nil
+	}
+
+	m.toGetKBParams()
+	getKBData.offset = 0
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.liveSearch.cancel = cancel
+
+	service, filter := m.service, getKBData.toKBQueryFilter()
+
+	return func() tea.Msg {
+		result, err := service.Search(ctx, filter)
+
+		return liveSearchResultMsg{seq: msg.seq, result: result, err: err}
+	}
+}
+
+// showSearchResult shows the result of the latest live search and drops the
+// ones that arrive out of order.
+func (m *model) showSearchResult(msg liveSearchResultMsg) {
+	if msg.seq != m.liveSearch.seq || errors.Is(msg.err, context.Canceled) {
+		return
+	}
+
+	m.liveSearch.cancel()
+	m.liveSearch.cancel = nil
+	m.liveSearch.err = msg.err
+
+	if msg.err != nil {
+		return
+	}
+
+	m.searchView.result = msg.result
+	if msg.result == nil {
+		m.searchView.result = &kbs.SearchResult{}
+	}
+
+	m.searchView.paginator = newPaginator(m.searchView.result.Limit, m.searchView.result.Total)
+	m.message = fmt.Sprintf("%d", m.searchView.result.Total)
+	m.updateTable()
+	m.searchView.table.UpdateViewport()
+}
+
+// renderLiveResults renders the kbs found while typing the filters.
+func (m *model) renderLiveResults() string {
+	if m.liveSearch.err != nil {
+		return continueStyle.Render(fmt.Sprintf(" unable to search: %s", m.liveSearch.err)) + "\n"
+	}
+
+	if m.searchView.result == nil || m.searchView.paginator == nil {
+		return ""
+	}
+
+	baseStyle := lipgloss.NewStyle().
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("240"))
+
+	var b strings.Builder
+	b.WriteString(" Knowledge Base Results: ")
+	b.WriteString(m.message)
+	b.WriteString("\n\n")
+	b.WriteString(baseStyle.Render(m.searchView.table.View()))
+	b.WriteString("\n")
+
+	return b.String()
+}
@@ -0,0 +1,188 @@
+package gets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+)
+
+// output defines how kbs are printed without the interactive mode.
+type output string
+
+// supported outputs
+const (
+	outputTable output = "table"
+	outputJSON  output = "json"
+	outputYAML  output = "yaml"
+	outputValue output = "value"
+)
+
+// maxSuggestions number of similar keys suggested when no kb has the given key.
+const maxSuggestions = 5
+
+// field labels
+const didYouMeanLabel = "no kb has the key %q, did you mean: %s?\n"
+
+var (
+	errNoKBsFound    = errors.New("no kbs were found")
+	errKeyNotFound   = errors.New("no kb has the given key")
+	errNothingToFind = errors.New("nothing to look for, use --id, --key, --keyword, --query, --category, --namespace or the tag filters")
+)
+
+// parseOutput gets the output with the given name. Empty means the interactive
+// mode if stdout is a terminal, or a table otherwise, e.g. in a pipeline.
+func parseOutput(value string) (output, error) {
+	result := output(strings.ToLower(strings.TrimSpace(value)))
+
+	switch result {
+	case outputTable, outputJSON, outputYAML, outputValue:
+		return result, nil
+	case "":
+		if cmds.IsTerminal(os.Stdout) {
+			return "", nil
+		}
+
+		return outputTable, nil
+	default:
+		return "", fmt.Errorf("invalid output %q, it must be one of: %s", value, strings.Join(outputs(), ", "))
+	}
+}
+
+// printKBs prints the kbs found to stdout in the given output.
+func printKBs(ctx context.Context, service *kbs.Service, out output) error {
+	kbsFound, err := findKBs(ctx, service, out)
+	if err != nil {
+		return fmt.Errorf("unable to get kbs: %w", err)
+	}
+
+	if len(kbsFound) == 0 {
+		return errNoKBsFound
+	}
+
+	switch out {
+	case outputJSON:
+		return encodeKBs(kbs.FormatJSON, kbsFound)
+	case outputYAML:
+		return encodeKBs(kbs.FormatYAML, kbsFound)
+	case outputValue:
+		for _, kb := range kbsFound {
+			fmt.Println(kb.Value)
+		}
+	default:
+		printTable(kbsFound)
+	}
+
+	return nil
+}
+
+// findKBs gets the kb with the given id, or the kb with exactly the given key
+// if there is one, or the kbs that match the search criteria otherwise. The
+// value output only prints the kb with the exact key, because the values of
+// similar kbs could be run by mistake, e.g. piped to a shell.
+func findKBs(ctx context.Context, service *kbs.Service, out output) ([]kbs.KB, error) {
+	if !kbs.IsStringEmpty(getKBData.id) {
+		kb, err := service.GetByID(ctx, getKBData.id)
+		if err != nil || kb == nil {
+			return nil, err
+		}
+
+		return []kbs.KB{*kb}, nil
+	}
+
+	if !kbs.IsStringEmpty(getKBData.key) {
+		kb, err := service.GetByKey(ctx, getKBData.key)
+		if err != nil {
+			return nil, err
+		}
+
+		if kb != nil {
+			return []kbs.KB{*kb}, nil
+		}
+
+		printSuggestions(ctx, service, getKBData.key)
+
+		if out == outputValue {
+			return nil, fmt.Errorf("%q: %w", getKBData.key, errKeyNotFound)
+		}
+	}
+
+	result, err := service.Search(ctx, getKBData.toKBQueryFilter())
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil {
+		return nil, errNothingToFind
+	}
+
+	// search results only have a summary of every kb.
+	kbsFound := make([]kbs.KB, 0, len(result.Items))
+
+	for _, item := range result.Items {
+		kb, err := service.GetByID(ctx, item.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if kb != nil {
+			kbsFound = append(kbsFound, *kb)
+		}
+	}
+
+	return kbsFound, nil
+}
+
+// printSuggestions prints to stderr the keys that look like the given one, so
+// they are not mixed with the kbs printed to stdout.
+func printSuggestions(ctx context.Context, service *kbs.Service, key string) {
+	suggestions, err := service.SuggestKeys(ctx, key, maxSuggestions)
+	if err != nil || len(suggestions) == 0 {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, didYouMeanLabel, key, strings.Join(suggestions, ", "))
+}
+
+func encodeKBs(format kbs.Format, kbsFound []kbs.KB) error {
+	encoder, err := kbs.NewEncoder(format, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("unable to print kbs: %w", err)
+	}
+
+	err = encoder.Encode(kbsFound...)
+	if err != nil {
+		return fmt.Errorf("unable to print kbs: %w", err)
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return fmt.Errorf("unable to print kbs: %w", err)
+	}
+
+	return nil
+}
+
+func printTable(kbsFound []kbs.KB) {
+	keyLength, categoryLength, namespaceLength := len(cmds.KeyCol), len(cmds.CategoryCol), len(cmds.NamespaceCol)
+	for _, kb := range kbsFound {
+		keyLength = max(keyLength, len(kb.Key))
+		categoryLength = max(categoryLength, len(kb.Category))
+		namespaceLength = max(namespaceLength, len(kb.Namespace))
+	}
+
+	fmt.Println(fmt.Sprintf("%-36s", cmds.IDCol), fmt.Sprintf("%-*s", keyLength, cmds.KeyCol),
+		fmt.Sprintf("%-*s", categoryLength, cmds.CategoryCol), fmt.Sprintf("%-*s", namespaceLength, cmds.NamespaceCol), cmds.TagCol)
+	for _, kb := range kbsFound {
+		fmt.Println(fmt.Sprintf("%-36s", kb.ID), fmt.Sprintf("%-*s", keyLength, kb.Key),
+			fmt.Sprintf("%-*s", categoryLength, kb.Category), fmt.Sprintf("%-*s", namespaceLength, kb.Namespace), strings.Join(kb.Tags, " "))
+	}
+}
+
+func outputs() []string {
+	return []string{string(outputTable), string(outputJSON), string(outputYAML), string(outputValue)}
+}
@@ -1,21 +1,27 @@
 package imports
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/adapters/filesystems"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
 	"github.com/spf13/cobra"
-	yaml "gopkg.in/yaml.v3"
 )
 
 // importKBParams contains parameters required by add command to add a new KB.
 type importKBParams struct {
 	file          string
+	format        string
+	dir           string
+	mode          string
+	dryRun        bool
+	atomic        bool
 	showFailedKBs bool
 	showAddedKBs  bool
 }
@@ -24,6 +30,12 @@ type importKBParams struct {
 const (
 	fileLabel                = "file path: "
 	importedLabel            = "Imported KBs"
+	updatedLabel             = "Updated KBs"
+	skippedLabel             = "Skipped KBs"
+	dryRunLabel              = "dry run, nothing was written"
+	importingLabel           = "importing"
+	canceledLabel            = "import was canceled, these kbs were processed before it stopped"
+	summaryTemplate          = "created: %d, updated: %d, skipped: %d, failed: %d\n"
 	unImportedLabel          = "Uimported KBs"
 	unImportedErrorLabel     = "ERROR"
 	unImportedErrorSeparator = "-----"
@@ -37,11 +49,26 @@ func MakeImportCommand(service *kbs.Service) *cobra.Command {
 	newCmd := cobra.Command{
 		Use:   "import",
 		Short: "import knowledge bases",
-		Long:  `import knowledge bases from a file or other sources to your own kb repository`,
-		Run:   makeRunImportKBCommand(service),
+		Long: `import knowledge bases from a file or other sources to your own kb repository.
+The file format (yaml, json, jsonl or csv) is detected from its extension, unless --format is given.
+With --dir, all markdown files in the folder and its subfolders are imported.
+Kbs that already exist, with the same id or key, are handled according to --mode:
+  create         add new kbs, kbs with the id of an existing kb update it and existing keys fail (default)
+  upsert         add new kbs and update existing ones
+  skip-existing  add new kbs and leave existing ones untouched
+With --dry-run, every kb is validated and the command reports what would be created, updated or skipped, without writing anything.
+Kbs are read while they are imported and written in batches, each one in its own transaction. With --atomic,
+all kbs are written in one transaction and nothing is imported if one of them fails.
+Ctrl-C stops the import after the current kb and reports what was imported.`,
+		Run: makeRunImportKBCommand(service),
 	}
 
 	newCmd.PersistentFlags().StringVarP(&importKBData.file, "file", "f", "", "knowledge base key")
+	newCmd.PersistentFlags().StringVarP(&importKBData.format, "format", "", "", "file format: "+strings.Join(kbs.Formats(), ", ")+". By default it is detected from the file extension")
+	newCmd.PersistentFlags().StringVarP(&importKBData.dir, "dir", "", "", "folder with one markdown file per kb to import, e.g. an exported vault")
+	newCmd.PersistentFlags().StringVarP(&importKBData.mode, "mode", "", string(kbs.ImportModeCreate), "what to do with existing kbs: create, upsert or skip-existing")
+	newCmd.PersistentFlags().BoolVarP(&importKBData.dryRun, "dry-run", "", false, "report what would be imported without writing anything")
+	newCmd.PersistentFlags().BoolVarP(&importKBData.atomic, "atomic", "", false, "import all kbs or none, rolling back on the first failure")
 	newCmd.PersistentFlags().BoolVarP(&importKBData.showAddedKBs, "show-added-kbs", "", false, "knowledge base key")
 	newCmd.PersistentFlags().BoolVarP(&importKBData.showFailedKBs, "show-failed-kbs", "", false, "knowledge base key")
 
@@ -52,7 +79,20 @@ func makeRunImportKBCommand(service *kbs.Service) func(_ *cobra.Command, _ []str
 	return func(_ *cobra.Command, _ []string) {
 		fillMissingImportFields()
 
-		result, err := importFile(service)
+		var result *kbs.ImportResult
+
+		err := cmds.RunWithProgress(importingLabel, func(ctx context.Context, bar *cmds.ProgressBar) error {
+			var err error
+			result, err = importFile(ctx, service, bar)
+
+			return err
+		})
+		if errors.Is(err, context.Canceled) && result != nil {
+			fmt.Fprintln(os.Stderr, canceledLabel)
+			printImportedReport(result)
+			os.Exit(1)
+		}
+
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "failed to process import:", err)
 			os.Exit(1)
@@ -62,41 +102,115 @@ func makeRunImportKBCommand(service *kbs.Service) func(_ *cobra.Command, _ []str
 	}
 }
 
-func importFile(service *kbs.Service) (*kbs.ImportResult, error) {
-	newKBs, err := loadImportFile()
+func importFile(ctx context.Context, service *kbs.Service, bar *cmds.ProgressBar) (*kbs.ImportResult, error) {
+	mode, err := kbs.ParseImportMode(importKBData.mode)
 	if err != nil {
 		return nil, fmt.Errorf("unable to import file: %w", err)
 	}
 
-	ctx := context.Background()
-	result, err := service.Import(ctx, newKBs)
+	options := kbs.ImportOptions{
+		Mode:   mode,
+		DryRun: importKBData.dryRun,
+		Atomic: importKBData.atomic,
+	}
+
+	if !kbs.IsStringEmpty(importKBData.dir) {
+		options.Progress = bar.Report
+
+		return importFolder(ctx, service, options)
+	}
+
+	format, err := importKBData.getFormat()
 	if err != nil {
-		return nil, fmt.Errorf("unable to import kbs: %w", err)
+		return nil, fmt.Errorf("unable to import file: %w", err)
+	}
+
+	file, err := filesystems.OpenFile(importKBData.file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file to import (%q): %w", importKBData.file, err)
+	}
+	defer file.Close()
+
+	reader, err := newProgressReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file to import (%q): %w", importKBData.file, err)
+	}
+
+	// the number of kbs in the file is not known, so the progress is the part of the file already read.
+	options.Progress = func(progress kbs.Progress) {
+		bar.Update(progress.String(), reader.percent())
+	}
+
+	// kbs are read while they are imported, so big files are not loaded in memory.
+	records, err := kbs.DecodeSeq(format, reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load file to import (%q): %w", importKBData.file, err)
+	}
+
+	result, err := service.ImportSeq(ctx, records, options)
+	if err != nil {
+		return result, fmt.Errorf("unable to import kbs: %w", err)
 	}
 
 	return result, nil
 }
 
-func loadImportFile() ([]kbs.NewKB, error) {
-	file, err := filesystems.ReadFile(importKBData.file)
+func importFolder(ctx context.Context, service *kbs.Service, options kbs.ImportOptions) (*kbs.ImportResult, error) {
+	kbItems, err := kbs.LoadVault(importKBData.dir)
 	if err != nil {
-		return nil, fmt.Errorf("unable to read file to import (%q): %w", importKBData.file, err)
+		return nil, fmt.Errorf("unable to load folder to import (%q): %w", importKBData.dir, err)
+	}
+
+	result, err := service.Import(ctx, kbItems, options)
+	if err != nil {
+		return result, fmt.Errorf("unable to import kbs: %w", err)
 	}
 
-	dec := yaml.NewDecoder(bytes.NewReader(file))
+	return result, nil
+}
+
+// progressReader counts the bytes read from the import file.
+type progressReader struct {
+	reader io.Reader
+	size   int64
+	read   int64
+}
 
-	var kbItems []kbs.NewKB
-	var kbItem kbs.NewKB
-	for dec.Decode(&kbItem) == nil {
-		kbItems = append(kbItems, kbItem)
-		kbItem = kbs.NewKB{}
+func newProgressReader(file *os.File) (*progressReader, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get file size: %w", err)
 	}
 
-	return kbItems, nil
+	return &progressReader{reader: file, size: stat.Size()}, nil
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.read += int64(n)
+
+	return n, err
+}
+
+// percent returns the part of the file already read, or -1 if the size is not known.
+func (p *progressReader) percent() float64 {
+	if p.size <= 0 {
+		return -1
+	}
+
+	return min(float64(p.read)/float64(p.size), 1)
+}
+
+func (i importKBParams) getFormat() (kbs.Format, error) {
+	if kbs.IsStringEmpty(i.format) {
+		return kbs.DetectFormat(i.file), nil
+	}
+
+	return kbs.ParseFormat(i.format)
 }
 
 func fillMissingImportFields() {
-	if kbs.IsStringEmpty(importKBData.file) {
+	if kbs.IsStringEmpty(importKBData.file) && kbs.IsStringEmpty(importKBData.dir) {
 		importKBData.file = cmds.RequestStringValue(fileLabel)
 	}
 }
@@ -106,19 +220,37 @@ func printImportedReport(kbs *kbs.ImportResult) {
 		return
 	}
 
-	if importKBData.dontPrint() {
-		return
+	printSummary(kbs)
+
+	// a dry run is done to see the details.
+	showAddedKBs := importKBData.showAddedKBs || kbs.DryRun
+	showFailedKBs := importKBData.showFailedKBs || kbs.DryRun
+
+	if showAddedKBs && len(kbs.NewIDs) > 0 {
+		printImportedKBs(importedLabel, kbs.NewIDs)
+	}
+
+	if showAddedKBs && len(kbs.UpdatedIDs) > 0 {
+		printImportedKBs(updatedLabel, kbs.UpdatedIDs)
 	}
 
-	if importKBData.showAddedKBs && len(kbs.NewIDs) > 0 {
-		printImportedKBs(kbs)
+	if showAddedKBs && len(kbs.SkippedIDs) > 0 {
+		printImportedKBs(skippedLabel, kbs.SkippedIDs)
 	}
 
-	if importKBData.showFailedKBs && len(kbs.FailedKeys) > 0 {
+	if showFailedKBs && len(kbs.FailedKeys) > 0 {
 		printUnimportedKBs(kbs)
 	}
 }
 
+func printSummary(kbs *kbs.ImportResult) {
+	fmt.Println()
+	if kbs.DryRun {
+		fmt.Println(dryRunLabel)
+	}
+	fmt.Printf(summaryTemplate, len(kbs.NewIDs), len(kbs.UpdatedIDs), len(kbs.SkippedIDs), len(kbs.FailedKeys))
+}
+
 func printUnimportedKBs(kbs *kbs.ImportResult) {
 	length := len(cmds.KeyCol)
 	for key := range kbs.FailedKeys {
@@ -139,19 +271,15 @@ func printUnimportedKBs(kbs *kbs.ImportResult) {
 	}
 }
 
-func printImportedKBs(kbs *kbs.ImportResult) {
+func printImportedKBs(title string, ids map[string]string) {
 	fmt.Println()
-	fmt.Println(importedLabel)
+	fmt.Println(title)
 	fmt.Println(cmds.TitleSeparator)
-	fmt.Println(totalImportedLabel, len(kbs.NewIDs))
+	fmt.Println(totalImportedLabel, len(ids))
 	fmt.Println()
 	fmt.Println(fmt.Sprintf("%-36s", cmds.IDCol), cmds.KeyCol)
 	fmt.Println(fmt.Sprintf("%-36s", cmds.IDColSeparator), cmds.KeyColSeparator)
-	for key, id := range kbs.NewIDs {
+	for key, id := range ids {
 		fmt.Println(id, key)
 	}
 }
-
-func (i importKBParams) dontPrint() bool {
-	return !importKBData.showAddedKBs && !importKBData.showFailedKBs
-}
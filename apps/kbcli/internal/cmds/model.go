@@ -47,9 +47,17 @@ const (
 	NamespaceColSeparator = "---------"
 	TagCol                = "TAGS"
 	TagColSeparator       = "----"
+	SnippetCol            = "SNIPPET"
 	GetKBIDLabel          = "id: "
 )
 
+// common flag usages
+const (
+	SortUsage      = "sort kbs by relevance, created, alphabetical, recent or most-used. By default full text searches are sorted by relevance and the other ones by created"
+	SortOrderUsage = "asc or desc, to reverse the default order of --sort, e.g. desc with created for the newest kbs first"
+	EditorUsage    = "write the kb in $VISUAL or $EDITOR as markdown, with the other fields in a yaml front matter"
+)
+
 var ErrNoConfiguration = errors.New("no configuration has been created yet")
 
 func GetConfiguration() (*settings.Configuration, error) {
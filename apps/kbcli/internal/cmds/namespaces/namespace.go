@@ -0,0 +1,140 @@
+package namespaces
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+	"github.com/spf13/cobra"
+)
+
+// field labels
+const (
+	namespacesLabel     = "Namespaces"
+	totalKBsLabel       = "Total kbs:"
+	countsHint          = "counts include the kbs of sub namespaces"
+	noNamespacesMessage = "there are no namespaces"
+	movedKBsTemplate    = "%d kbs were moved\n"
+	namespaceTemplate   = "%s%s (%d)\n"
+)
+
+// tree branches
+const (
+	branch     = "├── "
+	lastBranch = "└── "
+	trunk      = "│   "
+	noTrunk    = "    "
+)
+
+func MakeNamespacesCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "namespaces",
+		Short: "manage kb namespaces",
+		Long:  "show the namespace hierarchy and move namespaces with their sub namespaces, e.g. team/backend/payments",
+		Run: func(cmd *cobra.Command, _ []string) {
+			if err := cmd.Help(); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+
+	newCmd.AddCommand(makeTreeCommand(service))
+	newCmd.AddCommand(makeMoveCommand(service))
+
+	return &newCmd
+}
+
+func makeTreeCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "tree",
+		Short: "print the namespace hierarchy",
+		Long:  "print the namespaces of the kbs that are not in the trash as a tree, with how many kbs each one has",
+		Args:  cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			err := printNamespaceTree(context.Background(), service)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "printing namespaces:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	return &newCmd
+}
+
+func makeMoveCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:     "move namespace new-namespace",
+		Short:   "move a namespace and its sub namespaces",
+		Long:    "move the kbs of a namespace and of its sub namespaces to the new namespace",
+		Example: "kb namespaces move team/backend platform/backend",
+		Args:    cobra.ExactArgs(2),
+		Run: func(_ *cobra.Command, args []string) {
+			err := moveNamespace(context.Background(), service, args[0], args[1])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "moving namespace:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	return &newCmd
+}
+
+func printNamespaceTree(ctx context.Context, service *kbs.Service) error {
+	tree, err := service.NamespaceTree(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get namespaces: %w", err)
+	}
+
+	if len(tree) == 0 {
+		fmt.Println(noNamespacesMessage)
+		return nil
+	}
+
+	var total int
+	for _, node := range tree {
+		total += node.Total
+	}
+
+	fmt.Println()
+	fmt.Println(namespacesLabel)
+	fmt.Println(cmds.TitleSeparator)
+	fmt.Println(totalKBsLabel, total)
+	fmt.Println(countsHint)
+	fmt.Println()
+
+	for _, node := range tree {
+		fmt.Printf(namespaceTemplate, "", node.Name, node.Total)
+		printNamespaceNodes(node.Children, "")
+	}
+
+	return nil
+}
+
+// printNamespaceNodes prints the nodes as branches after the given indentation.
+func printNamespaceNodes(nodes []*kbs.NamespaceNode, indentation string) {
+	for i, node := range nodes {
+		prefix, childIndentation := branch, trunk
+		if i == len(nodes)-1 {
+			prefix, childIndentation = lastBranch, noTrunk
+		}
+
+		fmt.Printf(namespaceTemplate, indentation+prefix, node.Name, node.Total)
+		printNamespaceNodes(node.Children, indentation+childIndentation)
+	}
+}
+
+func moveNamespace(ctx context.Context, service *kbs.Service, namespace, newNamespace string) error {
+	moved, err := service.MoveNamespace(ctx, namespace, newNamespace)
+	if err != nil {
+		return fmt.Errorf("unable to move namespace %q: %w", strings.ToLower(namespace), err)
+	}
+
+	fmt.Printf(movedKBsTemplate, moved)
+
+	return nil
+}
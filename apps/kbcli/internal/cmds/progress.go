@@ -0,0 +1,167 @@
+package cmds
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"charm.land/bubbles/v2/progress"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+	"golang.org/x/term"
+)
+
+// progress bar labels
+const (
+	cancelHint     = "ctrl+c: cancel"
+	cancelingLabel = "canceling, waiting for the current kb..."
+	progressWidth  = 50
+)
+
+// progress bar style
+var hintStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#767676"))
+
+// ProgressBar shows the progress of a task in stderr. A nil ProgressBar shows nothing.
+type ProgressBar struct {
+	program *tea.Program
+}
+
+// progressMsg updates the progress bar. A negative percent hides the bar.
+type progressMsg struct {
+	label   string
+	percent float64
+}
+
+// taskDoneMsg tells the progress bar that the task finished.
+type taskDoneMsg struct{}
+
+// progressModel is the ui model of the progress bar.
+type progressModel struct {
+	title     string
+	label     string
+	percent   float64
+	canceling bool
+	done      bool
+	bar       progress.Model
+	cancel    context.CancelFunc
+}
+
+// RunCancelable runs the task with a context that ctrl-c cancels. The task must
+// stop and return what it completed.
+func RunCancelable(task func(ctx context.Context) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return task(ctx)
+}
+
+// RunWithProgress runs the task showing a progress bar in stderr. Ctrl-C cancels
+// the context given to the task, which must stop and return what it completed.
+// If the terminal is not interactive no bar is shown, but ctrl-c still cancels the task.
+func RunWithProgress(title string, task func(ctx context.Context, bar *ProgressBar) error) error {
+	if !IsTerminal(os.Stderr) || !IsTerminal(os.Stdin) {
+		return RunCancelable(func(ctx context.Context) error {
+			return task(ctx, nil)
+		})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	model := progressModel{
+		title:   title,
+		percent: -1,
+		bar:     progress.New(progress.WithDefaultBlend(), progress.WithWidth(progressWidth)),
+		cancel:  cancel,
+	}
+
+	program := tea.NewProgram(model, tea.WithOutput(os.Stderr))
+
+	taskErr := make(chan error, 1)
+
+	go func() {
+		err := task(ctx, &ProgressBar{program: program})
+		program.Send(taskDoneMsg{})
+		taskErr <- err
+	}()
+
+	_, err := program.Run()
+	if err != nil {
+		// the task keeps running without the bar.
+		slog.Warn("unable to show progress", slog.String("error", err.Error()))
+	}
+
+	return <-taskErr
+}
+
+// Update shows the label and the percent, between 0 and 1, of the task. A
+// negative percent shows only the label.
+func (p *ProgressBar) Update(label string, percent float64) {
+	if p == nil {
+		return
+	}
+
+	p.program.Send(progressMsg{label: label, percent: percent})
+}
+
+// Report shows the progress reported by the service.
+func (p *ProgressBar) Report(progress kbs.Progress) {
+	percent := progress.Percent()
+	if progress.Total <= 0 {
+		percent = -1
+	}
+
+	p.Update(progress.String(), percent)
+}
+
+// IsTerminal indicates if the file is a terminal, and not a pipe, a regular file or /dev/null.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+func (m progressModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case progressMsg:
+		m.label = msg.label
+		m.percent = msg.percent
+	case taskDoneMsg:
+		m.done = true
+
+		return m, tea.Quit
+	case tea.KeyPressMsg:
+		if msg.String() == "ctrl+c" {
+			m.canceling = true
+			m.cancel()
+		}
+	}
+
+	return m, nil
+}
+
+func (m progressModel) View() tea.View {
+	// the bar is removed once the task finished, so the report is printed alone.
+	if m.done {
+		return tea.NewView("")
+	}
+
+	view := m.title + " " + m.label + "\n"
+
+	if m.percent >= 0 {
+		view += m.bar.ViewAs(m.percent) + "\n"
+	}
+
+	if m.canceling {
+		return tea.NewView(view + cancelingLabel + "\n")
+	}
+
+	return tea.NewView(view + hintStyle.Render(cancelHint) + "\n")
+}
@@ -0,0 +1,199 @@
+package revisions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+	"github.com/spf13/cobra"
+)
+
+// revisionParams contains parameters required by history, diff and revert commands.
+type revisionParams struct {
+	id       string
+	revision int
+	yes      bool
+}
+
+// field labels
+const (
+	historyLabel           = "History of %s\n"
+	totalRevisionsLabel    = "Revisions:"
+	revisionCol            = "REV"
+	revisionColSeparator   = "---"
+	dateCol                = "DATE"
+	dateColSeparator       = "----"
+	changedCol             = "CHANGED"
+	changedColSeparator    = "-------"
+	noRevisionsMessage     = "kb has not been updated"
+	noDiffMessage          = "value and notes did not change"
+	revertQuestionLabel    = "> do you want to restore this revision? [y/n]: "
+	kbRevertedSuccessfully = "kb was restored to revision %d, run 'kb history --id %s' to see its revisions\n"
+	dateLayout             = time.DateTime
+)
+
+var revisionData revisionParams
+
+var (
+	errMissingID       = errors.New("the kb id is required, use --id")
+	errMissingRevision = errors.New("the revision number is required, use --rev")
+)
+
+func MakeHistoryCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "history",
+		Short: "list the revisions of a kb",
+		Long:  "list the revisions of a kb, the oldest first. Every update keeps the content it replaces as a revision",
+		Args:  cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			err := printHistory(context.Background(), service)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "getting history:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	newCmd.Flags().StringVarP(&revisionData.id, "id", "i", "", "knowledge base id")
+
+	return &newCmd
+}
+
+func MakeDiffCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:     "diff",
+		Short:   "show what an update changed",
+		Long:    "show the unified diff of the value and notes of a kb from the given revision to the next one, or to the current kb",
+		Example: "kb diff --id 88ac1fa1-2cdd-4f64-a4a3-13c6d162f504 --rev 2",
+		Args:    cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			err := printDiff(context.Background(), service)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "getting diff:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	newCmd.Flags().StringVarP(&revisionData.id, "id", "i", "", "knowledge base id")
+	newCmd.Flags().IntVarP(&revisionData.revision, "rev", "r", 0, "revision number, see kb history")
+
+	return &newCmd
+}
+
+func MakeRevertCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:     "revert",
+		Short:   "restore a revision of a kb",
+		Long:    "restore the content a kb had in the given revision. The content it replaces is kept as a new revision",
+		Example: "kb revert --id 88ac1fa1-2cdd-4f64-a4a3-13c6d162f504 --rev 2",
+		Args:    cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			err := revertKB(context.Background(), service)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "reverting kb:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	newCmd.Flags().StringVarP(&revisionData.id, "id", "i", "", "knowledge base id")
+	newCmd.Flags().IntVarP(&revisionData.revision, "rev", "r", 0, "revision number, see kb history")
+	newCmd.Flags().BoolVarP(&revisionData.yes, "yes", "y", false, "do not ask for confirmation")
+
+	return &newCmd
+}
+
+func printHistory(ctx context.Context, service *kbs.Service) error {
+	if kbs.IsStringEmpty(revisionData.id) {
+		return errMissingID
+	}
+
+	revisions, err := service.History(ctx, revisionData.id)
+	if err != nil {
+		return fmt.Errorf("unable to get history of kb %q: %w", revisionData.id, err)
+	}
+
+	if len(revisions) == 0 {
+		fmt.Println(noRevisionsMessage)
+		return nil
+	}
+
+	dateLength := len(dateLayout)
+
+	fmt.Println()
+	fmt.Printf(historyLabel, revisionData.id)
+	fmt.Println(cmds.TitleSeparator)
+	fmt.Println(totalRevisionsLabel, len(revisions))
+	fmt.Println()
+	fmt.Println(revisionCol, fmt.Sprintf("%-*s", dateLength, dateCol), changedCol)
+	fmt.Println(revisionColSeparator, fmt.Sprintf("%-*s", dateLength, dateColSeparator), changedColSeparator)
+	for _, revision := range revisions {
+		fmt.Println(fmt.Sprintf("%-*d", len(revisionCol), revision.Number),
+			revision.CreatedOn.Local().Format(dateLayout), strings.Join(revision.ChangedFields, ", "))
+	}
+
+	return nil
+}
+
+func printDiff(ctx context.Context, service *kbs.Service) error {
+	err := revisionData.validate()
+	if err != nil {
+		return err
+	}
+
+	diff, err := service.Diff(ctx, revisionData.id, revisionData.revision)
+	if err != nil {
+		return fmt.Errorf("unable to get diff of kb %q: %w", revisionData.id, err)
+	}
+
+	if diff == "" {
+		fmt.Println(noDiffMessage)
+		return nil
+	}
+
+	fmt.Print(diff)
+
+	return nil
+}
+
+func revertKB(ctx context.Context, service *kbs.Service) error {
+	err := revisionData.validate()
+	if err != nil {
+		return err
+	}
+
+	if !revisionData.yes && !cmds.AreYouSure(revertQuestionLabel) {
+		fmt.Println("bye")
+		return nil
+	}
+
+	kb, err := service.Revert(ctx, revisionData.id, revisionData.revision)
+	if err != nil {
+		return fmt.Errorf("unable to revert kb %q: %w", revisionData.id, err)
+	}
+
+	fmt.Println()
+	fmt.Println(kb)
+	fmt.Println()
+	fmt.Printf(kbRevertedSuccessfully, revisionData.revision, kb.ID)
+
+	return nil
+}
+
+func (r revisionParams) validate() error {
+	if kbs.IsStringEmpty(r.id) {
+		return errMissingID
+	}
+
+	if r.revision == 0 {
+		return errMissingRevision
+	}
+
+	return nil
+}
@@ -2,8 +2,10 @@ package syncs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
@@ -14,16 +16,35 @@ import (
 type syncKBParams struct {
 	showFailedKBs bool
 	showAddedKBs  bool
+	showPulledKBs bool
+	prefer        string
+	status        bool
 }
 
 // field labels
 const (
-	syncedLabel             = "Synced KBs"
+	syncedLabel             = "Pushed KBs"
+	pulledLabel             = "Pulled KBs"
+	conflictedLabel         = "Conflicted KBs"
 	notSyncedLabel          = "Not Synced KBs"
 	notSyncedErrorLabel     = "ERROR"
 	notSyncedErrorSeparator = "-----"
+	conflictReasonLabel     = "REASON"
+	conflictReasonSeparator = "------"
+	resolveConflictsHint    = "run sync again with --prefer local or --prefer remote to resolve them"
 	totalSyncedLabel        = "Total:"
 	totalNotSyncedLabel     = "Total:"
+	queuedLabel             = "Queued KBs"
+	newLabel                = "New KBs"
+	modifiedLabel           = "Modified KBs"
+	attemptsLabel           = "ATTEMPTS"
+	operationLabel          = "OPERATION"
+	nextAttemptLabel        = "NEXT ATTEMPT"
+	lastErrorLabel          = "LAST ERROR"
+	nothingPendingMessage   = "nothing is pending to be pushed"
+	syncingLabel            = "syncing"
+	canceledLabel           = "sync was canceled, these kbs were processed before it stopped"
+	summaryTemplate         = "pushed: %d, pulled: %d, conflicted: %d, resolved: %d, failed: %d\n"
 )
 
 var syncKBData syncKBParams
@@ -31,13 +52,21 @@ var syncKBData syncKBParams
 func MakeSyncCommand(service *kbs.Service) *cobra.Command {
 	newCmd := cobra.Command{
 		Use:   "sync",
-		Short: "sync locally saved kbs with the server",
-		Long:  "sync locally saved kbs with the server, these are kbs that could not be saved due to some server errors",
-		Run:   makeRunSyncCommand(service),
+		Short: "sync local kbs with the server",
+		Long: `push local kbs that were added or updated since the last sync to the server,
+then pull the kbs that were added or updated on the server. Kbs changed on both
+sides are reported as conflicts and left untouched, unless --prefer says which
+side must be kept. Kbs that could not be added wait in the sync queue and are
+retried with backoff until the server accepts them.
+Ctrl-C stops the sync after the current kb and reports what was synced`,
+		Run: makeRunSyncCommand(service),
 	}
 
-	newCmd.PersistentFlags().BoolVarP(&syncKBData.showAddedKBs, "show-added-kbs", "", false, "knowledge base key")
-	newCmd.PersistentFlags().BoolVarP(&syncKBData.showFailedKBs, "show-failed-kbs", "", false, "knowledge base key")
+	newCmd.PersistentFlags().BoolVarP(&syncKBData.showAddedKBs, "show-added-kbs", "", false, "print kbs pushed to the server")
+	newCmd.PersistentFlags().BoolVarP(&syncKBData.showPulledKBs, "show-pulled-kbs", "", false, "print kbs pulled from the server")
+	newCmd.PersistentFlags().BoolVarP(&syncKBData.showFailedKBs, "show-failed-kbs", "", false, "print kbs that could not be synced")
+	newCmd.PersistentFlags().BoolVarP(&syncKBData.status, "status", "", false, "print kbs pending to be pushed without syncing")
+	newCmd.PersistentFlags().StringVarP(&syncKBData.prefer, "prefer", "", "", "side kept when a kb was changed locally and on the server: local or remote")
 
 	return &newCmd
 }
@@ -46,7 +75,36 @@ func makeRunSyncCommand(service *kbs.Service) func(cmd *cobra.Command, args []st
 	return func(_ *cobra.Command, _ []string) {
 		ctx := context.Background()
 
-		result, err := service.Sync(ctx)
+		if syncKBData.status {
+			err := printSyncStatus(ctx, service)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "failed to get sync status:", err)
+				os.Exit(1)
+			}
+
+			return
+		}
+
+		prefer, err := kbs.ParseConflictResolution(syncKBData.prefer)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid sync options:", err)
+			os.Exit(1)
+		}
+
+		var result *kbs.SyncResult
+
+		err = cmds.RunWithProgress(syncingLabel, func(ctx context.Context, bar *cmds.ProgressBar) error {
+			var err error
+			result, err = service.Sync(ctx, kbs.SyncOptions{Prefer: prefer, Progress: bar.Report})
+
+			return err
+		})
+		if errors.Is(err, context.Canceled) && result != nil {
+			fmt.Fprintln(os.Stderr, canceledLabel)
+			printSyncedReport(result)
+			os.Exit(1)
+		}
+
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "failed to process synchronization:", err)
 			os.Exit(1)
@@ -65,13 +123,22 @@ func printSyncedReport(kbs *kbs.SyncResult) {
 		return
 	}
 
+	fmt.Printf(summaryTemplate, len(kbs.Pushed), len(kbs.Pulled), len(kbs.Conflicted), len(kbs.Resolved), len(kbs.FailedKeys))
+
+	if len(kbs.Conflicted) > 0 {
+		printConflictedKBs(kbs)
+	}
+
 	if syncKBData.dontPrint() {
-		fmt.Println("report output was not requested")
 		return
 	}
 
-	if syncKBData.showAddedKBs && len(kbs.NewIDs) > 0 {
-		printSyncedKBs(kbs)
+	if syncKBData.showAddedKBs && len(kbs.Pushed) > 0 {
+		printSyncedKBs(syncedLabel, kbs.Pushed)
+	}
+
+	if syncKBData.showPulledKBs && len(kbs.Pulled) > 0 {
+		printSyncedKBs(pulledLabel, kbs.Pulled)
 	}
 
 	if syncKBData.showFailedKBs && len(kbs.FailedKeys) > 0 {
@@ -79,6 +146,28 @@ func printSyncedReport(kbs *kbs.SyncResult) {
 	}
 }
 
+func printConflictedKBs(kbs *kbs.SyncResult) {
+	length := len(cmds.KeyCol)
+	for key := range kbs.Conflicted {
+		if len(key) > length {
+			length = len(key)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(conflictedLabel)
+	fmt.Println(cmds.TitleSeparator)
+	fmt.Println(totalNotSyncedLabel, len(kbs.Conflicted))
+	fmt.Println()
+	fmt.Println(fmt.Sprintf("%s%*s", cmds.KeyCol, length-len(cmds.KeyCol), ""), conflictReasonLabel)
+	fmt.Println(fmt.Sprintf("%s%*s", cmds.KeyColSeparator, length-len(cmds.KeyCol), ""), conflictReasonSeparator)
+	for key, reason := range kbs.Conflicted {
+		fmt.Println(fmt.Sprintf("%s%*s", key, length-len(key), ""), reason)
+	}
+	fmt.Println()
+	fmt.Println(resolveConflictsHint)
+}
+
 func printNotSyncedKBs(kbs *kbs.SyncResult) {
 	length := len(cmds.KeyCol)
 	for key := range kbs.FailedKeys {
@@ -99,19 +188,78 @@ func printNotSyncedKBs(kbs *kbs.SyncResult) {
 	}
 }
 
-func printSyncedKBs(kbs *kbs.SyncResult) {
+func printSyncedKBs(title string, ids map[string]string) {
 	fmt.Println()
-	fmt.Println(syncedLabel)
+	fmt.Println(title)
 	fmt.Println(cmds.TitleSeparator)
-	fmt.Println(totalSyncedLabel, len(kbs.NewIDs))
+	fmt.Println(totalSyncedLabel, len(ids))
 	fmt.Println()
 	fmt.Println(fmt.Sprintf("%-36s", cmds.IDCol), cmds.KeyCol)
 	fmt.Println(fmt.Sprintf("%-36s", cmds.IDColSeparator), cmds.KeyColSeparator)
-	for key, id := range kbs.NewIDs {
+	for key, id := range ids {
 		fmt.Println(id, key)
 	}
 }
 
 func (i syncKBParams) dontPrint() bool {
-	return !syncKBData.showAddedKBs && !syncKBData.showFailedKBs
+	return !syncKBData.showAddedKBs && !syncKBData.showPulledKBs && !syncKBData.showFailedKBs
+}
+
+func printSyncStatus(ctx context.Context, service *kbs.Service) error {
+	status, err := service.SyncStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get sync status: %w", err)
+	}
+
+	if status.Empty() {
+		fmt.Println(nothingPendingMessage)
+		return nil
+	}
+
+	if len(status.Queued) > 0 {
+		printQueuedKBs(status.Queued)
+	}
+
+	if len(status.New) > 0 {
+		printPendingKBs(newLabel, status.New)
+	}
+
+	if len(status.Modified) > 0 {
+		printPendingKBs(modifiedLabel, status.Modified)
+	}
+
+	return nil
+}
+
+func printQueuedKBs(entries []kbs.SyncQueueEntry) {
+	length := len(cmds.KeyCol)
+	for _, entry := range entries {
+		if len(entry.KB.Key) > length {
+			length = len(entry.KB.Key)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(queuedLabel)
+	fmt.Println(cmds.TitleSeparator)
+	fmt.Println(totalSyncedLabel, len(entries))
+	fmt.Println()
+	fmt.Printf("%-*s %-9s %-8s %-20s %s\n", length, cmds.KeyCol, operationLabel, attemptsLabel, nextAttemptLabel, lastErrorLabel)
+	for _, entry := range entries {
+		fmt.Printf("%-*s %-9s %-8d %-20s %s\n", length, entry.KB.Key, entry.Operation, entry.Attempts,
+			entry.NextAttemptOn.Local().Format(time.DateTime), entry.LastError)
+	}
+}
+
+func printPendingKBs(title string, pending []kbs.KB) {
+	fmt.Println()
+	fmt.Println(title)
+	fmt.Println(cmds.TitleSeparator)
+	fmt.Println(totalSyncedLabel, len(pending))
+	fmt.Println()
+	fmt.Println(fmt.Sprintf("%-36s", cmds.IDCol), cmds.KeyCol)
+	fmt.Println(fmt.Sprintf("%-36s", cmds.IDColSeparator), cmds.KeyColSeparator)
+	for _, kb := range pending {
+		fmt.Println(kb.ID, kb.Key)
+	}
 }
@@ -0,0 +1,209 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+	"github.com/spf13/cobra"
+)
+
+// tagParams contains parameters required by tag commands.
+type tagParams struct {
+	into string
+}
+
+// field labels
+const (
+	tagsLabel          = "Tags"
+	unusedTagsLabel    = "Tags used by one kb"
+	totalTagsLabel     = "Total:"
+	tagCol             = "TAG"
+	tagColSeparator    = "---"
+	countCol           = "KBS"
+	countColSeparator  = "---"
+	noTagsMessage      = "there are no tags"
+	noUnusedTagsMsg    = "every tag is used by more than one kb"
+	changedKBsTemplate = "%d kbs were updated\n"
+)
+
+var tagData tagParams
+
+var errMissingInto = errors.New("the tag to merge into is required, use --into")
+
+func MakeTagsCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "tags",
+		Short: "manage kb tags",
+		Long:  "list the tags in use and rename or merge them in every kb",
+		Run: func(cmd *cobra.Command, _ []string) {
+			if err := cmd.Help(); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+
+	newCmd.AddCommand(makeListCommand(service))
+	newCmd.AddCommand(makeUnusedCommand(service))
+	newCmd.AddCommand(makeRenameCommand(service))
+	newCmd.AddCommand(makeMergeCommand(service))
+
+	return &newCmd
+}
+
+func makeListCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "list",
+		Short: "list tags and how many kbs use them",
+		Long:  "list every tag of the kbs that are not in the trash, the most used first",
+		Args:  cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			err := listTags(context.Background(), service)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "listing tags:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	return &newCmd
+}
+
+func makeUnusedCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "unused",
+		Short: "list tags used by only one kb",
+		Long:  "list tags used by only one kb, which are usually typos or tags that can be merged with another one",
+		Args:  cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			err := listUnusedTags(context.Background(), service)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "listing unused tags:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	return &newCmd
+}
+
+func makeRenameCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:     "rename old new",
+		Short:   "rename a tag in every kb",
+		Long:    "replace the old tag with the new one in every kb that is not in the trash",
+		Example: "kb tags rename dokcer docker",
+		Args:    cobra.ExactArgs(2),
+		Run: func(_ *cobra.Command, args []string) {
+			err := renameTag(context.Background(), service, args[0], args[1])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "renaming tag:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	return &newCmd
+}
+
+func makeMergeCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:     "merge tag... --into tag",
+		Short:   "merge tags into one",
+		Long:    "replace the given tags with the --into tag in every kb that is not in the trash",
+		Example: "kb tags merge k8s kube --into kubernetes",
+		Args:    cobra.MinimumNArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			err := mergeTags(context.Background(), service, args)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "merging tags:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	newCmd.Flags().StringVarP(&tagData.into, "into", "", "", "tag that replaces the merged tags")
+
+	return &newCmd
+}
+
+func listTags(ctx context.Context, service *kbs.Service) error {
+	tags, err := service.ListTags(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list tags: %w", err)
+	}
+
+	if len(tags) == 0 {
+		fmt.Println(noTagsMessage)
+		return nil
+	}
+
+	printTags(tagsLabel, tags)
+
+	return nil
+}
+
+func listUnusedTags(ctx context.Context, service *kbs.Service) error {
+	tags, err := service.UnusedTags(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list unused tags: %w", err)
+	}
+
+	if len(tags) == 0 {
+		fmt.Println(noUnusedTagsMsg)
+		return nil
+	}
+
+	printTags(unusedTagsLabel, tags)
+
+	return nil
+}
+
+func renameTag(ctx context.Context, service *kbs.Service, oldTag, newTag string) error {
+	changed, err := service.RenameTag(ctx, oldTag, newTag)
+	if err != nil {
+		return fmt.Errorf("unable to rename tag %q: %w", oldTag, err)
+	}
+
+	fmt.Printf(changedKBsTemplate, changed)
+
+	return nil
+}
+
+func mergeTags(ctx context.Context, service *kbs.Service, tags []string) error {
+	if kbs.IsStringEmpty(tagData.into) {
+		return errMissingInto
+	}
+
+	changed, err := service.MergeTags(ctx, tags, tagData.into)
+	if err != nil {
+		return fmt.Errorf("unable to merge tags into %q: %w", tagData.into, err)
+	}
+
+	fmt.Printf(changedKBsTemplate, changed)
+
+	return nil
+}
+
+func printTags(title string, tags []kbs.TagCount) {
+	length := len(tagCol)
+	for _, tag := range tags {
+		if len(tag.Tag) > length {
+			length = len(tag.Tag)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(title)
+	fmt.Println(cmds.TitleSeparator)
+	fmt.Println(totalTagsLabel, len(tags))
+	fmt.Println()
+	fmt.Println(fmt.Sprintf("%-*s", length, tagCol), countCol)
+	fmt.Println(fmt.Sprintf("%-*s", length, tagColSeparator), countColSeparator)
+	for _, tag := range tags {
+		fmt.Println(fmt.Sprintf("%-*s", length, tag.Tag), tag.Count)
+	}
+}
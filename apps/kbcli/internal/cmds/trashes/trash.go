@@ -0,0 +1,219 @@
+package trashes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/cmds"
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+	"github.com/spf13/cobra"
+)
+
+// trashParams contains parameters required by trash commands.
+type trashParams struct {
+	id     string
+	all    bool
+	yes    bool
+	limit  uint32
+	offset uint32
+}
+
+// field labels
+const (
+	trashLabel              = "Trash"
+	totalTrashLabel         = "Total:"
+	deletedOnCol            = "DELETED ON"
+	deletedOnColSeparator   = "----------"
+	deletedOnFormat         = "2006-01-02 15:04:05"
+	emptyTrashMessage       = "the trash is empty"
+	restoredSuccessfully    = "kb was restored successfully"
+	purgedSuccessfully      = "kb was removed permanently"
+	purgeQuestionLabel      = "> do you want to remove permanently this kb? [y/n]: "
+	emptyTrashQuestionLabel = "> do you want to remove permanently all kbs in the trash? [y/n]: "
+	purgedKBsMessage        = "%d kbs were removed permanently\n"
+)
+
+var trashData trashParams
+
+var errMissingID = errors.New("kb id is required")
+
+func MakeTrashCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "trash",
+		Short: "manage deleted kbs",
+		Long:  "list, restore or remove permanently kbs that were deleted",
+		Run: func(cmd *cobra.Command, _ []string) {
+			if err := cmd.Help(); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+
+	newCmd.AddCommand(makeListCommand(service))
+	newCmd.AddCommand(makeRestoreCommand(service))
+	newCmd.AddCommand(makePurgeCommand(service))
+
+	return &newCmd
+}
+
+func makeListCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "list",
+		Short: "list kbs in the trash",
+		Long:  "list kbs in the trash",
+		Run: func(_ *cobra.Command, _ []string) {
+			err := listTrash(context.Background(), service)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "listing trash:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	newCmd.Flags().Uint32VarP(&trashData.limit, "limit", "l", 20, "number of rows you want to retrieve")
+	newCmd.Flags().Uint32VarP(&trashData.offset, "offset", "o", 0, "number of rows to skip before starting to return result rows")
+
+	return &newCmd
+}
+
+func makeRestoreCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "restore",
+		Short: "restore a kb from the trash",
+		Long:  "restore a kb with the given id from the trash",
+		Run: func(_ *cobra.Command, _ []string) {
+			err := restoreKB(context.Background(), service)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "restoring kb:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	newCmd.Flags().StringVarP(&trashData.id, "id", "i", "", "knowledge base id")
+
+	return &newCmd
+}
+
+func makePurgeCommand(service *kbs.Service) *cobra.Command {
+	newCmd := cobra.Command{
+		Use:   "purge",
+		Short: "remove permanently kbs from the trash",
+		Long:  "remove permanently a kb with the given id or all kbs in the trash",
+		Run: func(_ *cobra.Command, _ []string) {
+			err := purge(context.Background(), service)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "purging trash:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	newCmd.Flags().StringVarP(&trashData.id, "id", "i", "", "knowledge base id")
+	newCmd.Flags().BoolVarP(&trashData.all, "all", "a", false, "remove permanently all kbs in the trash")
+	newCmd.Flags().BoolVarP(&trashData.yes, "yes", "y", false, "do not ask for confirmation")
+
+	return &newCmd
+}
+
+func listTrash(ctx context.Context, service *kbs.Service) error {
+	filter := kbs.KBQueryFilter{
+		Limit:  trashData.limit,
+		Offset: trashData.offset,
+	}
+
+	result, err := service.GetTrash(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("unable to list trash: %w", err)
+	}
+
+	if result == nil || result.Total == 0 {
+		fmt.Println(emptyTrashMessage)
+		return nil
+	}
+
+	printTrash(result)
+
+	return nil
+}
+
+func restoreKB(ctx context.Context, service *kbs.Service) error {
+	if kbs.IsStringEmpty(trashData.id) {
+		return errMissingID
+	}
+
+	err := service.Restore(ctx, trashData.id)
+	if err != nil {
+		return fmt.Errorf("unable to restore kb: %w", err)
+	}
+
+	fmt.Println(restoredSuccessfully)
+
+	return nil
+}
+
+func purge(ctx context.Context, service *kbs.Service) error {
+	if trashData.all {
+		return emptyTrash(ctx, service)
+	}
+
+	if kbs.IsStringEmpty(trashData.id) {
+		return errMissingID
+	}
+
+	if !trashData.yes && !cmds.AreYouSure(purgeQuestionLabel) {
+		fmt.Println("bye")
+		return nil
+	}
+
+	err := service.Purge(ctx, trashData.id)
+	if err != nil {
+		return fmt.Errorf("unable to purge kb: %w", err)
+	}
+
+	fmt.Println(purgedSuccessfully)
+
+	return nil
+}
+
+func emptyTrash(ctx context.Context, service *kbs.Service) error {
+	if !trashData.yes && !cmds.AreYouSure(emptyTrashQuestionLabel) {
+		fmt.Println("bye")
+		return nil
+	}
+
+	purged, err := service.EmptyTrash(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to empty trash: %w", err)
+	}
+
+	fmt.Printf(purgedKBsMessage, purged)
+
+	return nil
+}
+
+func printTrash(result *kbs.GetAllResult) {
+	length := len(cmds.KeyCol)
+	for _, kb := range result.KBs {
+		if len(kb.Key) > length {
+			length = len(kb.Key)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(trashLabel)
+	fmt.Println(cmds.TitleSeparator)
+	fmt.Println(totalTrashLabel, result.Total)
+	fmt.Println()
+	fmt.Println(fmt.Sprintf("%-36s", cmds.IDCol), fmt.Sprintf("%-*s", length, cmds.KeyCol), deletedOnCol)
+	fmt.Println(fmt.Sprintf("%-36s", cmds.IDColSeparator), fmt.Sprintf("%-*s", length, cmds.KeyColSeparator), deletedOnColSeparator)
+	for _, kb := range result.KBs {
+		var deletedOn string
+		if kb.DeletedOn != nil {
+			deletedOn = kb.DeletedOn.Local().Format(deletedOnFormat)
+		}
+		fmt.Println(fmt.Sprintf("%-36s", kb.ID), fmt.Sprintf("%-*s", length, kb.Key), deletedOn)
+	}
+}
@@ -15,13 +15,28 @@ import (
 
 type errMsg error
 
-// ui model
-type model struct {
+// SavedMsg is sent by the form when the kb with its changes must be saved.
+type SavedMsg struct {
+	KB kbs.KB
+}
+
+// CanceledMsg is sent by the form when it is closed without saving.
+type CanceledMsg struct{}
+
+// Form edits a kb. It runs on its own in the update command, but it can also be
+// embedded in other views, which get a SavedMsg or a CanceledMsg when done.
+type Form struct {
+	kb      kbs.KB
 	inputs  []cmds.InputComponent
 	focused int
 	err     error
 }
 
+// ui model
+type model struct {
+	form *Form
+}
+
 // ui form fields
 const (
 	key = iota
@@ -46,7 +61,7 @@ var (
 )
 
 func runInteractive() error {
-	p := tea.NewProgram(initialModel())
+	p := tea.NewProgram(model{form: NewForm(*updateKBData.kb)})
 
 	_, err := p.Run()
 	if err != nil {
@@ -56,7 +71,8 @@ func runInteractive() error {
 	return nil
 }
 
-func initialModel() model {
+// NewForm creates a form filled with the data of the given kb.
+func NewForm(kb kbs.KB) *Form {
 	inputs := make([]cmds.InputComponent, 7)
 	keyInput := textinput.New()
 	keyInput.Placeholder = "key"
@@ -64,7 +80,7 @@ func initialModel() model {
 	keyInput.CharLimit = 64
 	keyInput.SetWidth(70)
 	keyInput.Prompt = ""
-	keyInput.SetValue(updateKBData.kb.Key)
+	keyInput.SetValue(kb.Key)
 	inputs[key].TextInput = &keyInput
 
 	categoryInput := textinput.New()
@@ -72,7 +88,7 @@ func initialModel() model {
 	categoryInput.CharLimit = 64
 	categoryInput.SetWidth(70)
 	categoryInput.Prompt = ""
-	categoryInput.SetValue(updateKBData.kb.Category)
+	categoryInput.SetValue(kb.Category)
 	inputs[category].TextInput = &categoryInput
 
 	namespaceInput := textinput.New()
@@ -80,7 +96,7 @@ func initialModel() model {
 	namespaceInput.CharLimit = 64
 	namespaceInput.SetWidth(70)
 	namespaceInput.Prompt = ""
-	namespaceInput.SetValue(updateKBData.kb.Namespace)
+	namespaceInput.SetValue(kb.Namespace)
 	inputs[namespace].TextInput = &namespaceInput
 
 	valueInput := textarea.New()
@@ -89,7 +105,7 @@ func initialModel() model {
 	valueInput.ShowLineNumbers = false
 	valueInput.SetHeight(4)
 	valueInput.SetWidth(80)
-	valueInput.SetValue(updateKBData.kb.Value)
+	valueInput.SetValue(kb.Value)
 	inputs[value].TextArea = &valueInput
 
 	notesInput := textarea.New()
@@ -99,7 +115,7 @@ func initialModel() model {
 	notesInput.ShowLineNumbers = false
 	notesInput.SetHeight(4)
 	notesInput.SetWidth(80)
-	notesInput.SetValue(updateKBData.kb.Notes)
+	notesInput.SetValue(kb.Notes)
 	inputs[notes].TextArea = &notesInput
 
 	refInput := textinput.New()
@@ -107,17 +123,18 @@ func initialModel() model {
 	refInput.CharLimit = 64
 	refInput.SetWidth(70)
 	refInput.Prompt = ""
-	refInput.SetValue(updateKBData.kb.Reference)
+	refInput.SetValue(kb.Reference)
 	inputs[reference].TextInput = &refInput
 
 	tagsInput := textinput.New()
 	tagsInput.Placeholder = "keyword1 keyword2 keyword3 keywordN"
 	tagsInput.CharLimit = 100
 	tagsInput.Prompt = ""
-	tagsInput.SetValue(strings.Join(updateKBData.kb.Tags, " "))
+	tagsInput.SetValue(strings.Join(kb.Tags, " "))
 	inputs[tags].TextInput = &tagsInput
 
-	return model{
+	return &Form{
+		kb:      kb,
 		inputs:  inputs,
 		focused: 0,
 		err:     nil,
@@ -125,57 +142,76 @@ func initialModel() model {
 }
 
 func (m model) Init() tea.Cmd {
-	return textinput.Blink
+	return m.form.Init()
 }
 
 //nolint:ireturn // BubbleTea architecture requires interface return
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	cmds := make([]tea.Cmd, len(m.inputs))
+	switch msg := msg.(type) {
+	case SavedMsg:
+		updateKBData.kb = &msg.KB
+		return m, tea.Quit
+	case CanceledMsg:
+		exitGUI = true
+		return m, tea.Quit
+	}
+
+	return m, m.form.Update(msg)
+}
+
+func (m model) View() tea.View {
+	return tea.NewView(m.form.View())
+}
+
+func (f *Form) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update updates the inputs with the given message.
+func (f *Form) Update(msg tea.Msg) tea.Cmd {
+	cmds := make([]tea.Cmd, len(f.inputs))
 
 	switch msg := msg.(type) {
 	case tea.KeyPressMsg:
 		switch msg.String() {
 		case "ctrl+c", "esc":
-			exitGUI = true
-			return m, tea.Quit
+			return cancel
 		case "shift+tab", "ctrl+p":
-			m.prevInput()
+			f.prevInput()
 		case "ctrl+s":
-			m.toAddKBParams()
-			return m, tea.Quit
+			return f.save()
 		case "tab", "ctrl+n":
-			if m.focused == len(m.inputs)-1 {
-				m.toAddKBParams()
-				return m, tea.Quit
+			if f.focused == len(f.inputs)-1 {
+				return f.save()
 			}
-			m.nextInput()
+			f.nextInput()
 		}
-		for i := range m.inputs {
-			m.inputs[i].Blur()
+		for i := range f.inputs {
+			f.inputs[i].Blur()
 		}
-		m.inputs[m.focused].Focus()
+		f.inputs[f.focused].Focus()
 
 	// We handle errors just like any other message
 	case errMsg:
-		m.err = msg
-		return m, nil
+		f.err = msg
+		return nil
 	}
 
-	for i := range m.inputs {
-		if m.inputs[i].TextInput != nil {
-			textInputModel, textInputCmd := m.inputs[i].TextInput.Update(msg)
-			m.inputs[i].TextInput, cmds[i] = &textInputModel, textInputCmd
+	for i := range f.inputs {
+		if f.inputs[i].TextInput != nil {
+			textInputModel, textInputCmd := f.inputs[i].TextInput.Update(msg)
+			f.inputs[i].TextInput, cmds[i] = &textInputModel, textInputCmd
 			continue
 		}
-		textInputModel, textInputCmd := m.inputs[i].TextArea.Update(msg)
-		m.inputs[i].TextArea, cmds[i] = &textInputModel, textInputCmd
+		textInputModel, textInputCmd := f.inputs[i].TextArea.Update(msg)
+		f.inputs[i].TextArea, cmds[i] = &textInputModel, textInputCmd
 	}
 
-	return m, tea.Batch(cmds...)
+	return tea.Batch(cmds...)
 }
 
-func (m model) View() tea.View {
-	return tea.NewView(fmt.Sprintf(
+func (f *Form) View() string {
+	return fmt.Sprintf(
 		` Updating KB [%s] - %s:
 
 %s
@@ -204,55 +240,69 @@ func (m model) View() tea.View {
 • tab fields • shift+tab fields • ctrl+s save • ctrl+c: quit
 
 `,
-		updateKBData.kb.ID, updateKBData.kb.Key,
+		f.kb.ID, f.kb.Key,
 		inputStyle.Width(30).Render("Key"),
-		m.inputs[key].View(),
+		f.inputs[key].View(),
 		inputStyle.Width(8).Render("Category"),
-		m.inputs[category].View(),
+		f.inputs[category].View(),
 		inputStyle.Width(9).Render("Namespace"),
-		m.inputs[namespace].View(),
+		f.inputs[namespace].View(),
 		inputStyle.Width(6).Render("Value"),
-		m.inputs[value].View(),
+		f.inputs[value].View(),
 		inputStyle.Width(6).Render("Notes"),
-		m.inputs[notes].View(),
+		f.inputs[notes].View(),
 		inputStyle.Width(9).Render("Reference"),
-		m.inputs[reference].View(),
+		f.inputs[reference].View(),
 		inputStyle.Width(9).Render("Tags"),
-		m.inputs[tags].View(),
+		f.inputs[tags].View(),
 		continueStyle.Render("Continue ->"),
-	))
+	)
 }
 
 // nextInput focuses the next input field
-func (m *model) nextInput() {
-	m.focused = (m.focused + 1) % len(m.inputs)
+func (f *Form) nextInput() {
+	f.focused = (f.focused + 1) % len(f.inputs)
 }
 
 // prevInput focuses the previous input field
-func (m *model) prevInput() {
-	m.focused--
+func (f *Form) prevInput() {
+	f.focused--
 	// Wrap around
-	if m.focused < 0 {
-		m.focused = len(m.inputs) - 1
+	if f.focused < 0 {
+		f.focused = len(f.inputs) - 1
 	}
 }
 
-func (m *model) toAddKBParams() {
-	updateKBData.kb.Key = strings.ToLower(m.inputs[key].Value())
-	updateKBData.kb.Category = strings.ToLower(m.inputs[category].Value())
-	updateKBData.kb.Namespace = strings.ToLower(m.inputs[namespace].Value())
-	updateKBData.kb.Value = m.inputs[value].Value()
-	updateKBData.kb.Notes = m.inputs[notes].Value()
-	updateKBData.kb.Reference = m.inputs[reference].Value()
-	updateKBData.kb.Tags = m.convertTagsToArray()
+// save sends the kb with the values of the inputs.
+func (f *Form) save() tea.Cmd {
+	f.toAddKBParams()
+	kb := f.kb
+
+	return func() tea.Msg {
+		return SavedMsg{KB: kb}
+	}
+}
+
+func cancel() tea.Msg {
+	return CanceledMsg{}
+}
+
+func (f *Form) toAddKBParams() {
+	f.kb.Key = strings.ToLower(f.inputs[key].Value())
+	f.kb.Category = strings.ToLower(f.inputs[category].Value())
+	f.kb.Namespace = kbs.CleanNamespace(f.inputs[namespace].Value())
+	f.kb.Value = f.inputs[value].Value()
+	f.kb.Notes = f.inputs[notes].Value()
+	f.kb.Reference = f.inputs[reference].Value()
+	f.kb.Tags = f.convertTagsToArray()
 }
 
-func (m *model) convertTagsToArray() []string {
-	if kbs.IsStringEmpty(m.inputs[tags].Value()) {
+func (f *Form) convertTagsToArray() []string {
+	if kbs.IsStringEmpty(f.inputs[tags].Value()) {
 		return nil
 	}
 
-	cleanedTags := m.inputs[tags].Value()
+	cleanedTags := f.inputs[tags].Value()
 	cleanedTags = strings.TrimLeft(cleanedTags, " ")
 	cleanedTags = strings.TrimRight(cleanedTags, " ")
 
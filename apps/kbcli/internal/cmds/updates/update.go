@@ -16,6 +16,7 @@ type updateKB struct {
 	kb          *kbs.KB
 	id          string
 	interactive bool
+	editor      bool
 }
 
 const (
@@ -41,6 +42,7 @@ func MakeUpdateCommand(service *kbs.Service) *cobra.Command {
 
 	newCmd.PersistentFlags().StringVarP(&updateKBData.id, "id", "i", "", "knowledge base id")
 	newCmd.PersistentFlags().BoolVarP(&updateKBData.interactive, "ux", "u", false, "show result in interactive mode")
+	newCmd.PersistentFlags().BoolVarP(&updateKBData.editor, "editor", "e", false, cmds.EditorUsage)
 
 	return &newCmd
 }
@@ -82,7 +84,7 @@ func showKBToUpdate(ctx context.Context) error {
 
 	fmt.Println()
 
-	err = runInteractive()
+	err = editKB(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to show form: %w", err)
 	}
@@ -97,7 +99,8 @@ func showKBToUpdate(ctx context.Context) error {
 	}
 
 	fmt.Println("updating...")
-	err = updateKBData.service.Update(ctx, getKBToUpdate())
+	kbToSave := getKBToUpdate()
+	err = updateKBData.service.Update(ctx, kbToSave)
 	if err != nil {
 		return fmt.Errorf("unable to update kb: %w", err)
 	}
@@ -108,6 +111,30 @@ func showKBToUpdate(ctx context.Context) error {
 	return nil
 }
 
+// editKB lets the user change the kb in the editor, or in the form otherwise.
+func editKB(ctx context.Context) error {
+	if !updateKBData.editor {
+		return runInteractive()
+	}
+
+	newKB, err := cmds.EditKB(*updateKBData.kb, func(newKB kbs.NewKB) error {
+		return updateKBData.service.Validate(ctx, newKB)
+	})
+	if errors.Is(err, cmds.ErrEditCanceled) {
+		exitGUI = true
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	editedKB := updateKBData.kb.Edited(newKB)
+	updateKBData.kb = &editedKB
+
+	return nil
+}
+
 func fillMissingUpdateFields() {
 	if !kbs.IsStringEmpty(updateKBData.id) {
 		return
@@ -0,0 +1,172 @@
+package kbs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+var (
+	errUnknownCategory      = errors.New("kb category is not registered, add it with: kb categories add")
+	errEmptyCategoryName    = errors.New("category name is empty")
+	errCategoryNameValues   = errors.New("category name must contain only letters, digits and hyphens")
+	errUnknownRequiredField = errors.New("category can only require these fields: " + strings.Join(categoryFields(), ", "))
+	errUnknownValueFormat   = errors.New("category value format must be one of: " + strings.Join(valueFormats(), ", "))
+	errEmptyKBNotes         = errors.New("kb notes is empty")
+	errEmptyKBReference     = errors.New("kb reference is empty")
+	errKBValueIsNotURL      = errors.New("kb value must be an absolute url, e.g. https://go.dev")
+)
+
+// NewCategoryRegistry returns a registry with the given categories.
+func NewCategoryRegistry(categories []Category) CategoryRegistry {
+	registry := make(CategoryRegistry, len(categories))
+
+	for _, category := range categories {
+		registry[category.Name] = category
+	}
+
+	return registry
+}
+
+// IsMedia indicates if the value of the kbs of the category is a media file.
+func (r CategoryRegistry) IsMedia(name string) bool {
+	category, ok := r[strings.ToLower(name)]
+
+	return ok && category.ValueFormat == ValueFormatMedia
+}
+
+// validate checks that the category is registered and that the kb fields follow its rules.
+func (r CategoryRegistry) validate(name, value, notes, reference string) error {
+	category, ok := r[name]
+	if !ok {
+		return fmt.Errorf("%q: %w", name, errUnknownCategory)
+	}
+
+	var err error
+
+	fields := map[string]struct {
+		value string
+		err   error
+	}{
+		NotesField:     {value: notes, err: errEmptyKBNotes},
+		ReferenceField: {value: reference, err: errEmptyKBReference},
+	}
+
+	for _, requiredField := range category.RequiredFields {
+		field, ok := fields[requiredField]
+		if ok && IsStringEmpty(field.value) {
+			err = errors.Join(err, field.err)
+		}
+	}
+
+	if category.ValueFormat == ValueFormatURL && value != "" && !isAbsoluteURL(value) {
+		err = errors.Join(err, errKBValueIsNotURL)
+	}
+
+	return err
+}
+
+// validate checks the category has a name and that its rules are known.
+func (c Category) validate() error {
+	var err error
+
+	if c.Name == "" {
+		err = errors.Join(err, errEmptyCategoryName)
+	} else if !IsLetter(c.Name) {
+		err = errors.Join(err, errCategoryNameValues)
+	}
+
+	for _, field := range c.RequiredFields {
+		if !slices.Contains(categoryFields(), field) {
+			err = errors.Join(err, fmt.Errorf("%q: %w", field, errUnknownRequiredField))
+		}
+	}
+
+	if !slices.Contains(valueFormats(), string(c.ValueFormat)) {
+		err = errors.Join(err, fmt.Errorf("%q: %w", c.ValueFormat, errUnknownValueFormat))
+	}
+
+	return err
+}
+
+// normalize returns the category in lower case, with the text value format by
+// default and with its required fields sorted and without duplicates.
+func (c Category) normalize() Category {
+	c.Name = strings.ToLower(strings.TrimSpace(c.Name))
+	c.Description = strings.TrimSpace(c.Description)
+	c.ValueFormat = ValueFormat(strings.ToLower(strings.TrimSpace(string(c.ValueFormat))))
+
+	if c.ValueFormat == "" {
+		c.ValueFormat = ValueFormatText
+	}
+
+	requiredFields := make([]string, 0, len(c.RequiredFields))
+	for _, field := range c.RequiredFields {
+		requiredFields = append(requiredFields, strings.ToLower(strings.TrimSpace(field)))
+	}
+
+	slices.Sort(requiredFields)
+
+	c.RequiredFields = slices.Compact(requiredFields)
+
+	return c
+}
+
+// ListCategories gets the registered categories sorted by name.
+func (s *Service) ListCategories(ctx context.Context) ([]Category, error) {
+	categories, err := s.storage.GetCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// Categories gets the registry of categories kbs can have.
+func (s *Service) Categories(ctx context.Context) (CategoryRegistry, error) {
+	categories, err := s.storage.GetCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get categories: %w", err)
+	}
+
+	return NewCategoryRegistry(categories), nil
+}
+
+// AddCategory registers the category, or replaces the rules of the category
+// with the same name.
+func (s *Service) AddCategory(ctx context.Context, category Category) (*Category, error) {
+	category = category.normalize()
+
+	err := category.validate()
+	if err != nil {
+		return nil, NewDataError(fmt.Sprintf("the given values are not valid: %s", err))
+	}
+
+	err = s.storage.SaveCategory(ctx, category)
+	if err != nil {
+		return nil, fmt.Errorf("unable to add category: %w", err)
+	}
+
+	return &category, nil
+}
+
+func categoryFields() []string {
+	return []string{NotesField, ReferenceField}
+}
+
+func valueFormats() []string {
+	return []string{string(ValueFormatText), string(ValueFormatURL), string(ValueFormatMedia)}
+}
+
+// isAbsoluteURL indicates if the value is a url with scheme and host, e.g. https://go.dev.
+func isAbsoluteURL(value string) bool {
+	result, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+
+	return result.Scheme != "" && result.Host != ""
+}
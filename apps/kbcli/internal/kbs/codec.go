@@ -0,0 +1,495 @@
+package kbs
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Format defines how kbs are written to and read from files.
+type Format string
+
+// Encoder writes kbs to an output in a given format. Encode can be called many
+// times, e.g. once per page of kbs, and Close must be called once at the end.
+type Encoder interface {
+	Encode(kbs ...KB) error
+	Close() error
+}
+
+// Codec knows how to encode and decode kbs in a format.
+type Codec struct {
+	// Extensions file extensions, with the dot, used to detect the format.
+	Extensions []string
+	NewEncoder func(w io.Writer) Encoder
+	// Decode reads kbs one by one, so big files are not held in memory.
+	Decode func(r io.Reader) iter.Seq2[NewKB, error]
+}
+
+// supported formats
+const (
+	FormatYAML  Format = "yaml"
+	FormatJSON  Format = "json"
+	FormatJSONL Format = "jsonl"
+	FormatCSV   Format = "csv"
+)
+
+// DefaultFormat format used when none is given. It was the only one supported before.
+const DefaultFormat = FormatYAML
+
+// csv columns
+const (
+	csvIDColumn        = "id"
+	csvKeyColumn       = "key"
+	csvValueColumn     = "value"
+	csvNotesColumn     = "notes"
+	csvCategoryColumn  = "category"
+	csvReferenceColumn = "reference"
+	csvMediaTypeColumn = "media_type"
+	csvNamespaceColumn = "namespace"
+	csvTagsColumn      = "tags"
+)
+
+var csvHeader = []string{
+	csvIDColumn, csvKeyColumn, csvValueColumn, csvNotesColumn, csvCategoryColumn,
+	csvReferenceColumn, csvNamespaceColumn, csvTagsColumn,
+}
+
+var errUnknownFormat = errors.New("unknown format")
+
+var codecs = map[Format]Codec{
+	FormatYAML: {
+		Extensions: []string{".yaml", ".yml"},
+		NewEncoder: newYAMLEncoder,
+		Decode:     decodeYAML,
+	},
+	FormatJSON: {
+		Extensions: []string{".json"},
+		NewEncoder: newJSONEncoder,
+		Decode:     decodeJSON,
+	},
+	FormatJSONL: {
+		Extensions: []string{".jsonl", ".ndjson"},
+		NewEncoder: newJSONLEncoder,
+		Decode:     decodeJSONL,
+	},
+	FormatCSV: {
+		Extensions: []string{".csv"},
+		NewEncoder: newCSVEncoder,
+		Decode:     decodeCSV,
+	},
+}
+
+// RegisterCodec adds a codec for the given format or replaces the existing one.
+func RegisterCodec(format Format, codec Codec) {
+	codecs[format] = codec
+}
+
+// GetCodec gets the codec of the given format.
+func GetCodec(format Format) (Codec, error) {
+	codec, ok := codecs[format]
+	if !ok {
+		return Codec{}, fmt.Errorf("%w %q, it must be one of: %s", errUnknownFormat, format, strings.Join(Formats(), ", "))
+	}
+
+	return codec, nil
+}
+
+// Formats returns the names of the registered formats.
+func Formats() []string {
+	formats := make([]string, 0, len(codecs))
+	for format := range codecs {
+		formats = append(formats, string(format))
+	}
+
+	slices.Sort(formats)
+
+	return formats
+}
+
+// ParseFormat gets the format with the given name.
+func ParseFormat(value string) (Format, error) {
+	format := Format(strings.ToLower(strings.TrimSpace(value)))
+
+	_, err := GetCodec(format)
+	if err != nil {
+		return "", err
+	}
+
+	return format, nil
+}
+
+// DetectFormat gets the format of a file based on its extension. Files with
+// unknown extensions use the default format.
+func DetectFormat(path string) Format {
+	extension := strings.ToLower(filepath.Ext(path))
+
+	for format, codec := range codecs {
+		if slices.Contains(codec.Extensions, extension) {
+			return format
+		}
+	}
+
+	return DefaultFormat
+}
+
+// Decode reads all kbs in the given format.
+func Decode(format Format, r io.Reader) ([]NewKB, error) {
+	records, err := DecodeSeq(format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	var newKBs []NewKB
+
+	for newKB, err := range records {
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode %s kbs: %w", format, err)
+		}
+
+		newKBs = append(newKBs, newKB)
+	}
+
+	return newKBs, nil
+}
+
+// DecodeSeq returns an iterator that reads the kbs in the given format one by one.
+// It stops after the first error.
+func DecodeSeq(format Format, r io.Reader) (iter.Seq2[NewKB, error], error) {
+	codec, err := GetCodec(format)
+	if err != nil {
+		return nil, err
+	}
+
+	return codec.Decode(r), nil
+}
+
+// NewEncoder creates an encoder that writes kbs in the given format.
+func NewEncoder(format Format, w io.Writer) (Encoder, error) {
+	codec, err := GetCodec(format)
+	if err != nil {
+		return nil, err
+	}
+
+	return codec.NewEncoder(w), nil
+}
+
+// yamlEncoder writes one yaml document per kb.
+type yamlEncoder struct {
+	encoder *yaml.Encoder
+}
+
+func newYAMLEncoder(w io.Writer) Encoder {
+	return &yamlEncoder{
+		encoder: yaml.NewEncoder(w),
+	}
+}
+
+func (e *yamlEncoder) Encode(kbs ...KB) error {
+	for _, kb := range kbs {
+		err := e.encoder.Encode(kb)
+		if err != nil {
+			return fmt.Errorf("unable to encode kb %q to yaml: %w", kb.Key, err)
+		}
+	}
+
+	return nil
+}
+
+func (e *yamlEncoder) Close() error {
+	return e.encoder.Close()
+}
+
+func decodeYAML(r io.Reader) iter.Seq2[NewKB, error] {
+	return func(yield func(NewKB, error) bool) {
+		dec := yaml.NewDecoder(r)
+
+		for document := 0; ; document++ {
+			var kbItem NewKB
+
+			err := dec.Decode(&kbItem)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			if err != nil {
+				yield(NewKB{}, fmt.Errorf("unable to decode yaml document %d: %w", document, err))
+				return
+			}
+
+			if !yield(kbItem, nil) {
+				return
+			}
+		}
+	}
+}
+
+// jsonEncoder writes all kbs in one json array.
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONEncoder(w io.Writer) Encoder {
+	return &jsonEncoder{
+		w: w,
+	}
+}
+
+func (e *jsonEncoder) Encode(kbs ...KB) error {
+	for _, kb := range kbs {
+		kbData, err := json.MarshalIndent(kb, "  ", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to encode kb %q to json: %w", kb.Key, err)
+		}
+
+		separator := ",\n  "
+		if e.count == 0 {
+			separator = "[\n  "
+		}
+
+		_, err = fmt.Fprintf(e.w, "%s%s", separator, kbData)
+		if err != nil {
+			return fmt.Errorf("unable to write kb %q: %w", kb.Key, err)
+		}
+
+		e.count++
+	}
+
+	return nil
+}
+
+func (e *jsonEncoder) Close() error {
+	closing := "\n]\n"
+	if e.count == 0 {
+		closing = "[]\n"
+	}
+
+	_, err := io.WriteString(e.w, closing)
+	if err != nil {
+		return fmt.Errorf("unable to close json array: %w", err)
+	}
+
+	return nil
+}
+
+// decodeJSON reads the kbs of a json array one by one.
+func decodeJSON(r io.Reader) iter.Seq2[NewKB, error] {
+	return func(yield func(NewKB, error) bool) {
+		dec := json.NewDecoder(r)
+
+		token, err := dec.Token()
+		if err != nil {
+			yield(NewKB{}, fmt.Errorf("unable to decode json array: %w", err))
+			return
+		}
+
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			yield(NewKB{}, errors.New("unable to decode json array: content is not an array"))
+			return
+		}
+
+		for index := 0; dec.More(); index++ {
+			var kbItem NewKB
+
+			err := dec.Decode(&kbItem)
+			if err != nil {
+				yield(NewKB{}, fmt.Errorf("unable to decode json item %d: %w", index, err))
+				return
+			}
+
+			if !yield(kbItem, nil) {
+				return
+			}
+		}
+	}
+}
+
+// jsonlEncoder writes one json object per line.
+type jsonlEncoder struct {
+	encoder *json.Encoder
+}
+
+func newJSONLEncoder(w io.Writer) Encoder {
+	return &jsonlEncoder{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+func (e *jsonlEncoder) Encode(kbs ...KB) error {
+	for _, kb := range kbs {
+		err := e.encoder.Encode(kb)
+		if err != nil {
+			return fmt.Errorf("unable to encode kb %q to json lines: %w", kb.Key, err)
+		}
+	}
+
+	return nil
+}
+
+func (e *jsonlEncoder) Close() error {
+	return nil
+}
+
+func decodeJSONL(r io.Reader) iter.Seq2[NewKB, error] {
+	return func(yield func(NewKB, error) bool) {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 10*1024*1024)
+
+		for line := 1; scanner.Scan(); line++ {
+			if IsStringEmpty(scanner.Text()) {
+				continue
+			}
+
+			var kbItem NewKB
+
+			err := json.Unmarshal(scanner.Bytes(), &kbItem)
+			if err != nil {
+				yield(NewKB{}, fmt.Errorf("unable to decode line %d: %w", line, err))
+				return
+			}
+
+			if !yield(kbItem, nil) {
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			yield(NewKB{}, fmt.Errorf("unable to read json lines: %w", err))
+		}
+	}
+}
+
+// csvEncoder writes a header and one row per kb. Tags are separated by spaces.
+type csvEncoder struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func newCSVEncoder(w io.Writer) Encoder {
+	return &csvEncoder{
+		writer: csv.NewWriter(w),
+	}
+}
+
+func (e *csvEncoder) Encode(kbs ...KB) error {
+	err := e.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	for _, kb := range kbs {
+		err := e.writer.Write([]string{
+			kb.ID, kb.Key, kb.Value, kb.Notes, kb.Category,
+			kb.Reference, kb.Namespace, strings.Join(kb.Tags, " "),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to encode kb %q to csv: %w", kb.Key, err)
+		}
+	}
+
+	e.writer.Flush()
+
+	return e.writer.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	err := e.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	e.writer.Flush()
+
+	return e.writer.Error()
+}
+
+func (e *csvEncoder) writeHeader() error {
+	if e.headerWritten {
+		return nil
+	}
+
+	err := e.writer.Write(csvHeader)
+	if err != nil {
+		return fmt.Errorf("unable to write csv header: %w", err)
+	}
+
+	e.headerWritten = true
+
+	return nil
+}
+
+// decodeCSV reads kbs from a csv with a header row. Columns are matched by
+// name, so their order does not matter and unknown columns are ignored.
+func decodeCSV(r io.Reader) iter.Seq2[NewKB, error] {
+	return func(yield func(NewKB, error) bool) {
+		reader := csv.NewReader(r)
+
+		header, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+
+		if err != nil {
+			yield(NewKB{}, fmt.Errorf("unable to read csv header: %w", err))
+			return
+		}
+
+		columns := make(map[string]int, len(header))
+		for index, name := range header {
+			columns[strings.ToLower(strings.TrimSpace(name))] = index
+		}
+
+		if _, ok := columns[csvKeyColumn]; !ok {
+			yield(NewKB{}, fmt.Errorf("csv header must have a %q column", csvKeyColumn))
+			return
+		}
+
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			if err != nil {
+				yield(NewKB{}, fmt.Errorf("unable to read csv record: %w", err))
+				return
+			}
+
+			if !yield(csvRecordToNewKB(columns, record), nil) {
+				return
+			}
+		}
+	}
+}
+
+func csvRecordToNewKB(columns map[string]int, record []string) NewKB {
+	field := func(name string) string {
+		index, ok := columns[name]
+		if !ok || index >= len(record) {
+			return ""
+		}
+
+		return record[index]
+	}
+
+	return NewKB{
+		ID:        field(csvIDColumn),
+		Key:       field(csvKeyColumn),
+		Value:     field(csvValueColumn),
+		Notes:     field(csvNotesColumn),
+		Category:  field(csvCategoryColumn),
+		Reference: field(csvReferenceColumn),
+		MediaType: field(csvMediaTypeColumn),
+		Namespace: field(csvNamespaceColumn),
+		Tags:      strings.Fields(field(csvTagsColumn)),
+	}
+}
@@ -0,0 +1,158 @@
+package kbs_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeCodecKBs() []kbs.KB {
+	return []kbs.KB{
+		{
+			ID:        "1",
+			Key:       "halving",
+			Value:     "The number of bitcoins generated per block is decreased 50% every four years",
+			Notes:     "Bitcoins have a finite supply, \"21 million\"",
+			Category:  "bitcoin",
+			Reference: "https://bitcoin.org",
+			Namespace: "cryptos",
+			Tags:      []string{"bitcoin", "halving"},
+		},
+		{
+			ID:        "2",
+			Key:       "docker-compose-up",
+			Value:     "docker compose up -d\ndocker compose logs -f",
+			Category:  "command",
+			Namespace: "devops",
+			Tags:      []string{"docker"},
+		},
+	}
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	for _, format := range []kbs.Format{kbs.FormatYAML, kbs.FormatJSON, kbs.FormatJSONL, kbs.FormatCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			givenKBs := makeCodecKBs()
+			var output bytes.Buffer
+
+			encoder, err := kbs.NewEncoder(format, &output)
+			require.NoError(t, err)
+			// kbs are written in pages by the export command.
+			require.NoError(t, encoder.Encode(givenKBs[0]))
+			require.NoError(t, encoder.Encode(givenKBs[1]))
+			require.NoError(t, encoder.Close())
+
+			got, err := kbs.Decode(format, &output)
+
+			require.NoError(t, err)
+			require.Len(t, got, len(givenKBs))
+			for i, kb := range givenKBs {
+				assert.Equal(t, kb.Key, got[i].Key)
+				assert.Equal(t, kb.Value, got[i].Value)
+				assert.Equal(t, kb.Notes, got[i].Notes)
+				assert.Equal(t, kb.Category, got[i].Category)
+				assert.Equal(t, kb.Reference, got[i].Reference)
+				assert.Equal(t, kb.Namespace, got[i].Namespace)
+				assert.Equal(t, kb.Tags, got[i].Tags)
+			}
+		})
+	}
+}
+
+func TestEncodeNoKBs(t *testing.T) {
+	var output bytes.Buffer
+
+	encoder, err := kbs.NewEncoder(kbs.FormatJSON, &output)
+	require.NoError(t, err)
+	require.NoError(t, encoder.Close())
+
+	assert.Equal(t, "[]\n", output.String())
+}
+
+func TestDecodeCSVMatchesColumnsByName(t *testing.T) {
+	content := `tags,Key,category,value,namespace,unknown
+docker compose,compose-up,command,docker compose up,devops,ignored
+`
+
+	got, err := kbs.Decode(kbs.FormatCSV, strings.NewReader(content))
+
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, kbs.NewKB{
+		Key:       "compose-up",
+		Value:     "docker compose up",
+		Category:  "command",
+		Namespace: "devops",
+		Tags:      []string{"docker", "compose"},
+	}, got[0])
+}
+
+func TestDecodeCSVWithoutKeyColumn(t *testing.T) {
+	_, err := kbs.Decode(kbs.FormatCSV, strings.NewReader("value,category\nx,y\n"))
+
+	assert.Error(t, err)
+}
+
+func TestDecodeJSONLSkipsBlankLines(t *testing.T) {
+	content := `{"key":"first","value":"one"}
+
+{"key":"second","value":"two"}
+`
+
+	got, err := kbs.Decode(kbs.FormatJSONL, strings.NewReader(content))
+
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "second", got[1].Key)
+}
+
+func TestDecodeInvalidYAML(t *testing.T) {
+	_, err := kbs.Decode(kbs.FormatYAML, strings.NewReader("Key: [unclosed"))
+
+	assert.Error(t, err)
+}
+
+func TestDetectFormat(t *testing.T) {
+	assert.Equal(t, kbs.FormatYAML, kbs.DetectFormat("kbs.yaml"))
+	assert.Equal(t, kbs.FormatYAML, kbs.DetectFormat("kbs.yml"))
+	assert.Equal(t, kbs.FormatJSON, kbs.DetectFormat("/tmp/kbs.JSON"))
+	assert.Equal(t, kbs.FormatJSONL, kbs.DetectFormat("kbs.jsonl"))
+	assert.Equal(t, kbs.FormatJSONL, kbs.DetectFormat("kbs.ndjson"))
+	assert.Equal(t, kbs.FormatCSV, kbs.DetectFormat("kbs.csv"))
+	// unknown extensions use the default format
+	assert.Equal(t, kbs.DefaultFormat, kbs.DetectFormat("kbs.txt"))
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := kbs.ParseFormat(" JSONL ")
+	require.NoError(t, err)
+	assert.Equal(t, kbs.FormatJSONL, format)
+
+	_, err = kbs.ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestDecodeSeqReadsKBsOneByOne(t *testing.T) {
+	// the array is broken after the first kb, which is read before the error is found.
+	content := `[{"key":"first","value":"one"},{"key": broken`
+
+	records, err := kbs.DecodeSeq(kbs.FormatJSON, strings.NewReader(content))
+	require.NoError(t, err)
+
+	var keys []string
+	var decodeErr error
+	for newKB, err := range records {
+		if err != nil {
+			decodeErr = err
+			break
+		}
+		keys = append(keys, newKB.Key)
+	}
+
+	assert.Equal(t, []string{"first"}, keys)
+	assert.Error(t, decodeErr)
+}
@@ -0,0 +1,180 @@
+package kbs
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// base scores of every kind of match, a better kind of match always scores more.
+const (
+	exactMatchScore       = 1000
+	prefixMatchScore      = 900
+	substringMatchScore   = 800
+	subsequenceMatchScore = 600
+	typoMatchScore        = 400
+)
+
+// typoRatio one typo is tolerated for every typoRatio characters of the query,
+// so shorter queries must not have typos.
+const typoRatio = 4
+
+// FuzzyScore indicates if the key looks like the query and how much, the higher
+// the better. In order, a key matches if it is the query, starts with it,
+// contains it, contains its characters in order, e.g. dkrprn for docker-prune,
+// or starts with something that differs from it in a few typos, e.g.
+// dcoker-prnue for docker-prune.
+func FuzzyScore(query, key string) (int, bool) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	key = strings.ToLower(key)
+
+	if query == "" {
+		return 0, false
+	}
+
+	extra := len([]rune(key)) - len([]rune(query))
+
+	if key == query {
+		return exactMatchScore, true
+	}
+
+	if strings.HasPrefix(key, query) {
+		return prefixMatchScore - extra, true
+	}
+
+	if index := strings.Index(key, query); index >= 0 {
+		return substringMatchScore - len([]rune(key[:index])) - extra, true
+	}
+
+	if gaps, ok := subsequenceGaps([]rune(query), []rune(key)); ok {
+		return subsequenceMatchScore - gaps - extra, true
+	}
+
+	distance := prefixEditDistance([]rune(query), []rune(key))
+	if distance <= len([]rune(query))/typoRatio {
+		return typoMatchScore - distance*10 - abs(extra), true
+	}
+
+	return 0, false
+}
+
+// FuzzyMatch returns up to limit keys that look like the query, the best first.
+// Keys with the same score are sorted alphabetically.
+func FuzzyMatch(query string, keys []string, limit int) []string {
+	type match struct {
+		key   string
+		score int
+	}
+
+	matches := make([]match, 0)
+
+	for _, key := range keys {
+		score, ok := FuzzyScore(query, key)
+		if ok {
+			matches = append(matches, match{key: key, score: score})
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b match) int {
+		return cmp.Or(cmp.Compare(b.score, a.score), strings.Compare(a.key, b.key))
+	})
+
+	result := make([]string, 0, min(limit, len(matches)))
+
+	for _, m := range matches[:min(limit, len(matches))] {
+		result = append(result, m.key)
+	}
+
+	return result
+}
+
+// Keys gets the keys of every kb, sorted alphabetically.
+func (s *Service) Keys(ctx context.Context) ([]string, error) {
+	keys, err := s.storage.GetKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get kb keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// SuggestKeys returns up to limit keys of kbs that look like the given key, the
+// most similar first, e.g. to suggest the key that was meant after a typo.
+func (s *Service) SuggestKeys(ctx context.Context, key string, limit int) ([]string, error) {
+	keys, err := s.Keys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to suggest keys: %w", err)
+	}
+
+	return FuzzyMatch(key, keys, limit), nil
+}
+
+// subsequenceGaps indicates if the query characters are in the key in the same
+// order, and how many key characters are between them.
+func subsequenceGaps(query, key []rune) (int, bool) {
+	gaps, start, next := 0, -1, 0
+
+	for i, r := range key {
+		if next == len(query) {
+			break
+		}
+
+		if r != query[next] {
+			continue
+		}
+
+		if start >= 0 {
+			gaps += i - start - 1
+		}
+
+		start = i
+		next++
+	}
+
+	return gaps, next == len(query)
+}
+
+// prefixEditDistance is the least number of insertions, deletions,
+// substitutions and swaps of adjacent characters needed to turn a into any
+// prefix of b.
+func prefixEditDistance(a, b []rune) int {
+	// rows of the distances of the prefixes of a to every prefix of b.
+	previous2 := make([]int, len(b)+1)
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+		}
+
+		previous2, previous, current = previous, current, previous2
+	}
+
+	return slices.Min(previous)
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
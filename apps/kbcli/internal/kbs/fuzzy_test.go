@@ -0,0 +1,63 @@
+package kbs_test
+
+import (
+	"testing"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	cases := map[string]struct {
+		query   string
+		key     string
+		matches bool
+	}{
+		"exact":                {query: "docker-prune", key: "docker-prune", matches: true},
+		"case insensitive":     {query: "Docker-Prune", key: "docker-prune", matches: true},
+		"prefix":               {query: "docker", key: "docker-prune", matches: true},
+		"substring":            {query: "prune", key: "docker-prune", matches: true},
+		"subsequence":          {query: "dkrprn", key: "docker-prune", matches: true},
+		"swapped letters":      {query: "dcoker-prnue", key: "docker-prune", matches: true},
+		"missing letter":       {query: "dockr-prune", key: "docker-prune", matches: true},
+		"wrong letter":         {query: "halvinf", key: "halving", matches: true},
+		"too many typos":       {query: "dxcker", key: "podman", matches: false},
+		"empty query":          {query: " ", key: "docker-prune", matches: false},
+		"out of order letters": {query: "enurp", key: "docker-prune", matches: false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, ok := kbs.FuzzyScore(tc.query, tc.key)
+
+			assert.Equal(t, tc.matches, ok)
+		})
+	}
+}
+
+func TestFuzzyScoreRanksBetterMatchesFirst(t *testing.T) {
+	exact, _ := kbs.FuzzyScore("docker", "docker")
+	prefix, _ := kbs.FuzzyScore("docker", "docker-prune")
+	substring, _ := kbs.FuzzyScore("docker", "prune-docker")
+	subsequence, _ := kbs.FuzzyScore("docker", "do-c-ker")
+	typo, _ := kbs.FuzzyScore("docker", "dockr")
+
+	assert.Greater(t, exact, prefix)
+	assert.Greater(t, prefix, substring)
+	assert.Greater(t, substring, subsequence)
+	assert.Greater(t, subsequence, typo)
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	keys := []string{"podman-prune", "docker-prune-all", "docker-prune", "halving", "docker-ps"}
+
+	got := kbs.FuzzyMatch("dcoker-prune", keys, 2)
+
+	assert.Equal(t, []string{"docker-prune", "docker-prune-all"}, got)
+}
+
+func TestFuzzyMatchNoMatches(t *testing.T) {
+	got := kbs.FuzzyMatch("kubernetes", []string{"docker-prune", "halving"}, 5)
+
+	assert.Empty(t, got)
+}
@@ -0,0 +1,337 @@
+package kbs
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// importBatchSize is the number of kbs written in each transaction of a non atomic import.
+const importBatchSize = 500
+
+// importPlan is what will be done with an imported kb.
+type importPlan struct {
+	kb KB
+	// existingKB the kb with the same id or key, nil if there is none.
+	existingKB *KB
+	// matchedByID indicates that existingKB has the id of the imported kb.
+	matchedByID bool
+}
+
+// importer keeps the state of an import while its records are read.
+type importer struct {
+	options ImportOptions
+	result  *ImportResult
+	// categories the imported kbs are validated with.
+	categories CategoryRegistry
+	// keys that a dry run would have created, so later kbs in the same import find them.
+	createdKeys map[string]KB
+	// index of the next record.
+	index int
+	// total number of records, zero if it is not known.
+	total int
+}
+
+// Import adds the given kbs. The options define what to do with kbs that already
+// exist and whether the changes are only reported instead of written.
+func (s *Service) Import(ctx context.Context, newKBs []NewKB, options ImportOptions) (*ImportResult, error) {
+	categories, err := s.Categories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to import kbs: %w", err)
+	}
+
+	if !options.DryRun {
+		err = validateKBs(newKBs, categories)
+		if err != nil {
+			return nil, fmt.Errorf("one kb is not valid: %w", err)
+		}
+	}
+
+	records := func(yield func(NewKB, error) bool) {
+		for _, newKB := range newKBs {
+			if !yield(newKB, nil) {
+				return
+			}
+		}
+	}
+
+	return s.importSeq(ctx, records, len(newKBs), categories, options)
+}
+
+// ImportSeq adds the kbs while they are read, so big files are not held in memory.
+// Kbs are written in batches, each one in its own transaction. If options.Atomic
+// is set, all kbs are written in one transaction that is rolled back on the first
+// failure. An error reading the records stops the import.
+// If ctx is canceled, the import stops after the current kb and returns what was
+// imported so far with the context error. An atomic import is rolled back.
+func (s *Service) ImportSeq(ctx context.Context, records iter.Seq2[NewKB, error], options ImportOptions) (*ImportResult, error) {
+	categories, err := s.Categories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to import kbs: %w", err)
+	}
+
+	return s.importSeq(ctx, records, 0, categories, options)
+}
+
+func (s *Service) importSeq(ctx context.Context, records iter.Seq2[NewKB, error], total int, categories CategoryRegistry, options ImportOptions) (*ImportResult, error) {
+	imp := importer{
+		options:    options,
+		categories: categories,
+		result: &ImportResult{
+			NewIDs:     make(map[string]string),
+			UpdatedIDs: make(map[string]string),
+			SkippedIDs: make(map[string]string),
+			FailedKeys: make(map[string]string),
+			DryRun:     options.DryRun,
+		},
+		createdKeys: make(map[string]KB),
+		total:       total,
+	}
+
+	var err error
+
+	switch {
+	case options.DryRun:
+		err = imp.importRecords(ctx, s.storage, records)
+	case options.Atomic:
+		err = s.storage.WithTransaction(context.WithoutCancel(ctx), func(storage Storage) error {
+			err := imp.importRecords(ctx, storage, records)
+			if err != nil {
+				return err
+			}
+
+			return ctx.Err()
+		})
+		if err != nil {
+			return nil, fmt.Errorf("import was rolled back: %w", err)
+		}
+	default:
+		err = imp.importBatches(ctx, s.storage, records)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.Err() != nil {
+		return imp.result, fmt.Errorf("import was canceled: %w", ctx.Err())
+	}
+
+	return imp.result, nil
+}
+
+// importRecords imports all records with the given storage, until ctx is canceled.
+func (i *importer) importRecords(ctx context.Context, storage Storage, records iter.Seq2[NewKB, error]) error {
+	for newKB, err := range records {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("unable to read record %d: %w", i.index, err)
+		}
+
+		err = i.add(ctx, storage, newKB)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importBatches imports the records in batches of importBatchSize, each batch in its
+// own transaction. Batches already written are kept if a later one fails. If ctx is
+// canceled, the kbs of the current batch that were already imported are kept too.
+func (i *importer) importBatches(ctx context.Context, storage Storage, records iter.Seq2[NewKB, error]) error {
+	next, stop := iter.Pull2(records)
+	defer stop()
+
+	for done := false; !done; {
+		err := storage.WithTransaction(context.WithoutCancel(ctx), func(txStorage Storage) error {
+			for range importBatchSize {
+				if ctx.Err() != nil {
+					done = true
+					return nil
+				}
+
+				newKB, err, ok := next()
+				if !ok {
+					done = true
+					return nil
+				}
+
+				if err != nil {
+					return fmt.Errorf("unable to read record %d: %w", i.index, err)
+				}
+
+				err = i.add(ctx, txStorage, newKB)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// add imports the given kb. Failures are reported in the result, unless the import
+// is atomic, then they are returned.
+func (i *importer) add(ctx context.Context, storage Storage, newKB NewKB) error {
+	index := i.index
+	i.index++
+
+	name := importedKBName(index, newKB)
+
+	// a kb that started to be written is finished even if the import is canceled.
+	err := i.importKB(context.WithoutCancel(ctx), storage, index, newKB)
+
+	i.options.Progress.report(ProgressStageImport, i.index, i.total)
+
+	if err == nil {
+		return nil
+	}
+
+	if i.options.Atomic && !i.options.DryRun {
+		return fmt.Errorf("unable to import %s: %w", name, err)
+	}
+
+	i.result.FailedKeys[name] = err.Error()
+
+	return nil
+}
+
+// importKB creates, updates or skips the kb according to the import mode.
+func (i *importer) importKB(ctx context.Context, storage Storage, index int, newKB NewKB) error {
+	err := newKB.validate(i.categories)
+	if err != nil {
+		return fmt.Errorf("record %d is not valid: %w", index, err)
+	}
+
+	plan, err := i.planImport(ctx, storage, newKB)
+	if err != nil {
+		return err
+	}
+
+	name := importedKBName(index, newKB)
+	kb := plan.kb
+
+	switch {
+	case plan.existingKB == nil:
+		if i.options.DryRun {
+			i.createdKeys[kb.Key] = kb
+		} else {
+			_, err := storage.Create(ctx, kb)
+			if err != nil {
+				return err
+			}
+		}
+
+		i.result.NewIDs[name] = kb.ID
+	case i.options.Mode == ImportModeSkipExisting:
+		i.result.SkippedIDs[name] = plan.existingKB.ID
+	case i.options.Mode == ImportModeUpsert || plan.matchedByID:
+		kb.ID = plan.existingKB.ID
+
+		if !i.options.DryRun {
+			err := storage.Update(ctx, &kb)
+			if err != nil {
+				return err
+			}
+		}
+
+		i.result.UpdatedIDs[name] = kb.ID
+	default:
+		return fmt.Errorf("a kb with the same key already exists: %s", plan.existingKB.ID)
+	}
+
+	return nil
+}
+
+// planImport looks for the kb with the same id, or with the same key. Kbs in the
+// trash keep their id and key, so a kb that matches one of them is not imported.
+func (i *importer) planImport(ctx context.Context, storage Storage, newKB NewKB) (importPlan, error) {
+	plan := importPlan{
+		kb: newKB.toKB(),
+	}
+
+	if !IsStringEmpty(newKB.ID) {
+		existingKB, err := storage.GetByID(ctx, newKB.ID)
+		if err != nil {
+			return plan, fmt.Errorf("unable to get kb %q: %w", newKB.ID, err)
+		}
+
+		if existingKB == nil {
+			trashedKB, err := storage.GetTrashedByID(ctx, newKB.ID)
+			if err != nil {
+				return plan, fmt.Errorf("unable to get kb %q: %w", newKB.ID, err)
+			}
+
+			if trashedKB != nil {
+				return plan, fmt.Errorf("kb %q is in the trash, restore or purge it", newKB.ID)
+			}
+		}
+
+		err = checkKeyIsNotInTrash(ctx, storage, plan.kb.Key, newKB.ID)
+		if err != nil {
+			return plan, err
+		}
+
+		if existingKB != nil {
+			plan.existingKB = existingKB
+			plan.matchedByID = true
+
+			return plan, nil
+		}
+	}
+
+	err := checkKeyIsNotInTrash(ctx, storage, plan.kb.Key, "")
+	if err != nil {
+		return plan, err
+	}
+
+	existingKB, err := storage.GetByKey(ctx, plan.kb.Key)
+	if err != nil {
+		return plan, fmt.Errorf("unable to get kb with key %q: %w", plan.kb.Key, err)
+	}
+
+	if existingKB == nil {
+		if createdKB, ok := i.createdKeys[plan.kb.Key]; ok {
+			existingKB = &createdKB
+		}
+	}
+
+	plan.existingKB = existingKB
+
+	return plan, nil
+}
+
+// ParseImportMode gets the import mode with the given name. It is create by default.
+func ParseImportMode(value string) (ImportMode, error) {
+	mode := ImportMode(strings.ToLower(strings.TrimSpace(value)))
+
+	switch mode {
+	case "":
+		return ImportModeCreate, nil
+	case ImportModeCreate, ImportModeUpsert, ImportModeSkipExisting:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid import mode %q, it must be %q, %q or %q", value, ImportModeCreate, ImportModeUpsert, ImportModeSkipExisting)
+	}
+}
+
+// importedKBName identifies an imported kb in the result, even if it has no key.
+func importedKBName(index int, newKB NewKB) string {
+	if IsStringEmpty(newKB.Key) {
+		return fmt.Sprintf("record %d", index)
+	}
+
+	return newKB.Key
+}
@@ -2,6 +2,8 @@ package kbs
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"iter"
@@ -10,6 +12,7 @@ import (
 	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	rand "math/rand/v2"
 
@@ -27,9 +30,32 @@ type KB struct {
 	Reference string   `json:"reference,omitempty" yaml:"Reference"`
 	Namespace string   `json:"namespace,omitempty" yaml:"Namespace"`
 	Tags      []string `json:"tags" yaml:"Tags"`
-}
+	// DeletedOn is set when the kb was moved to the trash.
+	DeletedOn *time.Time `json:"-" yaml:"-"`
+	// SyncState indicates if the kb has local changes that were not pushed to the server.
+	SyncState SyncState `json:"-" yaml:"-"`
+	// RemoteID is the id of this kb in the server.
+	RemoteID string `json:"-" yaml:"-"`
+	// LastSyncedOn is the last time the kb was pushed to or pulled from the server.
+	LastSyncedOn *time.Time `json:"-" yaml:"-"`
+	// SyncHash is the content hash of the kb the last time it was synced.
+	SyncHash string `json:"-" yaml:"-"`
+	// CreatedOn is when the kb was saved locally for the first time.
+	CreatedOn time.Time `json:"-" yaml:"-"`
+	// UpdatedOn is the last time the content of the kb changed, nil if it never changed.
+	UpdatedOn *time.Time `json:"-" yaml:"-"`
+	// LastAccessedOn is the last time the kb was opened, nil if it was never opened.
+	LastAccessedOn *time.Time `json:"-" yaml:"-"`
+	// AccessCount is the number of times the kb was opened.
+	AccessCount int `json:"-" yaml:"-"`
+}
+
+// SyncState defines the synchronization state of a local kb.
+type SyncState string
 
 type NewKB struct {
+	// ID is optional, imported kbs keep it so importing them again updates them.
+	ID        string   `json:"id,omitempty" yaml:"ID,omitempty"`
 	Key       string   `json:"key" yaml:"Key"`
 	Value     string   `json:"value" yaml:"Value"`
 	Notes     string   `json:"notes" yaml:"Notes"`
@@ -58,16 +84,66 @@ type GetAllResult struct {
 	Offset uint32 `json:"offset"`
 }
 
+// KBPage is a page of kbs read after the cursor of the previous page.
+type KBPage struct {
+	KBs []KB `json:"kbs"`
+	// Next is the cursor to read the following page, empty if this is the last one.
+	Next string `json:"next"`
+	// Total number of kbs that match the filter, it is only counted for the first page.
+	Total int `json:"total"`
+}
+
 type ImportResult struct {
 	// new kb keys and ids generated
 	NewIDs map[string]string `json:"ids"`
+	// kb keys and ids of existing kbs that were updated
+	UpdatedIDs map[string]string `json:"updated_ids"`
+	// kb keys and ids of existing kbs that were left untouched
+	SkippedIDs map[string]string `json:"skipped_ids"`
 	// failed kb keys with its respective error
 	FailedKeys map[string]string `json:"failed_keys"`
+	// DryRun indicates that nothing was written, the result is what would happen.
+	DryRun bool `json:"dry_run"`
+}
+
+// ImportOptions defines how kbs are imported.
+type ImportOptions struct {
+	// Mode defines what to do with kbs that already exist.
+	Mode ImportMode
+	// DryRun validates the kbs and reports what would be done without writing anything.
+	DryRun bool
+	// Atomic imports all kbs in one transaction that is rolled back on the first failure.
+	Atomic bool
+	// Progress receives the number of kbs imported.
+	Progress ProgressFunc
+}
+
+// ExportOptions defines which kbs are exported.
+type ExportOptions struct {
+	// Filter selects the kbs to export. Offset and Limit are the start and page size.
+	Filter KBQueryFilter
+	// Progress receives the number of kbs exported after every page.
+	Progress ProgressFunc
 }
 
+// ImportMode defines what to do with imported kbs that already exist.
+type ImportMode string
+
+// SortBy defines what the kbs found by a query are sorted by.
+type SortBy string
+
+// SortOrder defines if the kbs found by a query are sorted in ascending or descending order.
+type SortOrder string
+
 type SyncResult struct {
-	// new kb keys and ids generated
-	NewIDs map[string]string `json:"ids"`
+	// kb keys pushed to the server and their server ids
+	Pushed map[string]string `json:"pushed"`
+	// kb keys pulled from the server and their local ids
+	Pulled map[string]string `json:"pulled"`
+	// kb keys that were changed locally and on the server, with the reason
+	Conflicted map[string]string `json:"conflicted"`
+	// kb keys whose conflict was resolved and the side that was kept
+	Resolved map[string]ConflictResolution `json:"resolved"`
 	// failed kb keys with its respective error
 	FailedKeys map[string]string `json:"failed_keys"`
 }
@@ -78,13 +154,30 @@ type KBItem struct {
 	Category  string   `json:"category"`
 	Namespace string   `json:"namespace,omitempty"`
 	Tags      []string `json:"tags"`
+	// Snippet highlights the text that matched a full text search.
+	Snippet string `json:"snippet,omitempty"`
 }
 
 type KBQueryFilter struct {
+	// Query is a free text search over key, value, notes, reference and tags.
+	// It supports fts5 syntax: "phrases", AND/OR/NOT and prefix*.
+	Query     string `json:"query"`
 	Keyword   string `json:"keyword"`
 	Key       string `json:"key"`
 	Category  string `json:"category"`
 	Namespace string `json:"namespace"`
+	// IncludeSubNamespaces includes kbs in the descendants of the namespace, e.g. team/backend/payments for team/backend.
+	IncludeSubNamespaces bool `json:"include_sub_namespaces"`
+	// AllTags are exact tags a kb must all have.
+	AllTags []string `json:"all_tags"`
+	// AnyTags are exact tags a kb must have at least one of.
+	AnyTags []string `json:"any_tags"`
+	// SortBy defines what the kbs found are sorted by. By default full text
+	// searches are sorted by relevance and the other ones by creation.
+	SortBy SortBy `json:"sort_by"`
+	// SortOrder reverses the default order of SortBy, e.g. the oldest kbs first
+	// when sorting by recent use. Empty for the default order.
+	SortOrder SortOrder `json:"sort_order"`
 	// determines the number of rows.
 	Limit uint32 `json:"limit"`
 	// skips the offset rows before beginning to return the rows.
@@ -125,6 +218,148 @@ const (
 	MediaTypeLabel = "Media Type"
 	TagsLabel      = "Tags"
 	KeywordLabel   = "Keyword"
+	QueryLabel     = "Text"
+	AllTagsLabel   = "All tags"
+	AnyTagsLabel   = "Any tags"
+)
+
+// SyncQueueEntry is a change waiting in the sync queue to be sent to the server.
+type SyncQueueEntry struct {
+	ID        int64
+	Operation SyncOperation
+	// RemoteID is the id of the server kb to delete.
+	RemoteID string
+	// KB is the kb content. Deletions only keep the key.
+	KB       NewKB
+	Attempts int
+	// LastError is the error of the last failed attempt.
+	LastError     string
+	NextAttemptOn time.Time
+	CreatedOn     time.Time
+}
+
+// SyncOperation defines the change a sync queue entry makes on the server.
+type SyncOperation string
+
+// TagCount is a tag and the number of kbs that have it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// NamespaceCount is a namespace and the number of kbs in it.
+type NamespaceCount struct {
+	Namespace string `json:"namespace"`
+	Count     int    `json:"count"`
+}
+
+// NamespaceNode is a namespace in the namespace hierarchy.
+type NamespaceNode struct {
+	// Name is the last part of the path, e.g. payments for team/backend/payments.
+	Name string
+	Path string
+	// Count number of kbs in the namespace itself.
+	Count int
+	// Total number of kbs in the namespace and its descendants.
+	Total    int
+	Children []*NamespaceNode
+}
+
+// Category declares the rules that the kbs of a category must follow.
+type Category struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// RequiredFields kb fields that cannot be empty, besides the ones every kb requires, e.g. reference.
+	RequiredFields []string `json:"required_fields,omitempty"`
+	// ValueFormat format the kb value must have, e.g. url for bookmarks.
+	ValueFormat ValueFormat `json:"value_format"`
+}
+
+// ValueFormat defines the format of the value of the kbs of a category.
+type ValueFormat string
+
+// CategoryRegistry contains the categories kbs can have, by name.
+type CategoryRegistry map[string]Category
+
+// Revision is the content a kb had before one of its updates.
+type Revision struct {
+	// Number is the position of the revision in the history of the kb, starting at 1.
+	Number int
+	// KB content of the kb before the update.
+	KB KB
+	// ChangedFields fields the update changed, e.g. value and notes.
+	ChangedFields []string
+	// CreatedOn is when the update was made.
+	CreatedOn time.Time
+}
+
+// SyncStatus describes what is pending to be pushed to the server.
+type SyncStatus struct {
+	// Queued kbs that could not be added and wait in the sync queue.
+	Queued []SyncQueueEntry
+	// New local kbs that have never been pushed.
+	New []KB
+	// Modified local kbs whose changes were not pushed.
+	Modified []KB
+}
+
+// SyncOptions defines how the synchronization must be done.
+type SyncOptions struct {
+	// Prefer is the side kept when a kb was changed locally and on the server.
+	// By default conflicts are only reported.
+	Prefer ConflictResolution
+	// Progress receives the number of kbs pushed and pulled.
+	Progress ProgressFunc
+}
+
+// ConflictResolution defines which side wins when a kb was changed locally and on the server.
+type ConflictResolution string
+
+// conflict resolutions
+const (
+	// KeepConflicts conflicts are reported and both kbs are left untouched.
+	KeepConflicts ConflictResolution = ""
+	// PreferLocal the local kb overwrites the server kb.
+	PreferLocal ConflictResolution = "local"
+	// PreferRemote the server kb overwrites the local kb.
+	PreferRemote ConflictResolution = "remote"
+)
+
+// import modes
+const (
+	// ImportModeCreate adds new kbs. A kb with the id of an existing kb updates
+	// it and a kb whose key already exists fails.
+	ImportModeCreate ImportMode = "create"
+	// ImportModeUpsert adds new kbs and updates the ones with the same id or key.
+	ImportModeUpsert ImportMode = "upsert"
+	// ImportModeSkipExisting adds new kbs and leaves untouched the ones with the same id or key.
+	ImportModeSkipExisting ImportMode = "skip-existing"
+)
+
+// sync operations
+const (
+	SyncOperationCreate SyncOperation = "create"
+	SyncOperationDelete SyncOperation = "delete"
+)
+
+// sync queue retries
+const (
+	minSyncRetryDelay     = time.Minute
+	maxSyncRetryDelay     = 24 * time.Hour
+	maxSyncRetryDoublings = 12
+)
+
+// DefaultNamespace namespace used when a kb does not have one.
+const DefaultNamespace = "default"
+
+// sync states
+const (
+	// SyncStateNew the kb was created locally and it has never been pushed.
+	SyncStateNew SyncState = "new"
+	// SyncStateModified the kb was pushed or pulled but it was changed locally after that.
+	SyncStateModified SyncState = "modified"
+	// SyncStateSynced the kb has the same content locally and on the server.
+	SyncStateSynced SyncState = "synced"
 )
 
 // (removed unused mediaFolder constant)
@@ -136,6 +371,48 @@ const (
 	BookmarkCategory = "bookmark"
 )
 
+// value formats
+const (
+	// ValueFormatText the value can be any text.
+	ValueFormatText ValueFormat = "text"
+	// ValueFormatURL the value must be an absolute url, e.g. https://go.dev.
+	ValueFormatURL ValueFormat = "url"
+	// ValueFormatMedia the value is the url or path of a media file that can be saved locally.
+	ValueFormatMedia ValueFormat = "media"
+)
+
+// sort fields, every one has a default order and kbs that are equal keep the
+// order they were created in.
+const (
+	// SortByRelevance the kbs that match a full text search best first.
+	SortByRelevance SortBy = "relevance"
+	// SortByCreated the kbs created first first.
+	SortByCreated SortBy = "created"
+	// SortByAlphabetical kbs sorted by key.
+	SortByAlphabetical SortBy = "alphabetical"
+	// SortByRecent the kbs opened or changed most recently first.
+	SortByRecent SortBy = "recent"
+	// SortByMostUsed the kbs opened the most times first.
+	SortByMostUsed SortBy = "most-used"
+)
+
+// sort orders
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+// kb fields
+const (
+	KeyField       = "key"
+	ValueField     = "value"
+	NotesField     = "notes"
+	CategoryField  = "category"
+	ReferenceField = "reference"
+	NamespaceField = "namespace"
+	TagsField      = "tags"
+)
+
 // magic values
 const (
 	maxAllowedGetAllKBLimit = 100
@@ -158,8 +435,12 @@ var (
 	errEmptyKBTags      = errors.New("kb tags is empty")
 	errKBTagValues      = errors.New("kb tag must contain only alphabetic characters")
 	errMinGetAllKBLimit = errors.New("minimum number of records to retrieve is no valid")
+	errEmptyKBID        = errors.New("the given id is not valid, because it is empty")
 )
 
+// ErrKBNotFound is returned when the requested kb does not exist.
+var ErrKBNotFound = errors.New("kb not found")
+
 var IsLetter = regexp.MustCompile(`^[a-zA-Z0-9-]+$`).MatchString
 
 func (s *SearchResult) Keys() iter.Seq[string] {
@@ -192,6 +473,27 @@ func (s *SearchResult) Tags() iter.Seq[string] {
 	}
 }
 
+// HasSnippets indicates if any item was found by full text search.
+func (s *SearchResult) HasSnippets() bool {
+	for snippet := range s.Snippets() {
+		if snippet != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *SearchResult) Snippets() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for _, v := range s.Items {
+			if !yield(v.Snippet) {
+				return
+			}
+		}
+	}
+}
+
 func (s *SearchResult) Namespaces() iter.Seq[string] {
 	return func(yield func(string) bool) {
 		for _, v := range s.Items {
@@ -271,7 +573,7 @@ func (e *ClientError) Unwrap() error {
 	return e.inner
 }
 
-func (n NewKB) validate() error {
+func (n NewKB) validate(categories CategoryRegistry) error {
 	var err error
 
 	if n.Key == "" {
@@ -284,6 +586,8 @@ func (n NewKB) validate() error {
 
 	if n.Category == "" {
 		err = errors.Join(err, errEmptyKBCategory)
+	} else {
+		err = errors.Join(err, categories.validate(strings.ToLower(n.Category), n.Value, n.Notes, n.Reference))
 	}
 
 	if n.Namespace == "" {
@@ -303,7 +607,7 @@ func (n NewKB) validate() error {
 	return err
 }
 
-func (k KB) validate() error {
+func (k KB) validate(categories CategoryRegistry) error {
 	var err error
 
 	if k.Key == "" {
@@ -316,6 +620,8 @@ func (k KB) validate() error {
 
 	if k.Category == "" {
 		err = errors.Join(err, errEmptyKBCategory)
+	} else {
+		err = errors.Join(err, categories.validate(strings.ToLower(k.Category), k.Value, k.Notes, k.Reference))
 	}
 
 	if k.Namespace == "" {
@@ -335,15 +641,6 @@ func (k KB) validate() error {
 	return err
 }
 
-func (n NewKB) toYAML() ([]byte, error) {
-	kbData, err := yaml.Marshal(n)
-	if err != nil {
-		return nil, fmt.Errorf("unable to marshal new kb to yaml: %w", err)
-	}
-
-	return kbData, nil
-}
-
 func (k KB) ToYAML() ([]byte, error) {
 	kbData, err := yaml.Marshal(k)
 	if err != nil {
@@ -354,7 +651,7 @@ func (k KB) ToYAML() ([]byte, error) {
 }
 
 func (k KBQueryFilter) validate() error {
-	if IsStringEmpty(k.Key) && IsStringEmpty(k.Keyword) {
+	if IsStringEmpty(k.Key) && IsStringEmpty(k.Keyword) && IsStringEmpty(k.Query) && len(k.AllTags) == 0 && len(k.AnyTags) == 0 {
 		return fmt.Errorf("invalid data to search kbs")
 	}
 
@@ -366,18 +663,95 @@ func (n NewKB) toKB() KB {
 	slices.Sort(tags)
 	tags = slices.Compact(tags)
 
+	id := n.ID
+	if IsStringEmpty(id) {
+		id = uuid.New().String()
+	}
+
 	return KB{
-		ID:        uuid.New().String(),
+		ID:        id,
 		Key:       strings.ToLower(n.Key),
 		Value:     n.Value,
 		Notes:     n.Notes,
 		Category:  strings.ToLower(n.Category),
-		Namespace: strings.ToLower(n.Namespace),
+		Namespace: CleanNamespace(n.Namespace),
 		Reference: n.Reference,
 		Tags:      tags,
 	}
 }
 
+// toNewKB returns the data required to create this kb on the server.
+func (k KB) toNewKB() NewKB {
+	return NewKB{
+		Key:       k.Key,
+		Value:     k.Value,
+		Notes:     k.Notes,
+		Category:  k.Category,
+		Reference: k.Reference,
+		Namespace: k.Namespace,
+		Tags:      slices.Clone(k.Tags),
+	}
+}
+
+// Edited returns the kb with the content of the given one, e.g. read from the
+// file the user edited. The id and the local state of the kb are kept.
+func (k KB) Edited(content NewKB) KB {
+	edited := content.toKB()
+
+	k.Key = edited.Key
+	k.Value = edited.Value
+	k.Notes = edited.Notes
+	k.Category = edited.Category
+	k.Namespace = edited.Namespace
+	k.Reference = edited.Reference
+	k.Tags = edited.Tags
+
+	return k
+}
+
+// sameContent indicates if both kbs have the same data, ignoring ids and local state.
+func (k KB) sameContent(other KB) bool {
+	return k.Key == other.Key &&
+		k.Value == other.Value &&
+		k.Notes == other.Notes &&
+		k.Category == other.Category &&
+		k.Reference == other.Reference &&
+		k.Namespace == other.Namespace &&
+		slices.Equal(normalizeTags(k.Tags), normalizeTags(other.Tags))
+}
+
+// ContentHash returns a hash of the kb data kept by the server, so two kbs
+// with the same content have the same hash.
+func (k KB) ContentHash() string {
+	hash := sha256.New()
+
+	for _, field := range []string{k.Key, k.Value, k.Notes, k.Category, k.Reference, strings.Join(normalizeTags(k.Tags), " ")} {
+		hash.Write([]byte(field))
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// ParseConflictResolution gets the conflict resolution with the given name.
+func ParseConflictResolution(value string) (ConflictResolution, error) {
+	resolution := ConflictResolution(strings.ToLower(strings.TrimSpace(value)))
+
+	switch resolution {
+	case KeepConflicts, PreferLocal, PreferRemote:
+		return resolution, nil
+	default:
+		return KeepConflicts, fmt.Errorf("invalid conflict resolution %q, it must be %q or %q", value, PreferLocal, PreferRemote)
+	}
+}
+
+func normalizeTags(tags []string) []string {
+	result := slices.Clone(tags)
+	slices.Sort(result)
+
+	return slices.Compact(result)
+}
+
 func (k KB) String() string {
 	return fmt.Sprintf(`%s: %s
 %s: %s
@@ -421,7 +795,7 @@ Tags: %+v
 }
 
 func (i *ImportResult) Ok() bool {
-	return len(i.FailedKeys) == 0 && len(i.NewIDs) > 0
+	return len(i.FailedKeys) == 0 && (len(i.NewIDs) > 0 || len(i.UpdatedIDs) > 0 || len(i.SkippedIDs) > 0)
 }
 
 func (i *ImportResult) anyError() bool {
@@ -429,7 +803,7 @@ func (i *ImportResult) anyError() bool {
 }
 
 func (s *SyncResult) Ok() bool {
-	return len(s.FailedKeys) == 0 && len(s.NewIDs) > 0
+	return len(s.FailedKeys) == 0 && len(s.Conflicted) == 0 && (len(s.Pushed) > 0 || len(s.Pulled) > 0)
 }
 
 func (s *SyncResult) anyError() bool {
@@ -437,7 +811,48 @@ func (s *SyncResult) anyError() bool {
 }
 
 func (s *SyncResult) Empty() bool {
-	return len(s.FailedKeys) == 0 && len(s.NewIDs) == 0
+	return len(s.FailedKeys) == 0 && len(s.Pushed) == 0 && len(s.Pulled) == 0 && len(s.Conflicted) == 0
+}
+
+// Due indicates if the entry must be sent in a sync that happens at the given time.
+func (e SyncQueueEntry) Due(now time.Time) bool {
+	return !e.NextAttemptOn.After(now)
+}
+
+func newCreateEntry(newKB NewKB) SyncQueueEntry {
+	return SyncQueueEntry{
+		Operation: SyncOperationCreate,
+		KB:        newKB,
+	}
+}
+
+// Empty indicates that nothing is pending to be pushed.
+func (s *SyncStatus) Empty() bool {
+	return len(s.Queued) == 0 && len(s.New) == 0 && len(s.Modified) == 0
+}
+
+// syncRetryDelay returns how long to wait before trying again an entry that
+// already failed the given number of attempts. It doubles with every attempt.
+func syncRetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+
+	if attempts > maxSyncRetryDoublings {
+		return maxSyncRetryDelay
+	}
+
+	return min(minSyncRetryDelay<<(attempts-1), maxSyncRetryDelay)
+}
+
+func newSyncResult() *SyncResult {
+	return &SyncResult{
+		Pushed:     make(map[string]string),
+		Pulled:     make(map[string]string),
+		Conflicted: make(map[string]string),
+		Resolved:   make(map[string]ConflictResolution),
+		FailedKeys: make(map[string]string),
+	}
 }
 
 func IsStringEmpty(value string) bool {
@@ -450,13 +865,9 @@ func loadSyncFile(syncFile string) ([]NewKB, error) {
 		return nil, fmt.Errorf("unable to read file for synchronization (%q): %w", syncFile, err)
 	}
 
-	dec := yaml.NewDecoder(bytes.NewReader(file))
-
-	var kbItems []NewKB
-	var kbItem NewKB
-	for dec.Decode(&kbItem) == nil {
-		kbItems = append(kbItems, kbItem)
-		kbItem = NewKB{}
+	kbItems, err := Decode(FormatYAML, bytes.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load file for synchronization (%q): %w", syncFile, err)
 	}
 
 	return kbItems, nil
@@ -483,8 +894,11 @@ func (k KBItem) ToArray() []string {
 func (k KBQueryFilter) nothingToLookFor() bool {
 	return IsStringEmpty(k.Key) &&
 		IsStringEmpty(k.Keyword) &&
+		IsStringEmpty(k.Query) &&
 		IsStringEmpty(k.Category) &&
-		IsStringEmpty(k.Namespace)
+		IsStringEmpty(k.Namespace) &&
+		len(k.AllTags) == 0 &&
+		len(k.AnyTags) == 0
 }
 
 func (k KBQueryFilter) valid() error {
@@ -4,6 +4,7 @@ package kbs
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -38,6 +39,9 @@ func TestKBQueryFilterValidate(t *testing.T) {
 	validKey := KBQueryFilter{Key: "mykey"}
 	assert.NoError(t, validKey.validate())
 
+	validQuery := KBQueryFilter{Query: "docker AND compose"}
+	assert.NoError(t, validQuery.validate())
+
 	invalid := KBQueryFilter{}
 	assert.Error(t, invalid.validate())
 }
@@ -57,3 +61,12 @@ func TestSyncResultAnyError(t *testing.T) {
 	r2 := &SyncResult{FailedKeys: map[string]string{}}
 	assert.False(t, r2.anyError())
 }
+
+func TestSyncRetryDelay(t *testing.T) {
+	assert.Equal(t, time.Duration(0), syncRetryDelay(0))
+	assert.Equal(t, time.Minute, syncRetryDelay(1))
+	assert.Equal(t, 2*time.Minute, syncRetryDelay(2))
+	assert.Equal(t, 8*time.Minute, syncRetryDelay(4))
+	assert.Equal(t, 24*time.Hour, syncRetryDelay(12))
+	assert.Equal(t, 24*time.Hour, syncRetryDelay(100))
+}
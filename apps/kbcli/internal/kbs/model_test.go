@@ -199,7 +199,7 @@ func TestImportResultNotOkWhenEmpty(t *testing.T) {
 
 func TestSyncResultOk(t *testing.T) {
 	result := &kbs.SyncResult{
-		NewIDs:     map[string]string{"key": "id"},
+		Pushed:     map[string]string{"key": "id"},
 		FailedKeys: map[string]string{},
 	}
 	assert.True(t, result.Ok())
@@ -207,15 +207,24 @@ func TestSyncResultOk(t *testing.T) {
 
 func TestSyncResultNotOk(t *testing.T) {
 	result := &kbs.SyncResult{
-		NewIDs:     map[string]string{},
+		Pushed:     map[string]string{},
 		FailedKeys: map[string]string{"key": "err"},
 	}
 	assert.False(t, result.Ok())
 }
 
+func TestSyncResultWithConflictsNotOk(t *testing.T) {
+	result := &kbs.SyncResult{
+		Pulled:     map[string]string{"key": "id"},
+		Conflicted: map[string]string{"other": "changed on both sides"},
+	}
+	assert.False(t, result.Ok())
+	assert.False(t, result.Empty())
+}
+
 func TestSyncResultEmpty(t *testing.T) {
 	result := &kbs.SyncResult{
-		NewIDs:     map[string]string{},
+		Pushed:     map[string]string{},
 		FailedKeys: map[string]string{},
 	}
 	assert.True(t, result.Empty())
@@ -223,7 +232,7 @@ func TestSyncResultEmpty(t *testing.T) {
 
 func TestSyncResultNotEmpty(t *testing.T) {
 	result := &kbs.SyncResult{
-		NewIDs:     map[string]string{"key": "id"},
+		Pushed:     map[string]string{"key": "id"},
 		FailedKeys: map[string]string{},
 	}
 	assert.False(t, result.Empty())
@@ -299,6 +308,42 @@ func TestKBString(t *testing.T) {
 	assert.Contains(t, s, "myvalue")
 }
 
+func TestKBEdited(t *testing.T) {
+	kb := kbs.KB{
+		ID:          "uuid",
+		Key:         "mykey",
+		Value:       "myvalue",
+		Category:    "mycat",
+		Namespace:   "myns",
+		Tags:        []string{"a"},
+		AccessCount: 3,
+	}
+	content := kbs.NewKB{
+		ID:        "other-uuid",
+		Key:       "MyNewKey",
+		Value:     "line 1\nline 2",
+		Notes:     "mynotes",
+		Category:  "MyCat",
+		Namespace: "/myns/sub/",
+		Reference: "me",
+		Tags:      []string{"b", "a", "b"},
+	}
+
+	got := kb.Edited(content)
+
+	assert.Equal(t, kbs.KB{
+		ID:          "uuid",
+		Key:         "mynewkey",
+		Value:       "line 1\nline 2",
+		Notes:       "mynotes",
+		Category:    "mycat",
+		Namespace:   "myns/sub",
+		Reference:   "me",
+		Tags:        []string{"a", "b"},
+		AccessCount: 3,
+	}, got)
+}
+
 func TestNewKBStringNoMediaType(t *testing.T) {
 	newKB := kbs.NewKB{
 		Key:       "mykey",
@@ -343,3 +388,126 @@ func TestGetAllKBsLimitOverMax(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid limit number")
 }
+
+func TestSearchResultHasSnippets(t *testing.T) {
+	result := &kbs.SearchResult{
+		Items: []kbs.KBItem{
+			{ID: "1", Key: "alpha"},
+			{ID: "2", Key: "beta", Snippet: "the [beta] version"},
+		},
+	}
+
+	assert.True(t, result.HasSnippets())
+	assert.Equal(t, []string{"", "the [beta] version"}, slices.Collect(result.Snippets()))
+
+	withoutSnippets := &kbs.SearchResult{
+		Items: []kbs.KBItem{{ID: "1", Key: "alpha"}},
+	}
+
+	assert.False(t, withoutSnippets.HasSnippets())
+}
+
+func TestKBContentHash(t *testing.T) {
+	kb := kbs.KB{ID: "1", Key: "kb", Value: "value", Category: "test", Namespace: "work", Tags: []string{"b", "a"}}
+
+	sameContent := kb
+	sameContent.ID = "2"
+	sameContent.Namespace = "other"
+	sameContent.Tags = []string{"a", "b", "a"}
+
+	otherContent := kb
+	otherContent.Value = "other value"
+
+	assert.Equal(t, kb.ContentHash(), sameContent.ContentHash())
+	assert.NotEqual(t, kb.ContentHash(), otherContent.ContentHash())
+}
+
+func TestParseConflictResolution(t *testing.T) {
+	tests := map[string]kbs.ConflictResolution{
+		"":        kbs.KeepConflicts,
+		"local":   kbs.PreferLocal,
+		"Remote ": kbs.PreferRemote,
+	}
+
+	for value, want := range tests {
+		got, err := kbs.ParseConflictResolution(value)
+
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	_, err := kbs.ParseConflictResolution("mine")
+	assert.Error(t, err)
+}
+
+func TestParseImportMode(t *testing.T) {
+	mode, err := kbs.ParseImportMode("")
+	require.NoError(t, err)
+	assert.Equal(t, kbs.ImportModeCreate, mode)
+
+	mode, err = kbs.ParseImportMode("Skip-Existing")
+	require.NoError(t, err)
+	assert.Equal(t, kbs.ImportModeSkipExisting, mode)
+
+	_, err = kbs.ParseImportMode("replace")
+	assert.Error(t, err)
+}
+
+func TestParseSortBy(t *testing.T) {
+	sortBy, err := kbs.ParseSortBy("")
+	require.NoError(t, err)
+	assert.Empty(t, sortBy)
+
+	sortBy, err = kbs.ParseSortBy(" Most-Used ")
+	require.NoError(t, err)
+	assert.Equal(t, kbs.SortByMostUsed, sortBy)
+
+	_, err = kbs.ParseSortBy("newest")
+	assert.Error(t, err)
+}
+
+func TestParseSortOrder(t *testing.T) {
+	order, err := kbs.ParseSortOrder("DESC")
+	require.NoError(t, err)
+	assert.Equal(t, kbs.SortDescending, order)
+
+	_, err = kbs.ParseSortOrder("down")
+	assert.Error(t, err)
+}
+
+func TestKBQueryFilterSorting(t *testing.T) {
+	cases := map[string]struct {
+		filter    kbs.KBQueryFilter
+		wantBy    kbs.SortBy
+		wantOrder kbs.SortOrder
+	}{
+		"default":                {filter: kbs.KBQueryFilter{}, wantBy: kbs.SortByCreated, wantOrder: kbs.SortAscending},
+		"full text default":      {filter: kbs.KBQueryFilter{Query: "docker"}, wantBy: kbs.SortByRelevance, wantOrder: kbs.SortAscending},
+		"relevance without text": {filter: kbs.KBQueryFilter{SortBy: kbs.SortByRelevance}, wantBy: kbs.SortByCreated, wantOrder: kbs.SortAscending},
+		"recent":                 {filter: kbs.KBQueryFilter{SortBy: kbs.SortByRecent}, wantBy: kbs.SortByRecent, wantOrder: kbs.SortDescending},
+		"reversed":               {filter: kbs.KBQueryFilter{SortBy: kbs.SortByRecent, SortOrder: kbs.SortAscending}, wantBy: kbs.SortByRecent, wantOrder: kbs.SortAscending},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			sortBy, order := tc.filter.Sorting()
+
+			assert.Equal(t, tc.wantBy, sortBy)
+			assert.Equal(t, tc.wantOrder, order)
+		})
+	}
+}
+
+func TestCleanNamespace(t *testing.T) {
+	cases := map[string]string{
+		"team/backend":     "team/backend",
+		"/Team//Backend/":  "team/backend",
+		" team / backend ": "team/backend",
+		"default":          "default",
+		"/":                "",
+	}
+
+	for namespace, want := range cases {
+		assert.Equal(t, want, kbs.CleanNamespace(namespace), namespace)
+	}
+}
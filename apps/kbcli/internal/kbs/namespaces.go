@@ -0,0 +1,119 @@
+package kbs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// NamespaceSeparator separates the parts of a namespace path, e.g. team/backend/payments.
+const NamespaceSeparator = "/"
+
+var (
+	errEmptyNamespace     = errors.New("namespace is empty")
+	errSameNamespace      = errors.New("the new namespace is the same as the old one")
+	errMoveIntoDescendant = errors.New("a namespace cannot be moved into one of its descendants")
+)
+
+// CleanNamespace returns the namespace in lower case and without empty parts,
+// e.g. team/backend for /Team//backend/.
+func CleanNamespace(namespace string) string {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(namespace)), NamespaceSeparator)
+
+	parts = slices.DeleteFunc(parts, func(part string) bool {
+		return strings.TrimSpace(part) == ""
+	})
+
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+
+	return strings.Join(parts, NamespaceSeparator)
+}
+
+// isSubNamespace indicates if the namespace is the parent or any ancestor of the sub namespace.
+func isSubNamespace(namespace, subNamespace string) bool {
+	return strings.HasPrefix(subNamespace, namespace+NamespaceSeparator)
+}
+
+// NamespaceTree gets the hierarchy of namespaces of active kbs. Namespaces
+// that only have descendants are included with a count of zero.
+func (s *Service) NamespaceTree(ctx context.Context) ([]*NamespaceNode, error) {
+	counts, err := s.storage.GetNamespaceCounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get namespace tree: %w", err)
+	}
+
+	return buildNamespaceTree(counts), nil
+}
+
+// MoveNamespace moves the namespace and its descendants to the new path, e.g.
+// moving team/backend to platform/backend moves team/backend/payments to
+// platform/backend/payments. It returns how many kbs were changed.
+func (s *Service) MoveNamespace(ctx context.Context, namespace, newNamespace string) (int64, error) {
+	namespace = CleanNamespace(namespace)
+	newNamespace = CleanNamespace(newNamespace)
+
+	switch {
+	case namespace == "" || newNamespace == "":
+		return 0, errEmptyNamespace
+	case namespace == newNamespace:
+		return 0, errSameNamespace
+	case isSubNamespace(namespace, newNamespace):
+		return 0, errMoveIntoDescendant
+	}
+
+	changed, err := s.storage.MoveNamespace(ctx, namespace, newNamespace)
+	if err != nil {
+		return 0, fmt.Errorf("unable to move namespace: %w", err)
+	}
+
+	return changed, nil
+}
+
+// buildNamespaceTree arranges the namespaces by path, sorted by name on each level.
+func buildNamespaceTree(counts []NamespaceCount) []*NamespaceNode {
+	root := NamespaceNode{}
+	nodes := make(map[string]*NamespaceNode)
+
+	for _, namespaceCount := range counts {
+		parent := &root
+		path := ""
+
+		for part := range strings.SplitSeq(namespaceCount.Namespace, NamespaceSeparator) {
+			if path != "" {
+				path += NamespaceSeparator
+			}
+
+			path += part
+
+			node, ok := nodes[path]
+			if !ok {
+				node = &NamespaceNode{Name: part, Path: path}
+				nodes[path] = node
+				parent.Children = append(parent.Children, node)
+			}
+
+			node.Total += namespaceCount.Count
+			parent = node
+		}
+
+		parent.Count += namespaceCount.Count
+	}
+
+	sortNamespaceNodes(root.Children)
+
+	return root.Children
+}
+
+func sortNamespaceNodes(nodes []*NamespaceNode) {
+	slices.SortFunc(nodes, func(a, b *NamespaceNode) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for _, node := range nodes {
+		sortNamespaceNodes(node.Children)
+	}
+}
@@ -0,0 +1,58 @@
+package kbs
+
+import (
+	"fmt"
+)
+
+// progress stages
+const (
+	ProgressStageImport     = "import"
+	ProgressStageExport     = "export"
+	ProgressStagePushQueued = "push queued"
+	ProgressStagePush       = "push"
+	ProgressStagePull       = "pull"
+)
+
+// Progress is an update of a long running operation, like import, export or sync.
+type Progress struct {
+	// Stage is the step of the operation that is running.
+	Stage string
+	// Done number of kbs processed in the stage.
+	Done int
+	// Total number of kbs to process in the stage, zero if it is not known.
+	Total int
+}
+
+// ProgressFunc receives the progress of a long running operation. It is called
+// by the goroutine running the operation, so it must return quickly.
+type ProgressFunc func(progress Progress)
+
+// report calls the progress function, if there is one.
+func (p ProgressFunc) report(stage string, done, total int) {
+	if p == nil {
+		return
+	}
+
+	p(Progress{
+		Stage: stage,
+		Done:  done,
+		Total: total,
+	})
+}
+
+// Percent returns the processed fraction between 0 and 1, or 0 if the total is not known.
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+
+	return min(float64(p.Done)/float64(p.Total), 1)
+}
+
+func (p Progress) String() string {
+	if p.Total <= 0 {
+		return fmt.Sprintf("%s: %d kbs", p.Stage, p.Done)
+	}
+
+	return fmt.Sprintf("%s: %d/%d kbs", p.Stage, p.Done, p.Total)
+}
@@ -0,0 +1,22 @@
+package kbs_test
+
+import (
+	"testing"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	progress := kbs.Progress{Stage: kbs.ProgressStagePull, Done: 5, Total: 20}
+
+	assert.Equal(t, "pull: 5/20 kbs", progress.String())
+	assert.InDelta(t, 0.25, progress.Percent(), 0.0001)
+}
+
+func TestProgressWithUnknownTotal(t *testing.T) {
+	progress := kbs.Progress{Stage: kbs.ProgressStageImport, Done: 7}
+
+	assert.Equal(t, "import: 7 kbs", progress.String())
+	assert.Zero(t, progress.Percent())
+}
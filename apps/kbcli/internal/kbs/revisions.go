@@ -0,0 +1,178 @@
+package kbs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// diffContextLines number of unchanged lines shown around each change of a diff.
+const diffContextLines = 3
+
+var (
+	errInvalidRevision  = errors.New("revision number must be greater than zero")
+	errRevisionNotFound = errors.New("revision not found")
+)
+
+// ChangedFields returns the names of the fields whose content is different in the other kb.
+func (k KB) ChangedFields(other KB) []string {
+	fields := []struct {
+		name    string
+		changed bool
+	}{
+		{name: KeyField, changed: k.Key != other.Key},
+		{name: ValueField, changed: k.Value != other.Value},
+		{name: NotesField, changed: k.Notes != other.Notes},
+		{name: CategoryField, changed: k.Category != other.Category},
+		{name: ReferenceField, changed: k.Reference != other.Reference},
+		{name: NamespaceField, changed: k.Namespace != other.Namespace},
+		{name: TagsField, changed: !slices.Equal(normalizeTags(k.Tags), normalizeTags(other.Tags))},
+	}
+
+	var result []string
+
+	for _, field := range fields {
+		if field.changed {
+			result = append(result, field.name)
+		}
+	}
+
+	return result
+}
+
+// History gets the revisions of the kb, the oldest first.
+func (s *Service) History(ctx context.Context, id string) ([]Revision, error) {
+	if IsStringEmpty(id) {
+		return nil, errEmptyKBID
+	}
+
+	revisions, err := s.storage.GetRevisions(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get kb history: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// Diff returns the unified diff of the value and notes changed by the update
+// that created the revision, from the revision to the next one, or to the
+// current kb if it is the last revision.
+func (s *Service) Diff(ctx context.Context, id string, number int) (string, error) {
+	revisions, index, current, err := s.revisionsOf(ctx, id, number)
+	if err != nil {
+		return "", fmt.Errorf("unable to get kb diff: %w", err)
+	}
+
+	from := revisions[index].KB
+	toName := "current"
+	to := *current
+
+	if index+1 < len(revisions) {
+		toName = fmt.Sprintf("revision %d", revisions[index+1].Number)
+		to = revisions[index+1].KB
+	}
+
+	fromName := fmt.Sprintf("revision %d", number)
+
+	var diff strings.Builder
+
+	for _, field := range []struct {
+		name     string
+		from, to string
+	}{
+		{name: ValueField, from: from.Value, to: to.Value},
+		{name: NotesField, from: from.Notes, to: to.Notes},
+	} {
+		fieldDiff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        diffLines(field.from),
+			B:        diffLines(field.to),
+			FromFile: field.name + " (" + fromName + ")",
+			ToFile:   field.name + " (" + toName + ")",
+			Context:  diffContextLines,
+		})
+		if err != nil {
+			return "", fmt.Errorf("unable to get %s diff: %w", field.name, err)
+		}
+
+		diff.WriteString(fieldDiff)
+	}
+
+	return diff.String(), nil
+}
+
+// Revert restores the content the kb had in the given revision. The revert is
+// an update, so the content it replaces is kept as a new revision.
+func (s *Service) Revert(ctx context.Context, id string, number int) (*KB, error) {
+	revisions, index, current, err := s.revisionsOf(ctx, id, number)
+	if err != nil {
+		return nil, fmt.Errorf("unable to revert kb: %w", err)
+	}
+
+	revision := revisions[index].KB
+	kb := *current
+	kb.Key = revision.Key
+	kb.Value = revision.Value
+	kb.Notes = revision.Notes
+	kb.Category = revision.Category
+	kb.Reference = revision.Reference
+	kb.Namespace = revision.Namespace
+	kb.Tags = slices.Clone(revision.Tags)
+
+	err = s.Update(ctx, kb)
+	if err != nil {
+		return nil, fmt.Errorf("unable to revert kb: %w", err)
+	}
+
+	return &kb, nil
+}
+
+// diffLines splits the text in lines that end with a line break, as the diff expects them.
+func diffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+
+	lines[len(lines)-1] += "\n"
+
+	return lines
+}
+
+// revisionsOf gets the revisions and the current content of the kb, with the
+// index of the revision with the given number.
+func (s *Service) revisionsOf(ctx context.Context, id string, number int) ([]Revision, int, *KB, error) {
+	if number < 1 {
+		return nil, 0, nil, errInvalidRevision
+	}
+
+	current, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	if current == nil {
+		return nil, 0, nil, ErrKBNotFound
+	}
+
+	revisions, err := s.History(ctx, id)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	index := slices.IndexFunc(revisions, func(revision Revision) bool {
+		return revision.Number == number
+	})
+	if index < 0 {
+		return nil, 0, nil, fmt.Errorf("revision %d: %w", number, errRevisionNotFound)
+	}
+
+	return revisions, index, current, nil
+}
@@ -4,34 +4,96 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
+	"log/slog"
 	"net/http"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/adapters/filesystems"
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/adapters/webs"
 )
 
+// exportPageSize number of kbs read per page while exporting, if the filter has no limit.
+const exportPageSize = 20
+
+// duplicateKeySuffix is added to the key of a kb to suggest the key of its copy.
+const duplicateKeySuffix = "-copy"
+
 type Storage interface {
 	Create(ctx context.Context, newKB KB) (string, error)
 	GetByID(ctx context.Context, id string) (*KB, error)
 	GetByKey(ctx context.Context, key string) (*KB, error)
+	// GetTrashedByID gets the kb with the given id if it is in the trash. Kbs in
+	// the trash keep their id and key until they are purged.
+	GetTrashedByID(ctx context.Context, id string) (*KB, error)
+	// GetTrashedByKey gets the kb with the given key if it is in the trash.
+	GetTrashedByKey(ctx context.Context, key string) (*KB, error)
+	// GetKeys gets the keys of every kb that is not in the trash, sorted alphabetically.
+	GetKeys(ctx context.Context) ([]string, error)
 	Update(ctx context.Context, kb *KB) error
 	Search(ctx context.Context, filter KBQueryFilter) (*SearchResult, error)
 	GetAll(ctx context.Context, filter KBQueryFilter) (*GetAllResult, error)
+	// GetAllAfter gets a page of kbs sorted as the filter says after the given
+	// cursor, or from the first kb if it is empty. Offset is ignored.
+	GetAllAfter(ctx context.Context, filter KBQueryFilter, cursor string) (*KBPage, error)
 	CountByCategory(ctx context.Context, category string) (int64, error)
+	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, id string) error
+	EmptyTrash(ctx context.Context) (int64, error)
+	GetTrash(ctx context.Context, filter KBQueryFilter) (*GetAllResult, error)
+	GetByRemoteID(ctx context.Context, remoteID string) (*KB, error)
+	GetBySyncState(ctx context.Context, state SyncState) ([]KB, error)
+	MarkAsSynced(ctx context.Context, id, remoteID, hash string) error
+	// MarkAsDeletedOnServer unlinks the kb from the given server kb once it was
+	// deleted there, so it is created again if the kb is restored.
+	MarkAsDeletedOnServer(ctx context.Context, remoteID string) error
+	// MarkAsAccessed counts that the kb with the given id was opened now.
+	MarkAsAccessed(ctx context.Context, id string) error
+	GetTagCounts(ctx context.Context) ([]TagCount, error)
+	// ReplaceTags replaces the old tags with the new one in every kb that is not
+	// in the trash, in one transaction, and returns how many kbs were changed.
+	ReplaceTags(ctx context.Context, oldTags []string, newTag string) (int64, error)
+	GetNamespaceCounts(ctx context.Context) ([]NamespaceCount, error)
+	// MoveNamespace replaces the namespace with the new one in every kb in it or in
+	// its descendants that is not in the trash, and returns how many kbs were changed.
+	MoveNamespace(ctx context.Context, namespace, newNamespace string) (int64, error)
+	// GetCategories gets the registered categories sorted by name.
+	GetCategories(ctx context.Context) ([]Category, error)
+	// SaveCategory registers the category or replaces the one with the same name.
+	SaveCategory(ctx context.Context, category Category) error
+	// RegisterCategory registers the category without rules, unless it is already registered.
+	RegisterCategory(ctx context.Context, name string) error
+	// GetRevisions gets the revisions recorded by the updates of the kb, the oldest first.
+	GetRevisions(ctx context.Context, id string) ([]Revision, error)
+	// WithTransaction runs fn with a storage whose changes are committed together
+	// if fn returns no error, or rolled back otherwise.
+	WithTransaction(ctx context.Context, fn func(storage Storage) error) error
+}
+
+// SyncQueue keeps the changes that must be sent to the server once it is reachable.
+type SyncQueue interface {
+	Enqueue(ctx context.Context, entries ...SyncQueueEntry) error
+	GetSyncQueue(ctx context.Context) ([]SyncQueueEntry, error)
+	Dequeue(ctx context.Context, id int64) error
+	PostponeEntry(ctx context.Context, id int64, lastError string, nextAttemptOn time.Time) error
 }
 
 type KBServiceClient interface {
 	Create(ctx context.Context, newKB NewKB) (string, error)
 	Update(ctx context.Context, kb *KB) error
-	Search(ctx context.Context, filter KBQueryFilter) (*SearchResult, error)
+	Delete(ctx context.Context, id string) error
+	// List gets a page of all server kbs, only the limit and offset of the filter are used.
+	List(ctx context.Context, filter KBQueryFilter) (*SearchResult, error)
 	Get(ctx context.Context, id string) (*KB, error)
 }
 
 type ServiceSetup struct {
 	KBStorage       Storage
 	KBClient        KBServiceClient
+	SyncQueue       SyncQueue
 	Name            string
 	FileForSyncPath string
 	DirForMediaPath string
@@ -40,6 +102,7 @@ type ServiceSetup struct {
 type Service struct {
 	kbClient        KBServiceClient
 	storage         Storage
+	syncQueue       SyncQueue
 	fileForSyncPath string
 	dirForMediaPath string
 }
@@ -48,6 +111,7 @@ func NewService(settings ServiceSetup) *Service {
 	newService := Service{
 		kbClient:        settings.KBClient,
 		storage:         settings.KBStorage,
+		syncQueue:       settings.SyncQueue,
 		fileForSyncPath: settings.FileForSyncPath,
 		dirForMediaPath: settings.DirForMediaPath,
 	}
@@ -60,13 +124,18 @@ var (
 )
 
 func (s *Service) Add(ctx context.Context, newKB NewKB) (*KB, error) {
-	err := newKB.validate()
+	err := s.Validate(ctx, newKB)
 	if err != nil {
-		return nil, NewDataError(fmt.Sprintf("the given values are not valid: %s", err))
+		return nil, err
 	}
 
 	kb := newKB.toKB()
 
+	err = checkKeyIsNotInTrash(ctx, s.storage, kb.Key, kb.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	_, err = s.storage.Create(ctx, kb)
 	if err != nil && errors.As(err, &ClientError{}) {
 		return nil, NewDataError(fmt.Sprintf("unable to add kb due to given data: %s", err))
@@ -79,12 +148,38 @@ func (s *Service) Add(ctx context.Context, newKB NewKB) (*KB, error) {
 	return &kb, nil
 }
 
+// Validate checks the new kb has the values required to add it, e.g. to let
+// the user fix them before asking to save it. Invalid values are a DataError.
+func (s *Service) Validate(ctx context.Context, newKB NewKB) error {
+	categories, err := s.Categories(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to add kb: %w", err)
+	}
+
+	err = newKB.validate(categories)
+	if err != nil {
+		return NewDataError(fmt.Sprintf("the given values are not valid: %s", err))
+	}
+
+	return nil
+}
+
 func (s *Service) Update(ctx context.Context, kb KB) error {
-	err := kb.validate()
+	categories, err := s.Categories(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update kb: %w", err)
+	}
+
+	err = kb.validate(categories)
 	if err != nil {
 		return NewDataError(fmt.Sprintf("the given values are not valid: %s", err))
 	}
 
+	err = checkKeyIsNotInTrash(ctx, s.storage, kb.Key, kb.ID)
+	if err != nil {
+		return err
+	}
+
 	err = s.storage.Update(ctx, &kb)
 	if err != nil && errors.As(err, &ClientError{}) {
 		return NewDataError(fmt.Sprintf("unable to update kb due to given data: %s", err))
@@ -97,109 +192,242 @@ func (s *Service) Update(ctx context.Context, kb KB) error {
 	return nil
 }
 
-func (s *Service) Import(ctx context.Context, newKBs []NewKB) (*ImportResult, error) {
-	err := validateKBs(newKBs)
+// Delete moves the kb with the given id to the trash, so it is hidden from
+// searches but it can be restored later. If the kb exists on the server, it is
+// deleted there in the next sync.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if IsStringEmpty(id) {
+		return errEmptyKBID
+	}
+
+	err := s.storage.Delete(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete kb: %w", err)
+	}
+
+	return nil
+}
+
+// Duplicate adds a new kb with the content of the kb with the given id and the
+// given key.
+func (s *Service) Duplicate(ctx context.Context, id, key string) (*KB, error) {
+	kb, err := s.GetByID(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("one kb is not valid: %w", err)
+		return nil, fmt.Errorf("unable to duplicate kb: %w", err)
 	}
 
-	result := ImportResult{
-		NewIDs:     make(map[string]string),
-		FailedKeys: make(map[string]string),
+	if kb == nil {
+		return nil, ErrKBNotFound
 	}
 
-	for _, newKB := range newKBs {
-		kb := newKB.toKB()
-		_, err := s.storage.Create(ctx, kb)
+	newKB := kb.toNewKB()
+	newKB.Key = key
+
+	return s.Add(ctx, newKB)
+}
+
+// DuplicateKey suggests a key for a copy of the kb with the given key, the key
+// followed by -copy, or by -copy-N if that one is taken.
+func (s *Service) DuplicateKey(ctx context.Context, key string) (string, error) {
+	candidate := key + duplicateKeySuffix
+
+	for i := 2; ; i++ {
+		kb, err := s.storage.GetByKey(ctx, candidate)
 		if err != nil {
-			result.FailedKeys[newKB.Key] = err.Error()
-			continue
+			return "", fmt.Errorf("unable to suggest a key for the copy: %w", err)
 		}
 
-		result.NewIDs[newKB.Key] = kb.ID
-	}
+		if kb == nil {
+			kb, err = s.storage.GetTrashedByKey(ctx, candidate)
+			if err != nil {
+				return "", fmt.Errorf("unable to suggest a key for the copy: %w", err)
+			}
+		}
 
-	return &result, nil
+		if kb == nil {
+			return candidate, nil
+		}
+
+		candidate = fmt.Sprintf("%s%s-%d", key, duplicateKeySuffix, i)
+	}
 }
 
-func (s *Service) GetAllKBs(ctx context.Context, filter KBQueryFilter) (*GetAllResult, error) {
-	err := filter.valid()
+// checkKeyIsNotInTrash returns a DataError if the key belongs to a kb in the
+// trash other than the kb with the given id, because the key is still taken.
+func checkKeyIsNotInTrash(ctx context.Context, storage Storage, key, id string) error {
+	trashedKB, err := storage.GetTrashedByKey(ctx, key)
 	if err != nil {
-		return nil, fmt.Errorf("unable to get all kbs, invalid filter values: %w", err)
+		return fmt.Errorf("unable to check if key %q is in the trash: %w", key, err)
 	}
 
-	result, err := s.storage.GetAll(ctx, filter)
-	if err != nil {
-		return nil, fmt.Errorf("unable to export kbs: %w", err)
+	if trashedKB != nil && trashedKB.ID != id {
+		return NewDataError(fmt.Sprintf("key %q is in the trash, restore or purge it", key))
 	}
 
-	return result, nil
+	return nil
 }
 
-func (s *Service) SaveForSync(_ context.Context, newKB NewKB) error {
-	newKBYAML, err := newKB.toYAML()
-	if err != nil {
-		return fmt.Errorf("unable to save new kb for later sync: %w", err)
+// Restore takes the kb with the given id out of the trash. If it was already
+// deleted on the server, it is created there again in the next sync.
+func (s *Service) Restore(ctx context.Context, id string) error {
+	if IsStringEmpty(id) {
+		return errEmptyKBID
 	}
 
-	empty, err := filesystems.FileEmpty(s.fileForSyncPath)
+	err := s.storage.Restore(ctx, id)
 	if err != nil {
-		return fmt.Errorf("unable to check sync file: %w", err)
+		return fmt.Errorf("failed to restore kb: %w", err)
 	}
 
-	var content []byte
+	return nil
+}
 
-	if !empty {
-		content = append(content, []byte("---\n")...)
+// Purge removes permanently the kb with the given id from the trash.
+func (s *Service) Purge(ctx context.Context, id string) error {
+	if IsStringEmpty(id) {
+		return errEmptyKBID
 	}
 
-	content = append(content, newKBYAML...)
-
-	err = filesystems.SaveOrAppendFile(s.fileForSyncPath, content)
+	err := s.storage.Purge(ctx, id)
 	if err != nil {
-		return fmt.Errorf("unable to save new kb for later sync: %w", err)
+		return fmt.Errorf("failed to purge kb: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Service) Sync(ctx context.Context) (*SyncResult, error) {
-	newKBs, err := loadSyncFile(s.fileForSyncPath)
+// EmptyTrash removes permanently all kbs in the trash and returns how many were removed.
+func (s *Service) EmptyTrash(ctx context.Context) (int64, error) {
+	purged, err := s.storage.EmptyTrash(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("unable to process synchronization: %w", err)
+		return 0, fmt.Errorf("failed to empty trash: %w", err)
 	}
 
-	if len(newKBs) == 0 {
-		return nil, nil
+	return purged, nil
+}
+
+// GetTrash gets the kbs in the trash.
+func (s *Service) GetTrash(ctx context.Context, filter KBQueryFilter) (*GetAllResult, error) {
+	err := filter.valid()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get trash, invalid filter values: %w", err)
+	}
+
+	result, err := s.storage.GetTrash(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get trash: %w", err)
+	}
+
+	return result, nil
+}
+
+func (s *Service) GetAllKBs(ctx context.Context, filter KBQueryFilter) (*GetAllResult, error) {
+	err := filter.valid()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get all kbs, invalid filter values: %w", err)
 	}
 
-	err = validateKBs(newKBs)
+	result, err := s.storage.GetAll(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("one kb is not valid: %w", err)
+		return nil, fmt.Errorf("unable to export kbs: %w", err)
 	}
 
-	go func() {
-		if errTrunc := filesystems.TruncateFile(s.fileForSyncPath); errTrunc != nil {
-			fmt.Printf("failed to truncate sync file: %v\n", errTrunc)
+	return result, nil
+}
+
+// Export writes with the encoder all kbs that match the filter, a page at a time,
+// and returns how many were written. If ctx is canceled, it stops after the
+// current page and returns how many were written with the context error.
+func (s *Service) Export(ctx context.Context, encoder Encoder, options ExportOptions) (int, error) {
+	exported, total := 0, 0
+
+	for page, err := range s.KBPages(ctx, options.Filter) {
+		if err != nil {
+			return exported, fmt.Errorf("unable to export kbs: %w", err)
 		}
-	}()
 
-	result := SyncResult{
-		NewIDs:     make(map[string]string),
-		FailedKeys: make(map[string]string),
+		if exported == 0 {
+			total = page.Total
+		}
+
+		err = encoder.Encode(page.KBs...)
+		if err != nil {
+			return exported, fmt.Errorf("unable to export kbs: %w", err)
+		}
+
+		exported += len(page.KBs)
+		options.Progress.report(ProgressStageExport, exported, total)
+	}
+
+	return exported, nil
+}
+
+// AllKBs returns the kbs that match the filter one at a time, reading them a
+// page at a time. It stops after yielding the first error.
+func (s *Service) AllKBs(ctx context.Context, filter KBQueryFilter) iter.Seq2[KB, error] {
+	return func(yield func(KB, error) bool) {
+		for page, err := range s.KBPages(ctx, filter) {
+			if err != nil {
+				yield(KB{}, err)
+				return
+			}
+
+			for _, kb := range page.KBs {
+				if !yield(kb, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// KBPages returns the pages of kbs that match the filter, with Limit kbs each or
+// exportPageSize if it has no limit. Pages are read with a cursor in any sort
+// order, so reading a page does not depend on the number of pages before it.
+// If ctx is canceled, it stops before the next page and yields ctx error.
+func (s *Service) KBPages(ctx context.Context, filter KBQueryFilter) iter.Seq2[*KBPage, error] {
+	if filter.Limit == 0 {
+		filter.Limit = exportPageSize
 	}
 
-	for _, newKB := range newKBs {
-		id, err := s.kbClient.Create(ctx, newKB)
+	return func(yield func(*KBPage, error) bool) {
+		err := filter.valid()
 		if err != nil {
-			result.FailedKeys[newKB.Key] = err.Error()
-			continue
+			yield(nil, fmt.Errorf("unable to get all kbs, invalid filter values: %w", err))
+			return
 		}
 
-		result.NewIDs[newKB.Key] = id
+		cursor := ""
+
+		for {
+			if ctx.Err() != nil {
+				yield(nil, fmt.Errorf("reading kbs was canceled: %w", ctx.Err()))
+				return
+			}
+
+			page, err := s.storage.GetAllAfter(ctx, filter, cursor)
+			if err != nil {
+				yield(nil, fmt.Errorf("unable to get all kbs: %w", err))
+				return
+			}
+
+			if len(page.KBs) == 0 || !yield(page, nil) || page.Next == "" {
+				return
+			}
+
+			cursor = page.Next
+		}
 	}
+}
 
-	return &result, nil
+// SaveForSync queues the given kb to be created on the server in the next sync.
+func (s *Service) SaveForSync(ctx context.Context, newKB NewKB) error {
+	err := s.syncQueue.Enqueue(ctx, newCreateEntry(newKB))
+	if err != nil {
+		return fmt.Errorf("unable to save new kb for later sync: %w", err)
+	}
+
+	return nil
 }
 
 func (s *Service) SaveMedia(_ context.Context, newKB NewKB) error {
@@ -291,9 +519,27 @@ func (s *Service) GetByKey(ctx context.Context, key string) (*KB, error) {
 		return nil, fmt.Errorf("failed to get kb: %w", err)
 	}
 
+	if kb != nil {
+		s.markAsAccessed(ctx, kb)
+	}
+
 	return kb, nil
 }
 
+// markAsAccessed counts the kb was opened. Failing to count it does not prevent
+// the kb from being read, so the error is only logged.
+func (s *Service) markAsAccessed(ctx context.Context, kb *KB) {
+	err := s.storage.MarkAsAccessed(ctx, kb.ID)
+	if err != nil {
+		slog.Error("unable to mark kb as accessed", slog.String("id", kb.ID), slog.String("error", err.Error()))
+		return
+	}
+
+	now := time.Now().UTC()
+	kb.LastAccessedOn = &now
+	kb.AccessCount++
+}
+
 func (s *Service) GetRandomQuote(ctx context.Context) (*KB, error) {
 	// Get the number of quotes we have stored.
 	totalQuotes, err := s.storage.CountByCategory(ctx, QuoteCategory)
@@ -343,9 +589,9 @@ func (s *Service) Search(ctx context.Context, filter KBQueryFilter) (*SearchResu
 	return kbs, nil
 }
 
-func validateKBs(newKBs []NewKB) error {
+func validateKBs(newKBs []NewKB, categories CategoryRegistry) error {
 	for index, newKB := range newKBs {
-		err := newKB.validate()
+		err := newKB.validate(categories)
 		if err != nil {
 			return fmt.Errorf("record %d in file is not valid: %w", index, err)
 		}
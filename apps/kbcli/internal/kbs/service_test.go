@@ -1,11 +1,16 @@
 package kbs_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
 	"github.com/stretchr/testify/assert"
@@ -25,7 +30,7 @@ func TestAddKBInvalidValues(t *testing.T) {
 	expectedError := errors.New("the given values are not valid: kb value is empty\nkb category is empty\nkb namespace is empty")
 
 	ctx := context.TODO()
-	settings := kbs.ServiceSetup{}
+	settings := kbs.ServiceSetup{KBStorage: newStorageMock()}
 	kbService := kbs.NewService(settings)
 	// When
 	_, err := kbService.Add(ctx, newKB)
@@ -69,6 +74,26 @@ func TestAddKB(t *testing.T) {
 	assert.Equal(t, expectedKB, gotKB)
 }
 
+func TestAddKBWithKeyInTrash(t *testing.T) {
+	newKB := kbs.NewKB{
+		Key:       "halving",
+		Value:     "The number of bitcoins generated per block is decreased 50% every four years",
+		Category:  "bitcoin",
+		Namespace: "cryptos",
+		Tags:      []string{"bitcoin"},
+	}
+	ctx := context.TODO()
+	storageMock := newStorageMockWithTrash(kbs.KB{ID: "trashed-id", Key: "halving"})
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	gotKB, err := kbService.Add(ctx, newKB)
+
+	assert.Nil(t, gotKB)
+	assert.ErrorAs(t, err, &kbs.DataError{})
+	assert.ErrorContains(t, err, `key "halving" is in the trash, restore or purge it`)
+	storageMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
 func TestAddKBInvalidTagChars(t *testing.T) {
 	newKB := kbs.NewKB{
 		Key:       "halving",
@@ -80,7 +105,7 @@ func TestAddKBInvalidTagChars(t *testing.T) {
 	}
 
 	ctx := context.TODO()
-	svc := kbs.NewService(kbs.ServiceSetup{})
+	svc := kbs.NewService(kbs.ServiceSetup{KBStorage: newStorageMock()})
 
 	_, err := svc.Add(ctx, newKB)
 
@@ -88,6 +113,39 @@ func TestAddKBInvalidTagChars(t *testing.T) {
 	assert.Contains(t, err.Error(), "alphabetic")
 }
 
+func TestValidateKB(t *testing.T) {
+	newKB := kbs.NewKB{
+		Key:       "halving",
+		Value:     "The number of bitcoins generated per block is decreased 50% every four years",
+		Category:  "bitcoin",
+		Namespace: "cryptos",
+		Tags:      []string{"bitcoin", "halving"},
+	}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	err := kbService.Validate(ctx, newKB)
+
+	assert.NoError(t, err)
+	storageMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestValidateKBInvalidValues(t *testing.T) {
+	newKB := kbs.NewKB{
+		Key:  "halving",
+		Tags: []string{"bitcoin", "halving"},
+	}
+
+	ctx := context.TODO()
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: newStorageMock()})
+
+	err := kbService.Validate(ctx, newKB)
+
+	assert.ErrorAs(t, err, &kbs.DataError{})
+}
+
 // ---- Update ----
 
 func TestUpdateKBInvalidValues(t *testing.T) {
@@ -97,7 +155,7 @@ func TestUpdateKBInvalidValues(t *testing.T) {
 	}
 
 	ctx := context.TODO()
-	settings := kbs.ServiceSetup{}
+	settings := kbs.ServiceSetup{KBStorage: newStorageMock()}
 	kbService := kbs.NewService(settings)
 
 	err := kbService.Update(ctx, kb)
@@ -130,6 +188,82 @@ func TestUpdateKB(t *testing.T) {
 	storageMock.AssertExpectations(t)
 }
 
+// ---- Duplicate ----
+
+func TestDuplicateKB(t *testing.T) {
+	kb := &kbs.KB{
+		ID:        "some-uuid",
+		Key:       "halving",
+		Value:     "The number of bitcoins generated per block is decreased 50% every four years",
+		Notes:     "Bitcoins have a finite supply",
+		Category:  "bitcoin",
+		Namespace: "cryptos",
+		Tags:      []string{"bitcoin", "halving"},
+	}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetByID", ctx, "some-uuid").Return(kb, nil)
+	storageMock.On("Create", ctx, mock.AnythingOfType("kbs.KB")).Return("2", nil)
+
+	settings := kbs.ServiceSetup{KBStorage: storageMock}
+	kbService := kbs.NewService(settings)
+
+	got, err := kbService.Duplicate(ctx, "some-uuid", "halving-copy")
+
+	require.NoError(t, err)
+	assert.NotEqual(t, kb.ID, got.ID)
+	assert.Equal(t, "halving-copy", got.Key)
+	assert.Equal(t, kb.Value, got.Value)
+	assert.Equal(t, kb.Notes, got.Notes)
+	assert.Equal(t, kb.Category, got.Category)
+	assert.Equal(t, kb.Namespace, got.Namespace)
+	assert.Equal(t, kb.Tags, got.Tags)
+	storageMock.AssertExpectations(t)
+}
+
+func TestDuplicateKBNotFound(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetByID", ctx, "some-uuid").Return((*kbs.KB)(nil), nil)
+
+	settings := kbs.ServiceSetup{KBStorage: storageMock}
+	kbService := kbs.NewService(settings)
+
+	_, err := kbService.Duplicate(ctx, "some-uuid", "halving-copy")
+
+	assert.ErrorIs(t, err, kbs.ErrKBNotFound)
+	storageMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestDuplicateKey(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetByKey", ctx, "halving-copy").Return(&kbs.KB{Key: "halving-copy"}, nil)
+	storageMock.On("GetByKey", ctx, "halving-copy-2").Return(&kbs.KB{Key: "halving-copy-2"}, nil)
+	storageMock.On("GetByKey", ctx, "halving-copy-3").Return((*kbs.KB)(nil), nil)
+
+	settings := kbs.ServiceSetup{KBStorage: storageMock}
+	kbService := kbs.NewService(settings)
+
+	got, err := kbService.DuplicateKey(ctx, "halving")
+
+	require.NoError(t, err)
+	assert.Equal(t, "halving-copy-3", got)
+}
+
+func TestDuplicateKeySkipsKeysInTrash(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMockWithTrash(kbs.KB{ID: "trashed-id", Key: "halving-copy"})
+	storageMock.On("GetByKey", ctx, mock.AnythingOfType("string")).Return((*kbs.KB)(nil), nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	got, err := kbService.DuplicateKey(ctx, "halving")
+
+	require.NoError(t, err)
+	assert.Equal(t, "halving-copy-2", got)
+}
+
 // ---- Import ----
 
 func TestImportKBsInvalidValues(t *testing.T) {
@@ -138,10 +272,10 @@ func TestImportKBsInvalidValues(t *testing.T) {
 	}
 
 	ctx := context.TODO()
-	settings := kbs.ServiceSetup{}
+	settings := kbs.ServiceSetup{KBStorage: newStorageMock()}
 	kbService := kbs.NewService(settings)
 
-	result, err := kbService.Import(ctx, newKBs)
+	result, err := kbService.Import(ctx, newKBs, kbs.ImportOptions{})
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -161,12 +295,13 @@ func TestImportKBs(t *testing.T) {
 
 	ctx := context.TODO()
 	storageMock := newStorageMock()
-	storageMock.On("Create", ctx, mock.AnythingOfType("kbs.KB")).Return("1", nil)
+	storageMock.On("GetByKey", mock.Anything, "halving").Return((*kbs.KB)(nil), nil)
+	storageMock.On("Create", mock.Anything, mock.AnythingOfType("kbs.KB")).Return("1", nil)
 
 	settings := kbs.ServiceSetup{KBStorage: storageMock}
 	kbService := kbs.NewService(settings)
 
-	result, err := kbService.Import(ctx, newKBs)
+	result, err := kbService.Import(ctx, newKBs, kbs.ImportOptions{})
 
 	require.NoError(t, err)
 	assert.Len(t, result.NewIDs, 1)
@@ -174,205 +309,1010 @@ func TestImportKBs(t *testing.T) {
 	storageMock.AssertExpectations(t)
 }
 
-func TestImportKBsWithStorageError(t *testing.T) {
+func TestImportKBsWithIDs(t *testing.T) {
 	newKBs := []kbs.NewKB{
 		{
+			ID:        "existing-id",
 			Key:       "halving",
 			Value:     "The number of bitcoins generated per block is decreased 50% every four years",
-			Notes:     "Bitcoins have a finite supply",
 			Category:  "bitcoin",
 			Namespace: "cryptos",
 			Tags:      []string{"bitcoin", "halving"},
 		},
+		{
+			ID:        "new-id",
+			Key:       "mining",
+			Value:     "Mining secures the network",
+			Category:  "bitcoin",
+			Namespace: "cryptos",
+			Tags:      []string{"bitcoin"},
+		},
 	}
 
 	ctx := context.TODO()
 	storageMock := newStorageMock()
-	storageMock.On("Create", ctx, mock.AnythingOfType("kbs.KB")).Return("", errors.New("duplicate key"))
+	storageMock.On("GetByID", mock.Anything, "existing-id").Return(&kbs.KB{ID: "existing-id", Key: "halving"}, nil)
+	storageMock.On("GetByID", mock.Anything, "new-id").Return((*kbs.KB)(nil), nil)
+	storageMock.On("GetByKey", mock.Anything, "mining").Return((*kbs.KB)(nil), nil)
+	storageMock.On("Update", mock.Anything, mock.MatchedBy(func(kb *kbs.KB) bool { return kb.ID == "existing-id" })).Return(nil)
+	storageMock.On("Create", mock.Anything, mock.MatchedBy(func(kb kbs.KB) bool { return kb.ID == "new-id" })).Return("new-id", nil)
 
 	settings := kbs.ServiceSetup{KBStorage: storageMock}
 	kbService := kbs.NewService(settings)
 
-	result, err := kbService.Import(ctx, newKBs)
+	result, err := kbService.Import(ctx, newKBs, kbs.ImportOptions{})
 
 	require.NoError(t, err)
-	assert.Empty(t, result.NewIDs)
-	assert.Len(t, result.FailedKeys, 1)
+	assert.Equal(t, map[string]string{"halving": "existing-id"}, result.UpdatedIDs)
+	assert.Equal(t, map[string]string{"mining": "new-id"}, result.NewIDs)
+	assert.Empty(t, result.FailedKeys)
+	assert.True(t, result.Ok())
+	storageMock.AssertExpectations(t)
 }
 
-// ---- GetAllKBs ----
+func makeImportedKBs() []kbs.NewKB {
+	return []kbs.NewKB{
+		{Key: "halving", Value: "new halving value", Category: "bitcoin", Namespace: "cryptos", Tags: []string{"bitcoin"}},
+		{Key: "mining", Value: "Mining secures the network", Category: "bitcoin", Namespace: "cryptos", Tags: []string{"bitcoin"}},
+	}
+}
 
-func TestGetAllKBsInvalidFilter(t *testing.T) {
-	filter := kbs.KBQueryFilter{Limit: 0}
+func newImportStorageMock(ctx context.Context) *storageDummy {
+	storageMock := newStorageMock()
+	storageMock.On("GetByKey", mock.Anything, "halving").Return(&kbs.KB{ID: "existing-id", Key: "halving"}, nil)
+	storageMock.On("GetByKey", mock.Anything, "mining").Return((*kbs.KB)(nil), nil)
+
+	return storageMock
+}
 
+func TestImportKBsCreateModeFailsOnExistingKeys(t *testing.T) {
 	ctx := context.TODO()
-	settings := kbs.ServiceSetup{}
-	kbService := kbs.NewService(settings)
+	storageMock := newImportStorageMock(ctx)
+	storageMock.On("Create", mock.Anything, mock.AnythingOfType("kbs.KB")).Return("1", nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
 
-	result, err := kbService.GetAllKBs(ctx, filter)
+	result, err := kbService.Import(ctx, makeImportedKBs(), kbs.ImportOptions{Mode: kbs.ImportModeCreate})
 
-	assert.Error(t, err)
-	assert.Nil(t, result)
+	require.NoError(t, err)
+	assert.Len(t, result.NewIDs, 1)
+	assert.Contains(t, result.FailedKeys, "halving")
+	storageMock.AssertNumberOfCalls(t, "Create", 1)
 }
 
-func TestGetAllKBs(t *testing.T) {
-	filter := kbs.KBQueryFilter{Limit: 10, Offset: 0}
-	expectedResult := &kbs.GetAllResult{
-		KBs:   []kbs.KB{},
-		Total: 0,
-		Limit: 10,
+func TestImportKBsInTrashFail(t *testing.T) {
+	newKBs := []kbs.NewKB{
+		{ID: "trashed-id", Key: "halving", Value: "new halving value", Category: "bitcoin", Namespace: "cryptos", Tags: []string{"bitcoin"}},
+		{Key: "mining", Value: "Mining secures the network", Category: "bitcoin", Namespace: "cryptos", Tags: []string{"bitcoin"}},
 	}
-
 	ctx := context.TODO()
-	storageMock := newStorageMock()
-	storageMock.On("GetAll", ctx, filter).Return(expectedResult, nil)
+	storageMock := newStorageMockWithTrash(
+		kbs.KB{ID: "trashed-id", Key: "halving"},
+		kbs.KB{ID: "other-trashed-id", Key: "mining"},
+	)
+	storageMock.On("GetByID", mock.Anything, "trashed-id").Return((*kbs.KB)(nil), nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
 
-	settings := kbs.ServiceSetup{KBStorage: storageMock}
-	kbService := kbs.NewService(settings)
+	result, err := kbService.Import(ctx, newKBs, kbs.ImportOptions{Mode: kbs.ImportModeUpsert})
 
-	result, err := kbService.GetAllKBs(ctx, filter)
+	require.NoError(t, err)
+	assert.Empty(t, result.NewIDs)
+	assert.Empty(t, result.UpdatedIDs)
+	assert.Equal(t, map[string]string{
+		"halving": `kb "trashed-id" is in the trash, restore or purge it`,
+		"mining":  `key "mining" is in the trash, restore or purge it`,
+	}, result.FailedKeys)
+	storageMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	storageMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestImportKBsUpsertMode(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newImportStorageMock(ctx)
+	storageMock.On("Create", mock.Anything, mock.AnythingOfType("kbs.KB")).Return("1", nil)
+	storageMock.On("Update", mock.Anything, mock.MatchedBy(func(kb *kbs.KB) bool {
+		return kb.ID == "existing-id" && kb.Value == "new halving value"
+	})).Return(nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	result, err := kbService.Import(ctx, makeImportedKBs(), kbs.ImportOptions{Mode: kbs.ImportModeUpsert})
 
 	require.NoError(t, err)
-	assert.Equal(t, expectedResult, result)
+	assert.Len(t, result.NewIDs, 1)
+	assert.Equal(t, map[string]string{"halving": "existing-id"}, result.UpdatedIDs)
+	assert.Empty(t, result.FailedKeys)
 	storageMock.AssertExpectations(t)
 }
 
-// ---- Search ----
+func TestImportKBsSkipExistingMode(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newImportStorageMock(ctx)
+	storageMock.On("Create", mock.Anything, mock.AnythingOfType("kbs.KB")).Return("1", nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
 
-func TestSearchKBsNothingToLookFor(t *testing.T) {
-	filter := kbs.KBQueryFilter{}
+	result, err := kbService.Import(ctx, makeImportedKBs(), kbs.ImportOptions{Mode: kbs.ImportModeSkipExisting})
+
+	require.NoError(t, err)
+	assert.Len(t, result.NewIDs, 1)
+	assert.Equal(t, map[string]string{"halving": "existing-id"}, result.SkippedIDs)
+	assert.Empty(t, result.FailedKeys)
+	assert.True(t, result.Ok())
+	storageMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestImportKBsDryRun(t *testing.T) {
+	newKBs := append(makeImportedKBs(),
+		// invalid kbs are reported instead of stopping the import.
+		kbs.NewKB{Key: "invalid"},
+		// the second kb with the same key would update the first one.
+		kbs.NewKB{Key: "Mining", Value: "duplicated", Category: "bitcoin", Namespace: "cryptos", Tags: []string{"bitcoin"}},
+	)
 
 	ctx := context.TODO()
-	settings := kbs.ServiceSetup{}
-	kbService := kbs.NewService(settings)
+	storageMock := newImportStorageMock(ctx)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
 
-	result, err := kbService.Search(ctx, filter)
+	result, err := kbService.Import(ctx, newKBs, kbs.ImportOptions{Mode: kbs.ImportModeUpsert, DryRun: true})
 
-	assert.NoError(t, err)
-	assert.Nil(t, result)
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Len(t, result.NewIDs, 1)
+	assert.Contains(t, result.NewIDs, "mining")
+	assert.Equal(t, "existing-id", result.UpdatedIDs["halving"])
+	assert.Equal(t, result.NewIDs["mining"], result.UpdatedIDs["Mining"])
+	assert.Contains(t, result.FailedKeys, "invalid")
+	storageMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	storageMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
 }
 
-func TestSearchKBs(t *testing.T) {
-	filter := kbs.KBQueryFilter{Key: "halving", Limit: 5}
-	expectedResult := &kbs.SearchResult{
-		Items: []kbs.KBItem{},
-		Total: 0,
-		Limit: 5,
+func TestImportKBsWithStorageError(t *testing.T) {
+	newKBs := []kbs.NewKB{
+		{
+			Key:       "halving",
+			Value:     "The number of bitcoins generated per block is decreased 50% every four years",
+			Notes:     "Bitcoins have a finite supply",
+			Category:  "bitcoin",
+			Namespace: "cryptos",
+			Tags:      []string{"bitcoin", "halving"},
+		},
 	}
 
 	ctx := context.TODO()
 	storageMock := newStorageMock()
-	storageMock.On("Search", ctx, filter).Return(expectedResult, nil)
+	storageMock.On("GetByKey", mock.Anything, "halving").Return((*kbs.KB)(nil), nil)
+	storageMock.On("Create", mock.Anything, mock.AnythingOfType("kbs.KB")).Return("", errors.New("duplicate key"))
 
 	settings := kbs.ServiceSetup{KBStorage: storageMock}
 	kbService := kbs.NewService(settings)
 
-	result, err := kbService.Search(ctx, filter)
+	result, err := kbService.Import(ctx, newKBs, kbs.ImportOptions{})
 
 	require.NoError(t, err)
-	assert.Equal(t, expectedResult, result)
-	storageMock.AssertExpectations(t)
+	assert.Empty(t, result.NewIDs)
+	assert.Len(t, result.FailedKeys, 1)
 }
 
-// ---- GetByID ----
-
-func TestGetByIDEmptyID(t *testing.T) {
+func TestImportKBsAtomicRollsBackOnFailure(t *testing.T) {
 	ctx := context.TODO()
-	settings := kbs.ServiceSetup{}
-	kbService := kbs.NewService(settings)
+	storageMock := newImportStorageMock(ctx)
+	storageMock.On("Create", mock.Anything, mock.AnythingOfType("kbs.KB")).Return("1", nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
 
-	result, err := kbService.GetByID(ctx, "  ")
+	// halving already exists, so the whole import fails.
+	result, err := kbService.Import(ctx, makeImportedKBs(), kbs.ImportOptions{Mode: kbs.ImportModeCreate, Atomic: true})
 
-	assert.Error(t, err)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "halving")
 	assert.Nil(t, result)
+	storageMock.AssertNumberOfCalls(t, "WithTransaction", 1)
 }
 
-func TestGetByID(t *testing.T) {
-	id := "some-uuid"
-	expectedKB := &kbs.KB{
-		ID:        id,
-		Key:       "halving",
-		Value:     "The number of bitcoins generated per block is decreased 50% every four years",
-		Category:  "bitcoin",
-		Namespace: "cryptos",
-		Tags:      []string{"bitcoin", "halving"},
+func TestImportSeqWritesInBatches(t *testing.T) {
+	records := func(yield func(kbs.NewKB, error) bool) {
+		for i := range 501 {
+			newKB := kbs.NewKB{Key: fmt.Sprintf("key-%d", i), Value: "value", Category: "bitcoin", Namespace: "cryptos", Tags: []string{"bitcoin"}}
+			if !yield(newKB, nil) {
+				return
+			}
+		}
 	}
 
 	ctx := context.TODO()
 	storageMock := newStorageMock()
-	storageMock.On("GetByID", ctx, id).Return(expectedKB, nil)
+	storageMock.On("GetByKey", mock.Anything, mock.AnythingOfType("string")).Return((*kbs.KB)(nil), nil)
+	storageMock.On("Create", mock.Anything, mock.AnythingOfType("kbs.KB")).Return("1", nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
 
-	settings := kbs.ServiceSetup{KBStorage: storageMock}
-	kbService := kbs.NewService(settings)
-
-	result, err := kbService.GetByID(ctx, id)
+	result, err := kbService.ImportSeq(ctx, records, kbs.ImportOptions{})
 
 	require.NoError(t, err)
-	assert.Equal(t, expectedKB, result)
-	storageMock.AssertExpectations(t)
+	assert.Len(t, result.NewIDs, 501)
+	storageMock.AssertNumberOfCalls(t, "WithTransaction", 2)
 }
 
-// ---- GetByKey ----
+func TestImportSeqStopsOnReadError(t *testing.T) {
+	records, err := kbs.DecodeSeq(kbs.FormatJSONL, strings.NewReader(`{"key":"halving","value":"v","category":"bitcoin","namespace":"cryptos","tags":["bitcoin"]}
+{"key": broken`))
+	require.NoError(t, err)
 
-func TestGetByKeyEmptyKey(t *testing.T) {
 	ctx := context.TODO()
-	settings := kbs.ServiceSetup{}
-	kbService := kbs.NewService(settings)
+	storageMock := newStorageMock()
+	storageMock.On("GetByKey", mock.Anything, "halving").Return((*kbs.KB)(nil), nil)
+	storageMock.On("Create", mock.Anything, mock.AnythingOfType("kbs.KB")).Return("1", nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
 
-	result, err := kbService.GetByKey(ctx, "")
+	result, err := kbService.ImportSeq(ctx, records, kbs.ImportOptions{})
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
 }
 
-func TestGetByKey(t *testing.T) {
-	key := "halving"
-	expectedKB := &kbs.KB{
-		ID:        "some-uuid",
-		Key:       key,
-		Value:     "The number of bitcoins generated per block is decreased 50% every four years",
-		Category:  "bitcoin",
-		Namespace: "cryptos",
-		Tags:      []string{"bitcoin", "halving"},
+func TestImportReportsProgress(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newImportStorageMock(ctx)
+	storageMock.On("Create", mock.Anything, mock.AnythingOfType("kbs.KB")).Return("1", nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	var got []kbs.Progress
+	options := kbs.ImportOptions{
+		Mode:     kbs.ImportModeSkipExisting,
+		Progress: func(progress kbs.Progress) { got = append(got, progress) },
 	}
 
-	ctx := context.TODO()
+	_, err := kbService.Import(ctx, makeImportedKBs(), options)
+
+	require.NoError(t, err)
+	assert.Equal(t, []kbs.Progress{
+		{Stage: kbs.ProgressStageImport, Done: 1, Total: 2},
+		{Stage: kbs.ProgressStageImport, Done: 2, Total: 2},
+	}, got)
+}
+
+func TestImportCanceledKeepsImportedKBs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	storageMock := newStorageMock()
-	storageMock.On("GetByKey", ctx, key).Return(expectedKB, nil)
+	storageMock.On("GetByKey", mock.Anything, mock.AnythingOfType("string")).Return((*kbs.KB)(nil), nil)
+	storageMock.On("Create", mock.Anything, mock.AnythingOfType("kbs.KB")).Return("1", nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
 
-	settings := kbs.ServiceSetup{KBStorage: storageMock}
-	kbService := kbs.NewService(settings)
+	// the import is canceled after the first kb.
+	options := kbs.ImportOptions{
+		Progress: func(kbs.Progress) { cancel() },
+	}
 
-	result, err := kbService.GetByKey(ctx, key)
+	result, err := kbService.Import(ctx, makeImportedKBs(), options)
 
-	require.NoError(t, err)
-	assert.Equal(t, expectedKB, result)
-	storageMock.AssertExpectations(t)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, result)
+	assert.Len(t, result.NewIDs, 1)
+	storageMock.AssertNumberOfCalls(t, "Create", 1)
 }
 
-// ---- GetRandomQuote ----
+func TestImportAtomicCanceledIsRolledBack(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-func TestGetRandomQuoteNoQuotes(t *testing.T) {
-	ctx := context.TODO()
 	storageMock := newStorageMock()
-	storageMock.On("CountByCategory", ctx, kbs.QuoteCategory).Return(int64(0), nil)
+	storageMock.On("GetByKey", mock.Anything, mock.AnythingOfType("string")).Return((*kbs.KB)(nil), nil)
+	storageMock.On("Create", mock.Anything, mock.AnythingOfType("kbs.KB")).Return("1", nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
 
-	settings := kbs.ServiceSetup{KBStorage: storageMock}
+	options := kbs.ImportOptions{
+		Atomic:   true,
+		Progress: func(kbs.Progress) { cancel() },
+	}
+
+	result, err := kbService.Import(ctx, makeImportedKBs(), options)
+
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, result)
+}
+
+// ---- GetAllKBs ----
+
+func TestGetAllKBsInvalidFilter(t *testing.T) {
+	filter := kbs.KBQueryFilter{Limit: 0}
+
+	ctx := context.TODO()
+	settings := kbs.ServiceSetup{}
 	kbService := kbs.NewService(settings)
 
-	result, err := kbService.GetRandomQuote(ctx)
+	result, err := kbService.GetAllKBs(ctx, filter)
 
-	assert.NoError(t, err)
+	assert.Error(t, err)
 	assert.Nil(t, result)
-	storageMock.AssertExpectations(t)
 }
 
-func TestGetRandomQuoteOneQuote(t *testing.T) {
-	expectedKB := kbs.KB{
-		ID:        "some-uuid",
-		Key:       "my-quote",
-		Value:     "A quote value",
-		Category:  kbs.QuoteCategory,
+func TestGetAllKBs(t *testing.T) {
+	filter := kbs.KBQueryFilter{Limit: 10, Offset: 0}
+	expectedResult := &kbs.GetAllResult{
+		KBs:   []kbs.KB{},
+		Total: 0,
+		Limit: 10,
+	}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetAll", ctx, filter).Return(expectedResult, nil)
+
+	settings := kbs.ServiceSetup{KBStorage: storageMock}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.GetAllKBs(ctx, filter)
+
+	require.NoError(t, err)
+	assert.Equal(t, expectedResult, result)
+	storageMock.AssertExpectations(t)
+}
+
+// ---- Export ----
+
+func TestExportReportsProgressPerPage(t *testing.T) {
+	firstPage := kbs.KBQueryFilter{Limit: 1}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetAllAfter", ctx, firstPage, "").Return(&kbs.KBPage{KBs: []kbs.KB{{Key: "first"}}, Next: "1", Total: 2}, nil)
+	storageMock.On("GetAllAfter", ctx, firstPage, "1").Return(&kbs.KBPage{KBs: []kbs.KB{{Key: "second"}}}, nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	var output bytes.Buffer
+	encoder, err := kbs.NewEncoder(kbs.FormatJSONL, &output)
+	require.NoError(t, err)
+
+	var got []kbs.Progress
+	options := kbs.ExportOptions{
+		Filter:   firstPage,
+		Progress: func(progress kbs.Progress) { got = append(got, progress) },
+	}
+
+	exported, err := kbService.Export(ctx, encoder, options)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, exported)
+	assert.Equal(t, 2, strings.Count(output.String(), "\n"))
+	assert.Equal(t, []kbs.Progress{
+		{Stage: kbs.ProgressStageExport, Done: 1, Total: 2},
+		{Stage: kbs.ProgressStageExport, Done: 2, Total: 2},
+	}, got)
+	storageMock.AssertExpectations(t)
+}
+
+func TestExportCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storageMock := newStorageMock()
+	storageMock.On("GetAllAfter", ctx, kbs.KBQueryFilter{Limit: 1}, "").Return(&kbs.KBPage{KBs: []kbs.KB{{Key: "first"}}, Next: "1", Total: 2}, nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	encoder, err := kbs.NewEncoder(kbs.FormatJSONL, io.Discard)
+	require.NoError(t, err)
+
+	options := kbs.ExportOptions{
+		Filter:   kbs.KBQueryFilter{Limit: 1},
+		Progress: func(kbs.Progress) { cancel() },
+	}
+
+	exported, err := kbService.Export(ctx, encoder, options)
+
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, exported)
+	storageMock.AssertNumberOfCalls(t, "GetAllAfter", 1)
+}
+
+// ---- AllKBs ----
+
+func TestAllKBsReadsPagesWithCursor(t *testing.T) {
+	filter := kbs.KBQueryFilter{Category: "bitcoin", Limit: 2}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetAllAfter", ctx, filter, "").Return(&kbs.KBPage{KBs: []kbs.KB{{Key: "a"}, {Key: "b"}}, Next: "7", Total: 3}, nil)
+	storageMock.On("GetAllAfter", ctx, filter, "7").Return(&kbs.KBPage{KBs: []kbs.KB{{Key: "c"}}}, nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	var keys []string
+
+	for kb, err := range kbService.AllKBs(ctx, filter) {
+		require.NoError(t, err)
+
+		keys = append(keys, kb.Key)
+	}
+
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+	storageMock.AssertExpectations(t)
+}
+
+func TestAllKBsSortedByKeyReadsPagesWithCursor(t *testing.T) {
+	filter := kbs.KBQueryFilter{SortBy: kbs.SortByAlphabetical, Limit: 2}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetAllAfter", ctx, filter, "").Return(&kbs.KBPage{KBs: []kbs.KB{{Key: "a"}, {Key: "b"}}, Next: `["b",2]`, Total: 3}, nil)
+	storageMock.On("GetAllAfter", ctx, filter, `["b",2]`).Return(&kbs.KBPage{KBs: []kbs.KB{{Key: "c"}}}, nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	var keys []string
+
+	for kb, err := range kbService.AllKBs(ctx, filter) {
+		require.NoError(t, err)
+
+		keys = append(keys, kb.Key)
+	}
+
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+	storageMock.AssertExpectations(t)
+}
+
+func TestAllKBsStopsWhenTheConsumerStops(t *testing.T) {
+	filter := kbs.KBQueryFilter{Limit: 2}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetAllAfter", ctx, filter, "").Return(&kbs.KBPage{KBs: []kbs.KB{{Key: "a"}, {Key: "b"}}, Next: "2"}, nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	for kb, err := range kbService.AllKBs(ctx, filter) {
+		require.NoError(t, err)
+		assert.Equal(t, "a", kb.Key)
+
+		break
+	}
+
+	storageMock.AssertNumberOfCalls(t, "GetAllAfter", 1)
+}
+
+func TestAllKBsYieldsStorageError(t *testing.T) {
+	filter := kbs.KBQueryFilter{Limit: 2}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetAllAfter", ctx, filter, "").Return((*kbs.KBPage)(nil), errors.New("database is locked"))
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	var errs []error
+
+	for _, err := range kbService.AllKBs(ctx, filter) {
+		errs = append(errs, err)
+	}
+
+	require.Len(t, errs, 1)
+	assert.Error(t, errs[0])
+}
+
+// ---- Tags ----
+
+func TestSearchByTagsOnly(t *testing.T) {
+	ctx := context.TODO()
+	filter := kbs.KBQueryFilter{AllTags: []string{"docker"}, Limit: 5}
+	want := kbs.SearchResult{Total: 1, Items: []kbs.KBItem{{Key: "compose-up", Tags: []string{"docker"}}}}
+	storageMock := newStorageMock()
+	storageMock.On("Search", ctx, filter).Return(&want, nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	got, err := kbService.Search(ctx, filter)
+
+	require.NoError(t, err)
+	assert.Equal(t, &want, got)
+}
+
+func TestUnusedTags(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetTagCounts", ctx).Return([]kbs.TagCount{
+		{Tag: "docker", Count: 3},
+		{Tag: "bitcoin", Count: 2},
+		{Tag: "dokcer", Count: 1},
+	}, nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	got, err := kbService.UnusedTags(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, []kbs.TagCount{{Tag: "dokcer", Count: 1}}, got)
+}
+
+func TestRenameTag(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("ReplaceTags", ctx, []string{"dokcer"}, "docker").Return(int64(2), nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	changed, err := kbService.RenameTag(ctx, "dokcer", "docker")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), changed)
+	storageMock.AssertExpectations(t)
+}
+
+func TestRenameTagInvalidValues(t *testing.T) {
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: newStorageMock()})
+
+	_, err := kbService.RenameTag(context.TODO(), "docker", "docker")
+	assert.Error(t, err)
+
+	_, err = kbService.RenameTag(context.TODO(), "docker", "not valid")
+	assert.Error(t, err)
+}
+
+func TestMergeTags(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("ReplaceTags", ctx, []string{"k8s", "kube"}, "kubernetes").Return(int64(5), nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	changed, err := kbService.MergeTags(ctx, []string{"k8s", "kube"}, "kubernetes")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), changed)
+
+	_, err = kbService.MergeTags(ctx, nil, "kubernetes")
+	assert.Error(t, err)
+}
+
+// ---- Namespaces ----
+
+func TestNamespaceTree(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetNamespaceCounts", ctx).Return([]kbs.NamespaceCount{
+		{Namespace: "default", Count: 3},
+		{Namespace: "team/backend", Count: 1},
+		{Namespace: "team/backend/payments", Count: 4},
+		{Namespace: "team/frontend", Count: 2},
+	}, nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	got, err := kbService.NamespaceTree(ctx)
+
+	payments := kbs.NamespaceNode{Name: "payments", Path: "team/backend/payments", Count: 4, Total: 4}
+	backend := kbs.NamespaceNode{Name: "backend", Path: "team/backend", Count: 1, Total: 5, Children: []*kbs.NamespaceNode{&payments}}
+	frontend := kbs.NamespaceNode{Name: "frontend", Path: "team/frontend", Count: 2, Total: 2}
+	want := []*kbs.NamespaceNode{
+		{Name: "default", Path: "default", Count: 3, Total: 3},
+		// team has no kbs of its own, only in its descendants.
+		{Name: "team", Path: "team", Count: 0, Total: 7, Children: []*kbs.NamespaceNode{&backend, &frontend}},
+	}
+
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestMoveNamespace(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("MoveNamespace", ctx, "team/backend", "platform/backend").Return(int64(3), nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	moved, err := kbService.MoveNamespace(ctx, "/Team/backend/", "platform//backend")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), moved)
+	storageMock.AssertExpectations(t)
+}
+
+func TestMoveNamespaceInvalidValues(t *testing.T) {
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: newStorageMock()})
+
+	cases := map[string][2]string{
+		"empty":             {"team", "/"},
+		"same":              {"team/backend", "team/backend/"},
+		"into a descendant": {"team", "team/backend"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := kbService.MoveNamespace(context.TODO(), tc[0], tc[1])
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+// ---- Categories ----
+
+func TestAddKBWithCategoryRules(t *testing.T) {
+	cases := map[string]struct {
+		newKB   kbs.NewKB
+		wantErr string
+	}{
+		"unknown category": {
+			newKB:   kbs.NewKB{Key: "k", Value: "v", Category: "unknown", Namespace: "default", Tags: []string{"t"}},
+			wantErr: "kb category is not registered",
+		},
+		"quote without reference": {
+			newKB:   kbs.NewKB{Key: "k", Value: "v", Category: "Quote", Namespace: "default", Tags: []string{"t"}},
+			wantErr: "kb reference is empty",
+		},
+		"bookmark that is not a url": {
+			newKB:   kbs.NewKB{Key: "k", Value: "go.dev", Category: "bookmark", Namespace: "default", Tags: []string{"t"}},
+			wantErr: "kb value must be an absolute url",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: newStorageMock()})
+
+			_, err := kbService.Add(context.TODO(), tc.newKB)
+
+			var dataError kbs.DataError
+			require.ErrorAs(t, err, &dataError)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestAddKBFollowingCategoryRules(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("Create", ctx, mock.AnythingOfType("kbs.KB")).Return("1", nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	_, err := kbService.Add(ctx, kbs.NewKB{Key: "go", Value: "https://go.dev", Category: "bookmark", Namespace: "default", Tags: []string{"golang"}})
+	require.NoError(t, err)
+
+	_, err = kbService.Add(ctx, kbs.NewKB{Key: "simple", Value: "keep it simple", Category: "quote", Reference: "rob pike", Namespace: "default", Tags: []string{"golang"}})
+	require.NoError(t, err)
+}
+
+func TestUpdateKBWithUnknownCategory(t *testing.T) {
+	kb := kbs.KB{ID: "1", Key: "k", Value: "v", Category: "unknown", Namespace: "default", Tags: []string{"t"}}
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: newStorageMock()})
+
+	err := kbService.Update(context.TODO(), kb)
+
+	assert.ErrorContains(t, err, "kb category is not registered")
+}
+
+func TestUpdateKBWithUpperCaseCategory(t *testing.T) {
+	kb := kbs.KB{ID: "1", Key: "k", Value: "v", Category: "Bitcoin", Namespace: "default", Tags: []string{"t"}}
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("Update", ctx, mock.AnythingOfType("*kbs.KB")).Return(nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	err := kbService.Update(ctx, kb)
+
+	require.NoError(t, err)
+	storageMock.AssertExpectations(t)
+}
+
+func TestAddCategory(t *testing.T) {
+	ctx := context.TODO()
+	want := kbs.Category{
+		Name:           "book",
+		Description:    "books to read",
+		RequiredFields: []string{kbs.NotesField, kbs.ReferenceField},
+		ValueFormat:    kbs.ValueFormatText,
+	}
+	storageMock := newStorageMock()
+	storageMock.On("SaveCategory", ctx, want).Return(nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	got, err := kbService.AddCategory(ctx, kbs.Category{
+		Name:           " Book ",
+		Description:    "books to read",
+		RequiredFields: []string{"reference", "Notes", "reference"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, &want, got)
+	storageMock.AssertExpectations(t)
+}
+
+func TestAddCategoryInvalidValues(t *testing.T) {
+	cases := map[string]kbs.Category{
+		"empty name":           {Name: " "},
+		"invalid name":         {Name: "my category"},
+		"unknown field":        {Name: "book", RequiredFields: []string{"author"}},
+		"unknown value format": {Name: "book", ValueFormat: "markdown"},
+	}
+
+	for name, category := range cases {
+		t.Run(name, func(t *testing.T) {
+			kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: newStorageMock()})
+
+			_, err := kbService.AddCategory(context.TODO(), category)
+
+			assert.ErrorAs(t, err, &kbs.DataError{})
+		})
+	}
+}
+
+func TestAddCategoryNameWithInvalidCharacters(t *testing.T) {
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: newStorageMock()})
+
+	_, err := kbService.AddCategory(context.TODO(), kbs.Category{Name: "k8s_notes"})
+
+	assert.ErrorContains(t, err, "category name must contain only letters, digits and hyphens")
+}
+
+func TestImportKBsWithUnknownCategory(t *testing.T) {
+	newKBs := []kbs.NewKB{
+		{Key: "k", Value: "v", Category: "unknown", Namespace: "default", Tags: []string{"t"}},
+	}
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: newStorageMock()})
+
+	result, err := kbService.Import(context.TODO(), newKBs, kbs.ImportOptions{})
+
+	assert.ErrorContains(t, err, "kb category is not registered")
+	assert.Nil(t, result)
+}
+
+// ---- Revisions ----
+
+func TestDiffRevision(t *testing.T) {
+	ctx := context.TODO()
+	current := &kbs.KB{ID: "1", Key: "halving", Value: "line one\nline three\n", Notes: "notes", Category: "bitcoin", Namespace: "default", Tags: []string{"bitcoin"}}
+	revisions := []kbs.Revision{
+		{Number: 1, KB: kbs.KB{Value: "line one\nline two\n", Notes: "notes"}, ChangedFields: []string{kbs.ValueField}},
+	}
+	storageMock := newStorageMock()
+	storageMock.On("GetByID", ctx, "1").Return(current, nil)
+	storageMock.On("GetRevisions", ctx, "1").Return(revisions, nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	got, err := kbService.Diff(ctx, "1", 1)
+
+	want := `--- value (revision 1)
++++ value (current)
+@@ -1,2 +1,2 @@
+ line one
+-line two
++line three
+`
+
+	require.NoError(t, err)
+	// notes did not change, so they have no diff.
+	assert.Equal(t, want, got)
+}
+
+func TestDiffRevisionNotFound(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetByID", ctx, "1").Return(&kbs.KB{ID: "1"}, nil)
+	storageMock.On("GetRevisions", ctx, "1").Return([]kbs.Revision{{Number: 1}}, nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	_, err := kbService.Diff(ctx, "1", 2)
+	assert.ErrorContains(t, err, "revision not found")
+
+	_, err = kbService.Diff(ctx, "1", 0)
+	assert.Error(t, err)
+}
+
+func TestRevertRevision(t *testing.T) {
+	ctx := context.TODO()
+	current := &kbs.KB{ID: "1", Key: "halving", Value: "new value", Notes: "new notes", Category: "bitcoin", Namespace: "default", Tags: []string{"bitcoin"}, RemoteID: "remote-1"}
+	old := kbs.KB{ID: "1", Key: "halving", Value: "old value", Notes: "old notes", Category: "bitcoin", Namespace: "default", Tags: []string{"bitcoin", "halving"}}
+	want := old
+	want.RemoteID = "remote-1"
+	storageMock := newStorageMock()
+	storageMock.On("GetByID", ctx, "1").Return(current, nil)
+	storageMock.On("GetRevisions", ctx, "1").Return([]kbs.Revision{{Number: 1, KB: old}}, nil)
+	storageMock.On("Update", ctx, &want).Return(nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	got, err := kbService.Revert(ctx, "1", 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, &want, got)
+	storageMock.AssertExpectations(t)
+}
+
+func TestKBChangedFields(t *testing.T) {
+	kb := kbs.KB{Key: "k", Value: "v", Notes: "n", Category: "c", Namespace: "ns", Tags: []string{"a", "b"}}
+	other := kb
+	other.Value = "other"
+	other.Tags = []string{"b", "a"}
+
+	assert.Equal(t, []string{kbs.ValueField}, kb.ChangedFields(other))
+	assert.Empty(t, kb.ChangedFields(kb))
+}
+
+// ---- Search ----
+
+func TestSearchKBsNothingToLookFor(t *testing.T) {
+	filter := kbs.KBQueryFilter{}
+
+	ctx := context.TODO()
+	settings := kbs.ServiceSetup{}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.Search(ctx, filter)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestSearchKBs(t *testing.T) {
+	filter := kbs.KBQueryFilter{Key: "halving", Limit: 5}
+	expectedResult := &kbs.SearchResult{
+		Items: []kbs.KBItem{},
+		Total: 0,
+		Limit: 5,
+	}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("Search", ctx, filter).Return(expectedResult, nil)
+
+	settings := kbs.ServiceSetup{KBStorage: storageMock}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.Search(ctx, filter)
+
+	require.NoError(t, err)
+	assert.Equal(t, expectedResult, result)
+	storageMock.AssertExpectations(t)
+}
+
+// ---- GetByID ----
+
+func TestGetByIDEmptyID(t *testing.T) {
+	ctx := context.TODO()
+	settings := kbs.ServiceSetup{}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.GetByID(ctx, "  ")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestGetByID(t *testing.T) {
+	id := "some-uuid"
+	expectedKB := &kbs.KB{
+		ID:        id,
+		Key:       "halving",
+		Value:     "The number of bitcoins generated per block is decreased 50% every four years",
+		Category:  "bitcoin",
+		Namespace: "cryptos",
+		Tags:      []string{"bitcoin", "halving"},
+	}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetByID", ctx, id).Return(expectedKB, nil)
+
+	settings := kbs.ServiceSetup{KBStorage: storageMock}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.GetByID(ctx, id)
+
+	require.NoError(t, err)
+	assert.Equal(t, expectedKB, result)
+	storageMock.AssertExpectations(t)
+}
+
+// ---- GetByKey ----
+
+func TestGetByKeyEmptyKey(t *testing.T) {
+	ctx := context.TODO()
+	settings := kbs.ServiceSetup{}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.GetByKey(ctx, "")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestGetByKey(t *testing.T) {
+	key := "halving"
+	expectedKB := &kbs.KB{
+		ID:        "some-uuid",
+		Key:       key,
+		Value:     "The number of bitcoins generated per block is decreased 50% every four years",
+		Category:  "bitcoin",
+		Namespace: "cryptos",
+		Tags:      []string{"bitcoin", "halving"},
+	}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetByKey", ctx, key).Return(expectedKB, nil)
+	storageMock.On("MarkAsAccessed", ctx, "some-uuid").Return(nil)
+
+	settings := kbs.ServiceSetup{KBStorage: storageMock}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.GetByKey(ctx, key)
+
+	require.NoError(t, err)
+	assert.Equal(t, expectedKB, result)
+	assert.Equal(t, 1, result.AccessCount)
+	assert.NotNil(t, result.LastAccessedOn)
+	storageMock.AssertExpectations(t)
+}
+
+func TestGetByKeyNotFoundIsNotMarkedAsAccessed(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetByKey", ctx, "halving").Return((*kbs.KB)(nil), nil)
+
+	settings := kbs.ServiceSetup{KBStorage: storageMock}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.GetByKey(ctx, "halving")
+
+	require.NoError(t, err)
+	assert.Nil(t, result)
+	storageMock.AssertNotCalled(t, "MarkAsAccessed", mock.Anything, mock.Anything)
+}
+
+func TestGetByKeyMarkAsAccessedFails(t *testing.T) {
+	expectedKB := &kbs.KB{ID: "some-uuid", Key: "halving", AccessCount: 3}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetByKey", ctx, "halving").Return(expectedKB, nil)
+	storageMock.On("MarkAsAccessed", ctx, "some-uuid").Return(errors.New("database is locked"))
+
+	settings := kbs.ServiceSetup{KBStorage: storageMock}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.GetByKey(ctx, "halving")
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.AccessCount)
+	assert.Nil(t, result.LastAccessedOn)
+	storageMock.AssertExpectations(t)
+}
+
+// ---- SuggestKeys ----
+
+func TestSuggestKeys(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetKeys", ctx).Return([]string{"docker-prune", "docker-ps", "halving"}, nil)
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	got, err := kbService.SuggestKeys(ctx, "dokcer-prune", 3)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docker-prune"}, got)
+	storageMock.AssertExpectations(t)
+}
+
+func TestSuggestKeysStorageError(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetKeys", ctx).Return([]string(nil), errors.New("database is locked"))
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	got, err := kbService.SuggestKeys(ctx, "docker", 3)
+
+	assert.Error(t, err)
+	assert.Nil(t, got)
+}
+
+// ---- GetRandomQuote ----
+
+func TestGetRandomQuoteNoQuotes(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("CountByCategory", ctx, kbs.QuoteCategory).Return(int64(0), nil)
+
+	settings := kbs.ServiceSetup{KBStorage: storageMock}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.GetRandomQuote(ctx)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+	storageMock.AssertExpectations(t)
+}
+
+func TestGetRandomQuoteOneQuote(t *testing.T) {
+	expectedKB := kbs.KB{
+		ID:        "some-uuid",
+		Key:       "my-quote",
+		Value:     "A quote value",
+		Category:  kbs.QuoteCategory,
 		Namespace: "general",
 		Tags:      []string{"inspirational"},
 	}
@@ -439,7 +1379,6 @@ func TestGetRandomQuoteManyQuotes(t *testing.T) {
 // ---- SaveForSync ----
 
 func TestSaveForSync(t *testing.T) {
-	syncFilePath := filepath.Join(t.TempDir(), "sync.yaml")
 	newKB := kbs.NewKB{
 		Key:       "halving",
 		Value:     "The number of bitcoins generated per block is decreased 50% every four years",
@@ -450,97 +1389,602 @@ func TestSaveForSync(t *testing.T) {
 	}
 
 	ctx := context.TODO()
-	settings := kbs.ServiceSetup{FileForSyncPath: syncFilePath}
+	syncQueueMock := newSyncQueueMock()
+	syncQueueMock.On("Enqueue", ctx, []kbs.SyncQueueEntry{{Operation: kbs.SyncOperationCreate, KB: newKB}}).Return(nil)
+	settings := kbs.ServiceSetup{SyncQueue: syncQueueMock}
+	kbService := kbs.NewService(settings)
+
+	err := kbService.SaveForSync(ctx, newKB)
+
+	require.NoError(t, err)
+	syncQueueMock.AssertExpectations(t)
+}
+
+// ---- Sync ----
+
+func TestSyncNothingToSync(t *testing.T) {
+	syncFilePath := filepath.Join(t.TempDir(), "sync.yaml")
+	// file does not exist
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", ctx, kbs.SyncStateNew).Return([]kbs.KB{}, nil)
+	storageMock.On("GetBySyncState", ctx, kbs.SyncStateModified).Return([]kbs.KB{}, nil)
+	kbClientMock := newKBClientMock()
+	kbClientMock.On("List", ctx, mock.AnythingOfType("kbs.KBQueryFilter")).Return(&kbs.SearchResult{}, nil)
+
+	settings := kbs.ServiceSetup{
+		FileForSyncPath: syncFilePath,
+		KBStorage:       storageMock,
+		KBClient:        kbClientMock,
+		SyncQueue:       newEmptySyncQueueMock(ctx),
+	}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{})
+
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.True(t, result.Empty())
+}
+
+func TestSyncWithItems(t *testing.T) {
+	syncFilePath := filepath.Join(t.TempDir(), "sync.yaml")
+	// sync file written by previous versions
+	syncContent := `Key: halving
+Value: The number of bitcoins generated per block is decreased 50% every four years
+Notes: Bitcoins have a finite supply
+Category: bitcoin
+Namespace: cryptos
+Tags:
+    - bitcoin
+    - halving
+`
+	err := os.WriteFile(syncFilePath, []byte(syncContent), 0644)
+	require.NoError(t, err)
+
+	queuedKB := kbs.NewKB{
+		Key:       "halving",
+		Value:     "The number of bitcoins generated per block is decreased 50% every four years",
+		Notes:     "Bitcoins have a finite supply",
+		Category:  "bitcoin",
+		Namespace: "cryptos",
+		Tags:      []string{"bitcoin", "halving"},
+	}
+	remoteKB := kbs.KB{
+		ID:       "new-id",
+		Key:      "halving",
+		Value:    "The number of bitcoins generated per block is decreased 50% every four years",
+		Notes:    "Bitcoins have a finite supply",
+		Category: "bitcoin",
+		Tags:     []string{"bitcoin", "halving"},
+	}
+
+	ctx := context.TODO()
+	syncQueueMock := newSyncQueueMock()
+	syncQueueMock.On("Enqueue", mock.Anything, []kbs.SyncQueueEntry{{Operation: kbs.SyncOperationCreate, KB: queuedKB}}).Return(nil)
+	syncQueueMock.On("GetSyncQueue", mock.Anything).Return([]kbs.SyncQueueEntry{{ID: 1, Operation: kbs.SyncOperationCreate, KB: queuedKB}}, nil)
+	syncQueueMock.On("Dequeue", mock.Anything, int64(1)).Return(nil)
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", mock.Anything, kbs.SyncStateNew).Return([]kbs.KB{}, nil)
+	storageMock.On("GetBySyncState", mock.Anything, kbs.SyncStateModified).Return([]kbs.KB{}, nil)
+	storageMock.On("GetByRemoteID", mock.Anything, "new-id").Return((*kbs.KB)(nil), nil)
+	storageMock.On("GetByKey", mock.Anything, "halving").Return((*kbs.KB)(nil), nil)
+	storageMock.On("Create", mock.Anything, mock.MatchedBy(func(kb kbs.KB) bool {
+		return kb.RemoteID == "new-id" && kb.SyncState == kbs.SyncStateSynced && kb.Namespace == kbs.DefaultNamespace
+	})).Return("1", nil)
+	kbClientMock := newKBClientMock()
+	kbClientMock.On("Create", mock.Anything, queuedKB).Return("new-id", nil)
+	kbClientMock.On("List", mock.Anything, mock.AnythingOfType("kbs.KBQueryFilter")).Return(&kbs.SearchResult{
+		Items: []kbs.KBItem{{ID: "new-id", Key: "halving"}},
+		Total: 1,
+	}, nil)
+	kbClientMock.On("Get", mock.Anything, "new-id").Return(&remoteKB, nil)
+
+	settings := kbs.ServiceSetup{
+		FileForSyncPath: syncFilePath,
+		KBStorage:       storageMock,
+		KBClient:        kbClientMock,
+		SyncQueue:       syncQueueMock,
+	}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{})
+
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, map[string]string{"halving": "new-id"}, result.Pushed)
+	assert.Len(t, result.Pulled, 1)
+	assert.Empty(t, result.FailedKeys)
+	kbClientMock.AssertExpectations(t)
+	storageMock.AssertExpectations(t)
+	syncQueueMock.AssertExpectations(t)
+
+	content, err := os.ReadFile(syncFilePath)
+	require.NoError(t, err)
+	assert.Empty(t, content)
+}
+
+func TestSyncWithPartialFailure(t *testing.T) {
+	queuedKB := kbs.NewKB{Key: "halving", Value: "value", Category: "bitcoin"}
+
+	ctx := context.TODO()
+	syncQueueMock := newSyncQueueMock()
+	syncQueueMock.On("GetSyncQueue", ctx).Return([]kbs.SyncQueueEntry{{ID: 1, Operation: kbs.SyncOperationCreate, KB: queuedKB, Attempts: 2}}, nil)
+	// it waits 4 minutes after the third failed attempt.
+	syncQueueMock.On("PostponeEntry", ctx, int64(1), "server error", mock.MatchedBy(func(nextAttemptOn time.Time) bool {
+		delay := time.Until(nextAttemptOn)
+		return delay > 3*time.Minute && delay <= 4*time.Minute
+	})).Return(nil)
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", ctx, kbs.SyncStateNew).Return([]kbs.KB{}, nil)
+	storageMock.On("GetBySyncState", ctx, kbs.SyncStateModified).Return([]kbs.KB{}, nil)
+	kbClientMock := newKBClientMock()
+	kbClientMock.On("Create", ctx, mock.AnythingOfType("kbs.NewKB")).Return("", errors.New("server error"))
+	kbClientMock.On("List", ctx, mock.AnythingOfType("kbs.KBQueryFilter")).Return(&kbs.SearchResult{}, nil)
+
+	settings := kbs.ServiceSetup{
+		KBStorage: storageMock,
+		KBClient:  kbClientMock,
+		SyncQueue: syncQueueMock,
+	}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{})
+
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Empty(t, result.Pushed)
+	assert.Len(t, result.FailedKeys, 1)
+	syncQueueMock.AssertExpectations(t)
+	syncQueueMock.AssertNotCalled(t, "Dequeue", mock.Anything, mock.Anything)
+}
+
+func TestSyncReplaysQueuedOperationsInOrder(t *testing.T) {
+	ctx := context.TODO()
+	syncQueueMock := newSyncQueueMock()
+	syncQueueMock.On("GetSyncQueue", mock.Anything).Return([]kbs.SyncQueueEntry{
+		{ID: 1, Operation: kbs.SyncOperationCreate, KB: kbs.NewKB{Key: "halving", Value: "value"}},
+		{ID: 2, Operation: kbs.SyncOperationDelete, RemoteID: "remote-3", KB: kbs.NewKB{Key: "wallet"}},
+	}, nil)
+	syncQueueMock.On("Dequeue", mock.Anything, mock.AnythingOfType("int64")).Return(nil)
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", mock.Anything, mock.AnythingOfType("kbs.SyncState")).Return([]kbs.KB{}, nil)
+	kbClientMock := newKBClientMock()
+	kbClientMock.On("Create", mock.Anything, kbs.NewKB{Key: "halving", Value: "value"}).Return("remote-1", nil)
+	storageMock.On("MarkAsDeletedOnServer", mock.Anything, "remote-3").Return(nil)
+	kbClientMock.On("Delete", mock.Anything, "remote-3").Return(nil)
+	kbClientMock.On("List", mock.Anything, mock.AnythingOfType("kbs.KBQueryFilter")).Return(&kbs.SearchResult{}, nil)
+
+	var replayed []string
+	for _, method := range []string{"Create", "Delete"} {
+		for _, call := range kbClientMock.ExpectedCalls {
+			if call.Method == method {
+				call.Run(func(mock.Arguments) { replayed = append(replayed, method) })
+			}
+		}
+	}
+
+	settings := kbs.ServiceSetup{
+		KBStorage: storageMock,
+		KBClient:  kbClientMock,
+		SyncQueue: syncQueueMock,
+	}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Create", "Delete"}, replayed)
+	assert.Equal(t, map[string]string{"halving": "remote-1", "wallet": "remote-3"}, result.Pushed)
+	syncQueueMock.AssertNumberOfCalls(t, "Dequeue", 2)
+	storageMock.AssertExpectations(t)
+}
+
+func TestSyncSkipsServerKBsWithQueuedDeletion(t *testing.T) {
+	ctx := context.TODO()
+	syncQueueMock := newSyncQueueMock()
+	// the kb was deleted locally, but the server was not reachable to delete it.
+	syncQueueMock.On("GetSyncQueue", ctx).Return([]kbs.SyncQueueEntry{
+		{ID: 1, Operation: kbs.SyncOperationDelete, RemoteID: "remote-1", KB: kbs.NewKB{Key: "wallet"}},
+	}, nil)
+	syncQueueMock.On("PostponeEntry", ctx, int64(1), "server error", mock.AnythingOfType("time.Time")).Return(nil)
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", ctx, mock.AnythingOfType("kbs.SyncState")).Return([]kbs.KB{}, nil)
+	kbClientMock := newKBClientMock()
+	kbClientMock.On("Delete", ctx, "remote-1").Return(errors.New("server error"))
+	kbClientMock.On("List", ctx, mock.AnythingOfType("kbs.KBQueryFilter")).Return(&kbs.SearchResult{
+		Items: []kbs.KBItem{{ID: "remote-1", Key: "wallet"}},
+		Total: 1,
+	}, nil)
+
+	settings := kbs.ServiceSetup{
+		KBStorage: storageMock,
+		KBClient:  kbClientMock,
+		SyncQueue: syncQueueMock,
+	}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{})
+
+	require.NoError(t, err)
+	assert.Empty(t, result.Pulled)
+	assert.Len(t, result.FailedKeys, 1)
+	kbClientMock.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	storageMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestSyncKeepsLaterOperationsOfAFailedKB(t *testing.T) {
+	ctx := context.TODO()
+	syncQueueMock := newSyncQueueMock()
+	syncQueueMock.On("GetSyncQueue", ctx).Return([]kbs.SyncQueueEntry{
+		{ID: 1, Operation: kbs.SyncOperationCreate, KB: kbs.NewKB{Key: "halving", Value: "value"}},
+		{ID: 2, Operation: kbs.SyncOperationDelete, RemoteID: "remote-1", KB: kbs.NewKB{Key: "halving"}},
+	}, nil)
+	syncQueueMock.On("PostponeEntry", ctx, int64(1), "server error", mock.AnythingOfType("time.Time")).Return(nil)
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", ctx, mock.AnythingOfType("kbs.SyncState")).Return([]kbs.KB{}, nil)
+	kbClientMock := newKBClientMock()
+	kbClientMock.On("Create", ctx, mock.AnythingOfType("kbs.NewKB")).Return("", errors.New("server error"))
+	kbClientMock.On("List", ctx, mock.AnythingOfType("kbs.KBQueryFilter")).Return(&kbs.SearchResult{}, nil)
+
+	settings := kbs.ServiceSetup{
+		KBStorage: storageMock,
+		KBClient:  kbClientMock,
+		SyncQueue: syncQueueMock,
+	}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{})
+
+	require.NoError(t, err)
+	assert.Len(t, result.FailedKeys, 1)
+	kbClientMock.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	syncQueueMock.AssertNumberOfCalls(t, "PostponeEntry", 1)
+	syncQueueMock.AssertNotCalled(t, "Dequeue", mock.Anything, mock.Anything)
+}
+
+func TestSyncSkipsQueuedKBsNotDue(t *testing.T) {
+	queuedKB := kbs.NewKB{Key: "halving", Value: "value", Category: "bitcoin"}
+
+	ctx := context.TODO()
+	syncQueueMock := newSyncQueueMock()
+	syncQueueMock.On("GetSyncQueue", ctx).Return([]kbs.SyncQueueEntry{
+		{ID: 1, Operation: kbs.SyncOperationCreate, KB: queuedKB, Attempts: 1, NextAttemptOn: time.Now().Add(time.Hour)},
+	}, nil)
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", ctx, mock.AnythingOfType("kbs.SyncState")).Return([]kbs.KB{}, nil)
+	kbClientMock := newKBClientMock()
+	kbClientMock.On("List", ctx, mock.AnythingOfType("kbs.KBQueryFilter")).Return(&kbs.SearchResult{}, nil)
+
+	settings := kbs.ServiceSetup{
+		KBStorage: storageMock,
+		KBClient:  kbClientMock,
+		SyncQueue: syncQueueMock,
+	}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{})
+
+	require.NoError(t, err)
+	assert.True(t, result.Empty())
+	kbClientMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestSyncStatus(t *testing.T) {
+	ctx := context.TODO()
+	syncQueueMock := newSyncQueueMock()
+	syncQueueMock.On("GetSyncQueue", ctx).Return([]kbs.SyncQueueEntry{{ID: 1, Operation: kbs.SyncOperationCreate, KB: kbs.NewKB{Key: "queued"}}}, nil)
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", ctx, kbs.SyncStateNew).Return([]kbs.KB{{ID: "1", Key: "new"}}, nil)
+	storageMock.On("GetBySyncState", ctx, kbs.SyncStateModified).Return([]kbs.KB{}, nil)
+
+	settings := kbs.ServiceSetup{
+		KBStorage: storageMock,
+		SyncQueue: syncQueueMock,
+	}
+	kbService := kbs.NewService(settings)
+
+	status, err := kbService.SyncStatus(ctx)
+
+	require.NoError(t, err)
+	assert.False(t, status.Empty())
+	assert.Len(t, status.Queued, 1)
+	assert.Len(t, status.New, 1)
+	assert.Empty(t, status.Modified)
+}
+
+func TestSyncPushesLocalChanges(t *testing.T) {
+	newKB := kbs.KB{ID: "local-1", Key: "new-kb", Value: "value", Category: "test", SyncState: kbs.SyncStateNew}
+	// the server kb was not changed since the last sync.
+	remoteKB := kbs.KB{ID: "remote-2", Key: "modified-kb", Value: "old value", Category: "test"}
+	modifiedKB := kbs.KB{ID: "local-2", Key: "modified-kb", Value: "value", Category: "test", SyncState: kbs.SyncStateModified, RemoteID: "remote-2", SyncHash: remoteKB.ContentHash()}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", mock.Anything, kbs.SyncStateNew).Return([]kbs.KB{newKB}, nil)
+	storageMock.On("GetBySyncState", mock.Anything, kbs.SyncStateModified).Return([]kbs.KB{modifiedKB}, nil)
+	storageMock.On("MarkAsSynced", mock.Anything, "local-1", "remote-1", newKB.ContentHash()).Return(nil)
+	storageMock.On("MarkAsSynced", mock.Anything, "local-2", "remote-2", modifiedKB.ContentHash()).Return(nil)
+	kbClientMock := newKBClientMock()
+	kbClientMock.On("Create", mock.Anything, mock.MatchedBy(func(kb kbs.NewKB) bool { return kb.Key == "new-kb" })).Return("remote-1", nil)
+	// the server kb is updated with its own id, not the local one.
+	kbClientMock.On("Update", mock.Anything, mock.MatchedBy(func(kb *kbs.KB) bool { return kb.ID == "remote-2" })).Return(nil)
+	kbClientMock.On("Get", mock.Anything, "remote-2").Return(&remoteKB, nil)
+	kbClientMock.On("List", mock.Anything, mock.AnythingOfType("kbs.KBQueryFilter")).Return(&kbs.SearchResult{}, nil)
+
+	settings := kbs.ServiceSetup{
+		KBStorage: storageMock,
+		KBClient:  kbClientMock,
+		SyncQueue: newEmptySyncQueueMock(ctx),
+	}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"new-kb": "remote-1", "modified-kb": "remote-2"}, result.Pushed)
+	assert.Empty(t, result.FailedKeys)
+	storageMock.AssertExpectations(t)
+	kbClientMock.AssertExpectations(t)
+}
+
+func TestSyncCanceledReturnsWhatWasSynced(t *testing.T) {
+	firstKB := kbs.KB{ID: "local-1", Key: "first-kb", Value: "value", Category: "test", SyncState: kbs.SyncStateNew}
+	secondKB := kbs.KB{ID: "local-2", Key: "second-kb", Value: "value", Category: "test", SyncState: kbs.SyncStateNew}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", ctx, kbs.SyncStateNew).Return([]kbs.KB{firstKB, secondKB}, nil)
+	storageMock.On("GetBySyncState", ctx, kbs.SyncStateModified).Return([]kbs.KB{}, nil)
+	storageMock.On("MarkAsSynced", mock.Anything, "local-1", "remote-1", firstKB.ContentHash()).Return(nil)
+	kbClientMock := newKBClientMock()
+	kbClientMock.On("Create", ctx, mock.MatchedBy(func(kb kbs.NewKB) bool { return kb.Key == "first-kb" })).Return("remote-1", nil)
+
+	settings := kbs.ServiceSetup{
+		KBStorage: storageMock,
+		KBClient:  kbClientMock,
+		SyncQueue: newEmptySyncQueueMock(ctx),
+	}
+	kbService := kbs.NewService(settings)
+
+	var got []kbs.Progress
+	options := kbs.SyncOptions{
+		Progress: func(progress kbs.Progress) {
+			got = append(got, progress)
+			// the sync is canceled after the first kb is pushed.
+			cancel()
+		},
+	}
+
+	result, err := kbService.Sync(ctx, options)
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, result)
+	assert.Equal(t, map[string]string{"first-kb": "remote-1"}, result.Pushed)
+	assert.Equal(t, []kbs.Progress{{Stage: kbs.ProgressStagePush, Done: 1, Total: 2}}, got)
+	kbClientMock.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+	storageMock.AssertExpectations(t)
+}
+
+func TestSyncPullsServerChanges(t *testing.T) {
+	localKB := kbs.KB{ID: "local-1", Key: "kb", Value: "old value", Category: "test", Namespace: "work", SyncState: kbs.SyncStateSynced, RemoteID: "remote-1"}
+	remoteKB := kbs.KB{ID: "remote-1", Key: "kb", Value: "new value", Category: "test"}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", mock.Anything, mock.AnythingOfType("kbs.SyncState")).Return([]kbs.KB{}, nil)
+	storageMock.On("GetByRemoteID", mock.Anything, "remote-1").Return(&localKB, nil)
+	storageMock.On("Update", mock.Anything, mock.MatchedBy(func(kb *kbs.KB) bool {
+		return kb.ID == "local-1" && kb.Value == "new value" && kb.Namespace == "work"
+	})).Return(nil)
+	storageMock.On("MarkAsSynced", mock.Anything, "local-1", "remote-1", remoteKB.ContentHash()).Return(nil)
+	kbClientMock := newKBClientMock()
+	kbClientMock.On("List", mock.Anything, mock.AnythingOfType("kbs.KBQueryFilter")).Return(&kbs.SearchResult{
+		Items: []kbs.KBItem{{ID: "remote-1", Key: "kb"}},
+		Total: 1,
+	}, nil)
+	kbClientMock.On("Get", mock.Anything, "remote-1").Return(&remoteKB, nil)
+
+	settings := kbs.ServiceSetup{
+		KBStorage: storageMock,
+		KBClient:  kbClientMock,
+		SyncQueue: newEmptySyncQueueMock(ctx),
+	}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"kb": "local-1"}, result.Pulled)
+	assert.Empty(t, result.Conflicted)
+	storageMock.AssertExpectations(t)
+}
+
+func TestSyncRegistersCategoriesOfPulledKBs(t *testing.T) {
+	localKB := kbs.KB{ID: "local-1", Key: "changed", Value: "old value", Category: "test", Namespace: "work", SyncState: kbs.SyncStateSynced, RemoteID: "remote-1"}
+	changedKB := kbs.KB{ID: "remote-1", Key: "changed", Value: "new value", Category: "Music"}
+	newKB := kbs.KB{ID: "remote-2", Key: "new", Value: "value", Category: "recipes"}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", mock.Anything, mock.AnythingOfType("kbs.SyncState")).Return([]kbs.KB{}, nil)
+	storageMock.On("GetByRemoteID", mock.Anything, "remote-1").Return(&localKB, nil)
+	storageMock.On("GetByRemoteID", mock.Anything, "remote-2").Return((*kbs.KB)(nil), nil)
+	storageMock.On("GetByKey", mock.Anything, "new").Return((*kbs.KB)(nil), nil)
+	storageMock.On("Update", mock.Anything, mock.AnythingOfType("*kbs.KB")).Return(nil)
+	storageMock.On("MarkAsSynced", mock.Anything, "local-1", "remote-1", changedKB.ContentHash()).Return(nil)
+	storageMock.On("Create", mock.Anything, mock.AnythingOfType("kbs.KB")).Return("2", nil)
+	kbClientMock := newKBClientMock()
+	kbClientMock.On("List", mock.Anything, mock.AnythingOfType("kbs.KBQueryFilter")).Return(&kbs.SearchResult{
+		Items: []kbs.KBItem{{ID: "remote-1", Key: "changed"}, {ID: "remote-2", Key: "new"}},
+		Total: 2,
+	}, nil)
+	kbClientMock.On("Get", mock.Anything, "remote-1").Return(&changedKB, nil)
+	kbClientMock.On("Get", mock.Anything, "remote-2").Return(&newKB, nil)
+
+	settings := kbs.ServiceSetup{
+		KBStorage: storageMock,
+		KBClient:  kbClientMock,
+		SyncQueue: newEmptySyncQueueMock(ctx),
+	}
+	kbService := kbs.NewService(settings)
+
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{})
+
+	require.NoError(t, err)
+	assert.Len(t, result.Pulled, 2)
+	assert.Empty(t, result.FailedKeys)
+	storageMock.AssertCalled(t, "RegisterCategory", mock.Anything, "music")
+	storageMock.AssertCalled(t, "RegisterCategory", mock.Anything, "recipes")
+}
+
+func TestSyncPullsAllServerKBsPageByPage(t *testing.T) {
+	firstKB := kbs.KB{ID: "remote-1", Key: "first", Value: "value", Category: "test"}
+	secondKB := kbs.KB{ID: "remote-2", Key: "second", Value: "value", Category: "test"}
+
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", mock.Anything, mock.AnythingOfType("kbs.SyncState")).Return([]kbs.KB{}, nil)
+	storageMock.On("GetByRemoteID", mock.Anything, mock.AnythingOfType("string")).Return((*kbs.KB)(nil), nil)
+	storageMock.On("GetByKey", mock.Anything, mock.AnythingOfType("string")).Return((*kbs.KB)(nil), nil)
+	storageMock.On("Create", mock.Anything, mock.AnythingOfType("kbs.KB")).Return("1", nil)
+	kbClientMock := newKBClientMock()
+	// the server lists all kbs without any key or keyword, a search would find nothing.
+	kbClientMock.On("List", mock.Anything, mock.MatchedBy(func(filter kbs.KBQueryFilter) bool {
+		return filter.Offset == 0 && filter.Key == "" && filter.Keyword == ""
+	})).Return(&kbs.SearchResult{Items: []kbs.KBItem{{ID: "remote-1", Key: "first"}}, Total: 2}, nil).Once()
+	kbClientMock.On("List", mock.Anything, mock.MatchedBy(func(filter kbs.KBQueryFilter) bool {
+		return filter.Offset == 1
+	})).Return(&kbs.SearchResult{Items: []kbs.KBItem{{ID: "remote-2", Key: "second"}}, Offset: 1, Total: 2}, nil).Once()
+	kbClientMock.On("Get", mock.Anything, "remote-1").Return(&firstKB, nil)
+	kbClientMock.On("Get", mock.Anything, "remote-2").Return(&secondKB, nil)
+
+	settings := kbs.ServiceSetup{
+		KBStorage: storageMock,
+		KBClient:  kbClientMock,
+		SyncQueue: newEmptySyncQueueMock(ctx),
+	}
 	kbService := kbs.NewService(settings)
 
-	err := kbService.SaveForSync(ctx, newKB)
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{})
 
 	require.NoError(t, err)
-	content, readErr := os.ReadFile(syncFilePath)
-	require.NoError(t, readErr)
-	assert.NotEmpty(t, content)
-	assert.Contains(t, string(content), "halving")
+	assert.Len(t, result.Pulled, 2)
+	assert.Contains(t, result.Pulled, "first")
+	assert.Contains(t, result.Pulled, "second")
+	kbClientMock.AssertExpectations(t)
 }
 
-// ---- Sync ----
-
-func TestSyncNoFile(t *testing.T) {
-	syncFilePath := filepath.Join(t.TempDir(), "sync.yaml")
-	// file does not exist
+func TestSyncReportsConflicts(t *testing.T) {
+	baseKB := kbs.KB{Key: "modified-kb", Value: "base value", Category: "test"}
+	modifiedKB := kbs.KB{ID: "local-1", Key: "modified-kb", Value: "local value", Category: "test", SyncState: kbs.SyncStateModified, RemoteID: "remote-1", SyncHash: baseKB.ContentHash()}
+	sameKeyKB := kbs.KB{ID: "local-2", Key: "same-key", Value: "value", Category: "test", SyncState: kbs.SyncStateNew}
 
 	ctx := context.TODO()
-	settings := kbs.ServiceSetup{FileForSyncPath: syncFilePath}
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", mock.Anything, kbs.SyncStateNew).Return([]kbs.KB{}, nil)
+	storageMock.On("GetBySyncState", mock.Anything, kbs.SyncStateModified).Return([]kbs.KB{modifiedKB}, nil)
+	storageMock.On("GetByRemoteID", mock.Anything, "remote-1").Return(&modifiedKB, nil)
+	storageMock.On("GetByRemoteID", mock.Anything, "remote-2").Return((*kbs.KB)(nil), nil)
+	storageMock.On("GetByKey", mock.Anything, "same-key").Return(&sameKeyKB, nil)
+	kbClientMock := newKBClientMock()
+	kbClientMock.On("List", mock.Anything, mock.AnythingOfType("kbs.KBQueryFilter")).Return(&kbs.SearchResult{
+		Items: []kbs.KBItem{{ID: "remote-1", Key: "modified-kb"}, {ID: "remote-2", Key: "same-key"}},
+		Total: 2,
+	}, nil)
+	kbClientMock.On("Get", mock.Anything, "remote-1").Return(&kbs.KB{ID: "remote-1", Key: "modified-kb", Value: "remote value", Category: "test"}, nil)
+	kbClientMock.On("Get", mock.Anything, "remote-2").Return(&kbs.KB{ID: "remote-2", Key: "same-key", Value: "other value", Category: "test"}, nil)
+
+	settings := kbs.ServiceSetup{
+		KBStorage: storageMock,
+		KBClient:  kbClientMock,
+		SyncQueue: newEmptySyncQueueMock(ctx),
+	}
 	kbService := kbs.NewService(settings)
 
-	result, err := kbService.Sync(ctx)
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{})
 
-	assert.NoError(t, err)
-	assert.Nil(t, result)
+	require.NoError(t, err)
+	assert.Empty(t, result.FailedKeys)
+	assert.Len(t, result.Conflicted, 2)
+	assert.Contains(t, result.Conflicted, "modified-kb")
+	assert.Contains(t, result.Conflicted, "same-key")
+	assert.Empty(t, result.Pulled)
+	assert.Empty(t, result.Pushed)
+	storageMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	storageMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	kbClientMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
 }
 
-func TestSyncWithItems(t *testing.T) {
-	syncFilePath := filepath.Join(t.TempDir(), "sync.yaml")
-	syncContent := `Key: halving
-Value: The number of bitcoins generated per block is decreased 50% every four years
-Notes: Bitcoins have a finite supply
-Category: bitcoin
-Namespace: cryptos
-Tags:
-    - bitcoin
-    - halving
-`
-	err := os.WriteFile(syncFilePath, []byte(syncContent), 0644)
-	require.NoError(t, err)
+func TestSyncPreferLocal(t *testing.T) {
+	baseKB := kbs.KB{Key: "kb", Value: "base value", Category: "test"}
+	localKB := kbs.KB{ID: "local-1", Key: "kb", Value: "local value", Category: "test", SyncState: kbs.SyncStateModified, RemoteID: "remote-1", SyncHash: baseKB.ContentHash()}
+	remoteKB := kbs.KB{ID: "remote-1", Key: "kb", Value: "remote value", Category: "test"}
 
 	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", mock.Anything, kbs.SyncStateNew).Return([]kbs.KB{}, nil)
+	storageMock.On("GetBySyncState", mock.Anything, kbs.SyncStateModified).Return([]kbs.KB{localKB}, nil)
+	storageMock.On("MarkAsSynced", mock.Anything, "local-1", "remote-1", localKB.ContentHash()).Return(nil)
 	kbClientMock := newKBClientMock()
-	kbClientMock.On("Create", ctx, mock.AnythingOfType("kbs.NewKB")).Return("new-id", nil)
+	kbClientMock.On("Get", mock.Anything, "remote-1").Return(&remoteKB, nil)
+	kbClientMock.On("Update", mock.Anything, mock.MatchedBy(func(kb *kbs.KB) bool {
+		return kb.ID == "remote-1" && kb.Value == "local value"
+	})).Return(nil)
+	kbClientMock.On("List", mock.Anything, mock.AnythingOfType("kbs.KBQueryFilter")).Return(&kbs.SearchResult{}, nil)
 
 	settings := kbs.ServiceSetup{
-		FileForSyncPath: syncFilePath,
-		KBClient:        kbClientMock,
+		KBStorage: storageMock,
+		KBClient:  kbClientMock,
+		SyncQueue: newEmptySyncQueueMock(ctx),
 	}
 	kbService := kbs.NewService(settings)
 
-	result, err := kbService.Sync(ctx)
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{Prefer: kbs.PreferLocal})
 
 	require.NoError(t, err)
-	require.NotNil(t, result)
-	assert.Len(t, result.NewIDs, 1)
-	assert.Empty(t, result.FailedKeys)
+	assert.Empty(t, result.Conflicted)
+	assert.Equal(t, map[string]kbs.ConflictResolution{"kb": kbs.PreferLocal}, result.Resolved)
+	assert.Equal(t, map[string]string{"kb": "remote-1"}, result.Pushed)
+	storageMock.AssertExpectations(t)
 	kbClientMock.AssertExpectations(t)
 }
 
-func TestSyncWithPartialFailure(t *testing.T) {
-	syncFilePath := filepath.Join(t.TempDir(), "sync.yaml")
-	syncContent := `Key: halving
-Value: The number of bitcoins generated per block is decreased 50% every four years
-Notes: Bitcoins have a finite supply
-Category: bitcoin
-Namespace: cryptos
-Tags:
-    - bitcoin
-    - halving
-`
-	err := os.WriteFile(syncFilePath, []byte(syncContent), 0644)
-	require.NoError(t, err)
+func TestSyncPreferRemote(t *testing.T) {
+	baseKB := kbs.KB{Key: "kb", Value: "base value", Category: "test"}
+	localKB := kbs.KB{ID: "local-1", Key: "kb", Value: "local value", Category: "test", Namespace: "work", SyncState: kbs.SyncStateModified, RemoteID: "remote-1", SyncHash: baseKB.ContentHash()}
+	remoteKB := kbs.KB{ID: "remote-1", Key: "kb", Value: "remote value", Category: "test"}
 
 	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("GetBySyncState", ctx, kbs.SyncStateNew).Return([]kbs.KB{}, nil)
+	storageMock.On("GetBySyncState", ctx, kbs.SyncStateModified).Return([]kbs.KB{localKB}, nil)
+	storageMock.On("Update", ctx, mock.MatchedBy(func(kb *kbs.KB) bool {
+		return kb.ID == "local-1" && kb.Value == "remote value" && kb.Namespace == "work"
+	})).Return(nil)
+	storageMock.On("MarkAsSynced", ctx, "local-1", "remote-1", remoteKB.ContentHash()).Return(nil)
 	kbClientMock := newKBClientMock()
-	kbClientMock.On("Create", ctx, mock.AnythingOfType("kbs.NewKB")).Return("", errors.New("server error"))
+	kbClientMock.On("Get", ctx, "remote-1").Return(&remoteKB, nil)
+	kbClientMock.On("List", ctx, mock.AnythingOfType("kbs.KBQueryFilter")).Return(&kbs.SearchResult{}, nil)
 
 	settings := kbs.ServiceSetup{
-		FileForSyncPath: syncFilePath,
-		KBClient:        kbClientMock,
+		KBStorage: storageMock,
+		KBClient:  kbClientMock,
+		SyncQueue: newEmptySyncQueueMock(ctx),
 	}
 	kbService := kbs.NewService(settings)
 
-	result, err := kbService.Sync(ctx)
+	result, err := kbService.Sync(ctx, kbs.SyncOptions{Prefer: kbs.PreferRemote})
 
 	require.NoError(t, err)
-	require.NotNil(t, result)
-	assert.Empty(t, result.NewIDs)
-	assert.Len(t, result.FailedKeys, 1)
+	assert.Empty(t, result.Conflicted)
+	assert.Equal(t, map[string]kbs.ConflictResolution{"kb": kbs.PreferRemote}, result.Resolved)
+	assert.Equal(t, map[string]string{"kb": "local-1"}, result.Pulled)
+	storageMock.AssertExpectations(t)
+	kbClientMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
 }
 
 // ---- SaveMedia ----
@@ -560,6 +2004,92 @@ func TestSaveMediaNotMediaFile(t *testing.T) {
 	assert.ErrorIs(t, err, kbs.ErrIsNotMediaFile)
 }
 
+// ---- Delete ----
+
+func TestDeleteKBEmptyID(t *testing.T) {
+	ctx := context.TODO()
+	kbService := kbs.NewService(kbs.ServiceSetup{})
+
+	err := kbService.Delete(ctx, " ")
+
+	assert.Error(t, err)
+}
+
+func TestDeleteKB(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("Delete", ctx, "some-uuid").Return(nil)
+
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	err := kbService.Delete(ctx, "some-uuid")
+
+	require.NoError(t, err)
+	storageMock.AssertExpectations(t)
+}
+
+func TestDeleteKBNotFound(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("Delete", ctx, "some-uuid").Return(kbs.ErrKBNotFound)
+
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	err := kbService.Delete(ctx, "some-uuid")
+
+	assert.ErrorIs(t, err, kbs.ErrKBNotFound)
+}
+
+// ---- Trash ----
+
+func TestRestoreKB(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("Restore", ctx, "some-uuid").Return(nil)
+
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	err := kbService.Restore(ctx, "some-uuid")
+
+	require.NoError(t, err)
+	storageMock.AssertExpectations(t)
+}
+
+func TestPurgeKB(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("Purge", ctx, "some-uuid").Return(nil)
+
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	err := kbService.Purge(ctx, "some-uuid")
+
+	require.NoError(t, err)
+	storageMock.AssertExpectations(t)
+}
+
+func TestEmptyTrash(t *testing.T) {
+	ctx := context.TODO()
+	storageMock := newStorageMock()
+	storageMock.On("EmptyTrash", ctx).Return(int64(3), nil)
+
+	kbService := kbs.NewService(kbs.ServiceSetup{KBStorage: storageMock})
+
+	purged, err := kbService.EmptyTrash(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+}
+
+func TestGetTrashInvalidFilter(t *testing.T) {
+	ctx := context.TODO()
+	kbService := kbs.NewService(kbs.ServiceSetup{})
+
+	result, err := kbService.GetTrash(ctx, kbs.KBQueryFilter{Limit: 0})
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
 
 // ---- Mocks ----
 
@@ -568,7 +2098,47 @@ type storageDummy struct {
 }
 
 func newStorageMock() *storageDummy {
-	return &storageDummy{}
+	storageMock := &storageDummy{}
+	storageMock.On("WithTransaction", mock.Anything).Return(nil).Maybe()
+	storageMock.On("GetCategories", mock.Anything).Return(testCategories(), nil).Maybe()
+	storageMock.On("RegisterCategory", mock.Anything, mock.Anything).Return(nil).Maybe()
+	storageMock.On("GetTrashedByID", mock.Anything, mock.Anything).Return((*kbs.KB)(nil), nil).Maybe()
+	storageMock.On("GetTrashedByKey", mock.Anything, mock.Anything).Return((*kbs.KB)(nil), nil).Maybe()
+
+	return storageMock
+}
+
+// newStorageMockWithTrash is like newStorageMock, but the given kbs are in the trash.
+func newStorageMockWithTrash(trashedKBs ...kbs.KB) *storageDummy {
+	storageMock := &storageDummy{}
+	storageMock.On("WithTransaction", mock.Anything).Return(nil).Maybe()
+	storageMock.On("GetCategories", mock.Anything).Return(testCategories(), nil).Maybe()
+	storageMock.On("RegisterCategory", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	for _, trashedKB := range trashedKBs {
+		storageMock.On("GetTrashedByID", mock.Anything, trashedKB.ID).Return(&trashedKB, nil).Maybe()
+		storageMock.On("GetTrashedByKey", mock.Anything, trashedKB.Key).Return(&trashedKB, nil).Maybe()
+	}
+
+	storageMock.On("GetTrashedByID", mock.Anything, mock.Anything).Return((*kbs.KB)(nil), nil).Maybe()
+	storageMock.On("GetTrashedByKey", mock.Anything, mock.Anything).Return((*kbs.KB)(nil), nil).Maybe()
+
+	return storageMock
+}
+
+// testCategories are the categories registered for the kbs used in tests.
+func testCategories() []kbs.Category {
+	categories := []kbs.Category{
+		{Name: kbs.BookmarkCategory, ValueFormat: kbs.ValueFormatURL},
+		{Name: kbs.MediaCategory, ValueFormat: kbs.ValueFormatMedia},
+		{Name: kbs.QuoteCategory, RequiredFields: []string{kbs.ReferenceField}, ValueFormat: kbs.ValueFormatText},
+	}
+
+	for _, name := range []string{"bitcoin", "cat1", "cat2", "command", "mycat", "protocols", "test"} {
+		categories = append(categories, kbs.Category{Name: name, ValueFormat: kbs.ValueFormatText})
+	}
+
+	return categories
 }
 
 func (k *storageDummy) Create(ctx context.Context, newKB kbs.KB) (string, error) {
@@ -595,6 +2165,18 @@ func (k *storageDummy) GetByKey(ctx context.Context, id string) (*kbs.KB, error)
 	return args.Get(0).(*kbs.KB), args.Error(1)
 }
 
+func (k *storageDummy) GetTrashedByID(ctx context.Context, id string) (*kbs.KB, error) {
+	args := k.Called(ctx, id)
+
+	return args.Get(0).(*kbs.KB), args.Error(1)
+}
+
+func (k *storageDummy) GetTrashedByKey(ctx context.Context, key string) (*kbs.KB, error) {
+	args := k.Called(ctx, key)
+
+	return args.Get(0).(*kbs.KB), args.Error(1)
+}
+
 func (k *storageDummy) Update(ctx context.Context, kb *kbs.KB) error {
 	args := k.Called(ctx, kb)
 
@@ -607,12 +2189,180 @@ func (k *storageDummy) GetAll(ctx context.Context, filter kbs.KBQueryFilter) (*k
 	return args.Get(0).(*kbs.GetAllResult), args.Error(1)
 }
 
+func (k *storageDummy) GetKeys(ctx context.Context) ([]string, error) {
+	args := k.Called(ctx)
+
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (k *storageDummy) GetAllAfter(ctx context.Context, filter kbs.KBQueryFilter, cursor string) (*kbs.KBPage, error) {
+	args := k.Called(ctx, filter, cursor)
+
+	return args.Get(0).(*kbs.KBPage), args.Error(1)
+}
+
 func (k *storageDummy) CountByCategory(ctx context.Context, category string) (int64, error) {
 	args := k.Called(ctx, category)
 
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (k *storageDummy) Delete(ctx context.Context, id string) error {
+	args := k.Called(ctx, id)
+
+	return args.Error(0)
+}
+
+func (k *storageDummy) Restore(ctx context.Context, id string) error {
+	args := k.Called(ctx, id)
+
+	return args.Error(0)
+}
+
+func (k *storageDummy) Purge(ctx context.Context, id string) error {
+	args := k.Called(ctx, id)
+
+	return args.Error(0)
+}
+
+func (k *storageDummy) EmptyTrash(ctx context.Context) (int64, error) {
+	args := k.Called(ctx)
+
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (k *storageDummy) GetTrash(ctx context.Context, filter kbs.KBQueryFilter) (*kbs.GetAllResult, error) {
+	args := k.Called(ctx, filter)
+
+	return args.Get(0).(*kbs.GetAllResult), args.Error(1)
+}
+
+func (k *storageDummy) GetByRemoteID(ctx context.Context, remoteID string) (*kbs.KB, error) {
+	args := k.Called(ctx, remoteID)
+
+	return args.Get(0).(*kbs.KB), args.Error(1)
+}
+
+func (k *storageDummy) GetBySyncState(ctx context.Context, state kbs.SyncState) ([]kbs.KB, error) {
+	args := k.Called(ctx, state)
+
+	return args.Get(0).([]kbs.KB), args.Error(1)
+}
+
+func (k *storageDummy) MarkAsSynced(ctx context.Context, id, remoteID, hash string) error {
+	args := k.Called(ctx, id, remoteID, hash)
+
+	return args.Error(0)
+}
+
+func (k *storageDummy) MarkAsDeletedOnServer(ctx context.Context, remoteID string) error {
+	args := k.Called(ctx, remoteID)
+
+	return args.Error(0)
+}
+
+func (k *storageDummy) MarkAsAccessed(ctx context.Context, id string) error {
+	args := k.Called(ctx, id)
+
+	return args.Error(0)
+}
+
+func (k *storageDummy) GetTagCounts(ctx context.Context) ([]kbs.TagCount, error) {
+	args := k.Called(ctx)
+
+	return args.Get(0).([]kbs.TagCount), args.Error(1)
+}
+
+func (k *storageDummy) ReplaceTags(ctx context.Context, oldTags []string, newTag string) (int64, error) {
+	args := k.Called(ctx, oldTags, newTag)
+
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (k *storageDummy) GetNamespaceCounts(ctx context.Context) ([]kbs.NamespaceCount, error) {
+	args := k.Called(ctx)
+
+	return args.Get(0).([]kbs.NamespaceCount), args.Error(1)
+}
+
+func (k *storageDummy) MoveNamespace(ctx context.Context, namespace, newNamespace string) (int64, error) {
+	args := k.Called(ctx, namespace, newNamespace)
+
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (k *storageDummy) GetCategories(ctx context.Context) ([]kbs.Category, error) {
+	args := k.Called(ctx)
+
+	return args.Get(0).([]kbs.Category), args.Error(1)
+}
+
+func (k *storageDummy) SaveCategory(ctx context.Context, category kbs.Category) error {
+	args := k.Called(ctx, category)
+
+	return args.Error(0)
+}
+
+func (k *storageDummy) RegisterCategory(ctx context.Context, name string) error {
+	args := k.Called(ctx, name)
+
+	return args.Error(0)
+}
+
+func (k *storageDummy) GetRevisions(ctx context.Context, id string) ([]kbs.Revision, error) {
+	args := k.Called(ctx, id)
+
+	return args.Get(0).([]kbs.Revision), args.Error(1)
+}
+
+func (k *storageDummy) WithTransaction(ctx context.Context, fn func(storage kbs.Storage) error) error {
+	args := k.Called(ctx)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return fn(k)
+}
+
+type syncQueueDummy struct {
+	mock.Mock
+}
+
+func newSyncQueueMock() *syncQueueDummy {
+	return &syncQueueDummy{}
+}
+
+func newEmptySyncQueueMock(ctx context.Context) *syncQueueDummy {
+	syncQueueMock := newSyncQueueMock()
+	syncQueueMock.On("GetSyncQueue", ctx).Return([]kbs.SyncQueueEntry{}, nil)
+
+	return syncQueueMock
+}
+
+func (q *syncQueueDummy) Enqueue(ctx context.Context, entries ...kbs.SyncQueueEntry) error {
+	args := q.Called(ctx, entries)
+
+	return args.Error(0)
+}
+
+func (q *syncQueueDummy) GetSyncQueue(ctx context.Context) ([]kbs.SyncQueueEntry, error) {
+	args := q.Called(ctx)
+
+	return args.Get(0).([]kbs.SyncQueueEntry), args.Error(1)
+}
+
+func (q *syncQueueDummy) Dequeue(ctx context.Context, id int64) error {
+	args := q.Called(ctx, id)
+
+	return args.Error(0)
+}
+
+func (q *syncQueueDummy) PostponeEntry(ctx context.Context, id int64, lastError string, nextAttemptOn time.Time) error {
+	args := q.Called(ctx, id, lastError, nextAttemptOn)
+
+	return args.Error(0)
+}
+
 type kbClientDummy struct {
 	mock.Mock
 }
@@ -633,7 +2383,13 @@ func (k *kbClientDummy) Update(ctx context.Context, kb *kbs.KB) error {
 	return args.Error(0)
 }
 
-func (k *kbClientDummy) Search(ctx context.Context, filter kbs.KBQueryFilter) (*kbs.SearchResult, error) {
+func (k *kbClientDummy) Delete(ctx context.Context, id string) error {
+	args := k.Called(ctx, id)
+
+	return args.Error(0)
+}
+
+func (k *kbClientDummy) List(ctx context.Context, filter kbs.KBQueryFilter) (*kbs.SearchResult, error) {
 	args := k.Called(ctx, filter)
 
 	return args.Get(0).(*kbs.SearchResult), args.Error(1)
@@ -0,0 +1,91 @@
+package kbs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SortFields returns what kbs can be sorted by.
+func SortFields() []SortBy {
+	return []SortBy{SortByRelevance, SortByCreated, SortByAlphabetical, SortByRecent, SortByMostUsed}
+}
+
+// ParseSortBy gets the sort field with the given name, empty for the default one.
+func ParseSortBy(value string) (SortBy, error) {
+	sortBy := SortBy(strings.ToLower(strings.TrimSpace(value)))
+
+	if sortBy != "" && !slices.Contains(SortFields(), sortBy) {
+		return "", fmt.Errorf("invalid sort %q, it must be one of: %s", value, strings.Join(sortFieldNames(), ", "))
+	}
+
+	return sortBy, nil
+}
+
+// ParseSortOrder gets the sort order with the given name, empty for the default order.
+func ParseSortOrder(value string) (SortOrder, error) {
+	order := SortOrder(strings.ToLower(strings.TrimSpace(value)))
+
+	switch order {
+	case "", SortAscending, SortDescending:
+		return order, nil
+	default:
+		return "", fmt.Errorf("invalid sort order %q, it must be %q or %q", value, SortAscending, SortDescending)
+	}
+}
+
+// DefaultOrder returns the order kbs are sorted in by the field if no order is given.
+func (s SortBy) DefaultOrder() SortOrder {
+	switch s {
+	case SortByRecent, SortByMostUsed:
+		return SortDescending
+	default:
+		return SortAscending
+	}
+}
+
+// Reverse returns the opposite order.
+func (s SortOrder) Reverse() SortOrder {
+	if s == SortDescending {
+		return SortAscending
+	}
+
+	return SortDescending
+}
+
+// Sorting returns what the kbs found by the filter are sorted by and in which
+// order, resolving the defaults. Only full text searches can be sorted by
+// relevance, the other ones are sorted by creation instead.
+func (k KBQueryFilter) Sorting() (SortBy, SortOrder) {
+	sortBy := k.SortBy
+
+	switch {
+	case sortBy == "" && k.IsFullTextSearch():
+		sortBy = SortByRelevance
+	case sortBy == "", sortBy == SortByRelevance && !k.IsFullTextSearch():
+		sortBy = SortByCreated
+	}
+
+	order := k.SortOrder
+	if order == "" {
+		order = sortBy.DefaultOrder()
+	}
+
+	return sortBy, order
+}
+
+// IsFullTextSearch indicates if the filter looks for a text in the kbs content.
+func (k KBQueryFilter) IsFullTextSearch() bool {
+	return k.Keyword != "" || k.Query != ""
+}
+
+func sortFieldNames() []string {
+	fields := SortFields()
+	names := make([]string, 0, len(fields))
+
+	for _, field := range fields {
+		names = append(names, string(field))
+	}
+
+	return names
+}
@@ -0,0 +1,529 @@
+package kbs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/adapters/filesystems"
+)
+
+// syncPageSize number of server kbs requested per page while pulling.
+const syncPageSize = 50
+
+var (
+	errLocalChangesNotPushed = errors.New("the kb was changed locally and on the server")
+	errKeyAlreadyExists      = errors.New("a local kb with the same key is not linked to the server kb")
+)
+
+// Sync pushes local creations and updates to the server and then pulls the server
+// kbs into the local storage. Kbs that were changed on both sides are reported as
+// conflicts, unless the options say which side must be kept.
+// If ctx is canceled, Sync stops after the current kb and returns what was synced
+// so far with the context error.
+func (s *Service) Sync(ctx context.Context, options SyncOptions) (*SyncResult, error) {
+	result := newSyncResult()
+
+	steps := []func(ctx context.Context, options SyncOptions, result *SyncResult) error{
+		s.pushQueuedKBs,
+		s.pushLocalChanges,
+		s.pullRemoteKBs,
+	}
+
+	for _, step := range steps {
+		err := step(ctx, options, result)
+		if ctx.Err() != nil {
+			return result, fmt.Errorf("synchronization was canceled: %w", ctx.Err())
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to process synchronization: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// pushQueuedKBs replays the due sync queue entries on the server in the order
+// they were queued. Entries are removed only after the server accepted them,
+// failed ones are tried again later, waiting longer after every failed attempt.
+// Once an entry of a kb is not sent, the following entries of that kb wait too,
+// so its changes never reach the server out of order.
+func (s *Service) pushQueuedKBs(ctx context.Context, options SyncOptions, result *SyncResult) error {
+	err := s.importSyncFile(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to import sync file: %w", err)
+	}
+
+	entries, err := s.syncQueue.GetSyncQueue(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get sync queue: %w", err)
+	}
+
+	now := time.Now()
+	waitingKeys := make(map[string]bool)
+
+	for index, entry := range entries {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		options.Progress.report(ProgressStagePushQueued, index+1, len(entries))
+
+		if waitingKeys[entry.KB.Key] || !entry.Due(now) {
+			waitingKeys[entry.KB.Key] = true
+			continue
+		}
+
+		id, err := s.pushQueueEntry(ctx, entry)
+		if err != nil && ctx.Err() != nil {
+			// the entry is kept as it is for the next sync.
+			return nil
+		}
+
+		if err != nil {
+			result.FailedKeys[entry.KB.Key] = err.Error()
+			waitingKeys[entry.KB.Key] = true
+			s.postponeEntry(ctx, entry, err, now)
+
+			continue
+		}
+
+		// the server accepted the change, so it is dequeued even if the sync was canceled.
+		err = s.unlinkDeletedKB(context.WithoutCancel(ctx), entry)
+		if err != nil {
+			result.FailedKeys[entry.KB.Key] = fmt.Sprintf("deleted on server, but unable to update sync state: %s", err)
+			waitingKeys[entry.KB.Key] = true
+
+			continue
+		}
+
+		err = s.syncQueue.Dequeue(context.WithoutCancel(ctx), entry.ID)
+		if err != nil {
+			result.FailedKeys[entry.KB.Key] = fmt.Sprintf("pushed as %s, but unable to remove it from sync queue: %s", id, err)
+			waitingKeys[entry.KB.Key] = true
+
+			continue
+		}
+
+		result.Pushed[entry.KB.Key] = id
+	}
+
+	return nil
+}
+
+// pushQueueEntry sends the entry change to the server and returns the id of the server kb.
+func (s *Service) pushQueueEntry(ctx context.Context, entry SyncQueueEntry) (string, error) {
+	switch entry.Operation {
+	case SyncOperationCreate:
+		return s.kbClient.Create(ctx, entry.KB)
+	case SyncOperationDelete:
+		return entry.RemoteID, s.kbClient.Delete(ctx, entry.RemoteID)
+	default:
+		return "", fmt.Errorf("unknown sync operation %q", entry.Operation)
+	}
+}
+
+// unlinkDeletedKB unlinks the local kb from its server kb once the server deleted it.
+// It is done before the entry is dequeued, so the kb is never linked to a server
+// kb that does not exist anymore.
+func (s *Service) unlinkDeletedKB(ctx context.Context, entry SyncQueueEntry) error {
+	if entry.Operation != SyncOperationDelete {
+		return nil
+	}
+
+	return s.storage.MarkAsDeletedOnServer(ctx, entry.RemoteID)
+}
+
+func (s *Service) postponeEntry(ctx context.Context, entry SyncQueueEntry, syncErr error, now time.Time) {
+	nextAttemptOn := now.Add(syncRetryDelay(entry.Attempts + 1))
+
+	err := s.syncQueue.PostponeEntry(ctx, entry.ID, syncErr.Error(), nextAttemptOn)
+	if err != nil {
+		slog.Error("unable to postpone sync queue entry",
+			slog.Int64("id", entry.ID),
+			slog.String("key", entry.KB.Key),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// importSyncFile moves the kbs of the sync file used by previous versions to the
+// sync queue. The file is truncated only after all of them were queued.
+func (s *Service) importSyncFile(ctx context.Context) error {
+	if s.fileForSyncPath == "" {
+		return nil
+	}
+
+	newKBs, err := loadSyncFile(s.fileForSyncPath)
+	if err != nil {
+		return fmt.Errorf("unable to load sync file: %w", err)
+	}
+
+	if len(newKBs) == 0 {
+		return nil
+	}
+
+	categories, err := s.Categories(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = validateKBs(newKBs, categories)
+	if err != nil {
+		return fmt.Errorf("one kb is not valid: %w", err)
+	}
+
+	entries := make([]SyncQueueEntry, 0, len(newKBs))
+	for _, newKB := range newKBs {
+		entries = append(entries, newCreateEntry(newKB))
+	}
+
+	err = s.syncQueue.Enqueue(ctx, entries...)
+	if err != nil {
+		return fmt.Errorf("unable to queue kbs: %w", err)
+	}
+
+	err = filesystems.TruncateFile(s.fileForSyncPath)
+	if err != nil {
+		return fmt.Errorf("unable to truncate sync file: %w", err)
+	}
+
+	return nil
+}
+
+// SyncStatus gets what is pending to be pushed to the server.
+func (s *Service) SyncStatus(ctx context.Context) (*SyncStatus, error) {
+	err := s.importSyncFile(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get sync status: %w", err)
+	}
+
+	queued, err := s.syncQueue.GetSyncQueue(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get sync queue: %w", err)
+	}
+
+	newKBs, err := s.storage.GetBySyncState(ctx, SyncStateNew)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get new kbs: %w", err)
+	}
+
+	modifiedKBs, err := s.storage.GetBySyncState(ctx, SyncStateModified)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get modified kbs: %w", err)
+	}
+
+	status := SyncStatus{
+		Queued:   queued,
+		New:      newKBs,
+		Modified: modifiedKBs,
+	}
+
+	return &status, nil
+}
+
+// pushLocalChanges creates on the server the kbs that were never pushed and
+// updates the ones that were modified since the last sync.
+func (s *Service) pushLocalChanges(ctx context.Context, options SyncOptions, result *SyncResult) error {
+	newKBs, err := s.storage.GetBySyncState(ctx, SyncStateNew)
+	if err != nil {
+		return fmt.Errorf("unable to get new kbs: %w", err)
+	}
+
+	modifiedKBs, err := s.storage.GetBySyncState(ctx, SyncStateModified)
+	if err != nil {
+		return fmt.Errorf("unable to get modified kbs: %w", err)
+	}
+
+	total := len(newKBs) + len(modifiedKBs)
+
+	for index, kb := range newKBs {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		remoteID, err := s.kbClient.Create(ctx, kb.toNewKB())
+		if err != nil {
+			result.FailedKeys[kb.Key] = err.Error()
+		} else {
+			s.markAsPushed(ctx, kb, remoteID, result)
+		}
+
+		options.Progress.report(ProgressStagePush, index+1, total)
+	}
+
+	for index, kb := range modifiedKBs {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		s.pushModifiedKB(ctx, kb, options, result)
+		options.Progress.report(ProgressStagePush, len(newKBs)+index+1, total)
+	}
+
+	return nil
+}
+
+// pushModifiedKB updates the server kb, unless it was changed since the last sync.
+func (s *Service) pushModifiedKB(ctx context.Context, localKB KB, options SyncOptions, result *SyncResult) {
+	remoteKB, err := s.kbClient.Get(ctx, localKB.RemoteID)
+	if err != nil {
+		result.FailedKeys[localKB.Key] = err.Error()
+		return
+	}
+
+	if remoteKB == nil {
+		s.pushUpdate(ctx, localKB, localKB.RemoteID, result)
+		return
+	}
+
+	remoteKB.Namespace = withDefault(remoteKB.Namespace, localKB.Namespace)
+
+	if changedOnServer(localKB, *remoteKB) {
+		s.resolveConflict(ctx, localKB, *remoteKB, errLocalChangesNotPushed, options, result)
+
+		return
+	}
+
+	s.pushUpdate(ctx, localKB, localKB.RemoteID, result)
+}
+
+// pushUpdate overwrites the server kb with the given id with the local kb content.
+func (s *Service) pushUpdate(ctx context.Context, localKB KB, remoteID string, result *SyncResult) {
+	remoteKB := localKB
+	remoteKB.ID = remoteID
+
+	err := s.kbClient.Update(ctx, &remoteKB)
+	if err != nil {
+		result.FailedKeys[localKB.Key] = err.Error()
+		return
+	}
+
+	s.markAsPushed(ctx, localKB, remoteID, result)
+}
+
+func (s *Service) markAsPushed(ctx context.Context, kb KB, remoteID string, result *SyncResult) {
+	// the server has the kb, so it is marked as synced even if the sync was canceled.
+	err := s.storage.MarkAsSynced(context.WithoutCancel(ctx), kb.ID, remoteID, kb.ContentHash())
+	if err != nil {
+		result.FailedKeys[kb.Key] = fmt.Sprintf("pushed as %s, but unable to update sync state: %s", remoteID, err)
+		return
+	}
+
+	result.Pushed[kb.Key] = remoteID
+}
+
+// pullRemoteKBs walks all server kbs page by page and saves locally the ones
+// that are new or were changed on the server. Server kbs whose deletion is still
+// queued are skipped, so kbs deleted locally are not pulled again.
+func (s *Service) pullRemoteKBs(ctx context.Context, options SyncOptions, result *SyncResult) error {
+	filter := KBQueryFilter{
+		Limit: syncPageSize,
+	}
+
+	deleted, err := s.queuedDeletions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for {
+		page, err := s.kbClient.List(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("unable to list server kbs: %w", err)
+		}
+
+		if page == nil || len(page.Items) == 0 {
+			return nil
+		}
+
+		for index, item := range page.Items {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			if !deleted[item.ID] {
+				s.pullRemoteKB(ctx, item, options, result)
+			}
+
+			options.Progress.report(ProgressStagePull, int(filter.Offset)+index+1, page.Total)
+		}
+
+		filter.Offset += uint32(len(page.Items))
+		if int(filter.Offset) >= page.Total {
+			return nil
+		}
+	}
+}
+
+// queuedDeletions gets the ids of the server kbs whose deletion is queued.
+func (s *Service) queuedDeletions(ctx context.Context) (map[string]bool, error) {
+	entries, err := s.syncQueue.GetSyncQueue(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get sync queue: %w", err)
+	}
+
+	deleted := make(map[string]bool)
+
+	for _, entry := range entries {
+		if entry.Operation == SyncOperationDelete {
+			deleted[entry.RemoteID] = true
+		}
+	}
+
+	return deleted, nil
+}
+
+func (s *Service) pullRemoteKB(ctx context.Context, item KBItem, options SyncOptions, result *SyncResult) {
+	remoteKB, err := s.kbClient.Get(ctx, item.ID)
+	if err != nil {
+		result.FailedKeys[item.Key] = err.Error()
+		return
+	}
+
+	if remoteKB == nil {
+		return
+	}
+
+	// the server kb was read, so it is saved locally even if the sync was canceled.
+	ctx = context.WithoutCancel(ctx)
+
+	localKB, err := s.storage.GetByRemoteID(ctx, remoteKB.ID)
+	if err != nil {
+		result.FailedKeys[remoteKB.Key] = err.Error()
+		return
+	}
+
+	if localKB == nil {
+		remoteKB.Namespace = withDefault(remoteKB.Namespace, DefaultNamespace)
+		s.pullNewKB(ctx, *remoteKB, options, result)
+
+		return
+	}
+
+	// the server does not keep namespaces, so the local one is kept.
+	remoteKB.Namespace = withDefault(remoteKB.Namespace, localKB.Namespace)
+
+	s.pullChangedKB(ctx, *localKB, *remoteKB, options, result)
+}
+
+// pullNewKB saves locally a server kb that is not linked to any local kb yet.
+// Its category is registered without rules if it is not registered yet.
+func (s *Service) pullNewKB(ctx context.Context, remoteKB KB, options SyncOptions, result *SyncResult) {
+	sameKeyKB, err := s.storage.GetByKey(ctx, remoteKB.Key)
+	if err != nil {
+		result.FailedKeys[remoteKB.Key] = err.Error()
+		return
+	}
+
+	if sameKeyKB != nil {
+		s.resolveConflict(ctx, *sameKeyKB, remoteKB, errKeyAlreadyExists, options, result)
+		return
+	}
+
+	newKB := remoteKB.toNewKB().toKB()
+	newKB.SyncState = SyncStateSynced
+	newKB.RemoteID = remoteKB.ID
+	newKB.SyncHash = remoteKB.ContentHash()
+
+	err = s.storage.RegisterCategory(ctx, newKB.Category)
+	if err != nil {
+		result.FailedKeys[remoteKB.Key] = err.Error()
+		return
+	}
+
+	_, err = s.storage.Create(ctx, newKB)
+	if err != nil {
+		result.FailedKeys[remoteKB.Key] = err.Error()
+		return
+	}
+
+	result.Pulled[remoteKB.Key] = newKB.ID
+}
+
+// pullChangedKB updates the local kb with the server content. If the local kb
+// has changes that were not pushed, it is a conflict.
+func (s *Service) pullChangedKB(ctx context.Context, localKB, remoteKB KB, options SyncOptions, result *SyncResult) {
+	if localKB.DeletedOn != nil || localKB.sameContent(remoteKB) {
+		return
+	}
+
+	if localKB.SyncState == SyncStateSynced {
+		s.pullUpdate(ctx, localKB, remoteKB, result)
+		return
+	}
+
+	if !changedOnServer(localKB, remoteKB) {
+		// local changes will be pushed in the next sync.
+		return
+	}
+
+	s.resolveConflict(ctx, localKB, remoteKB, errLocalChangesNotPushed, options, result)
+}
+
+// pullUpdate overwrites the local kb with the server kb content. Its category
+// is registered without rules if it is not registered yet.
+func (s *Service) pullUpdate(ctx context.Context, localKB, remoteKB KB, result *SyncResult) {
+	updatedKB := remoteKB
+	updatedKB.ID = localKB.ID
+
+	err := s.storage.RegisterCategory(ctx, strings.ToLower(updatedKB.Category))
+	if err != nil {
+		result.FailedKeys[localKB.Key] = err.Error()
+		return
+	}
+
+	err = s.storage.Update(ctx, &updatedKB)
+	if err != nil {
+		result.FailedKeys[localKB.Key] = err.Error()
+		return
+	}
+
+	err = s.storage.MarkAsSynced(ctx, localKB.ID, remoteKB.ID, remoteKB.ContentHash())
+	if err != nil {
+		result.FailedKeys[localKB.Key] = err.Error()
+		return
+	}
+
+	result.Pulled[remoteKB.Key] = localKB.ID
+}
+
+// resolveConflict keeps the side the options prefer, or reports the conflict.
+func (s *Service) resolveConflict(ctx context.Context, localKB, remoteKB KB, reason error, options SyncOptions, result *SyncResult) {
+	switch options.Prefer {
+	case PreferLocal:
+		s.pushUpdate(ctx, localKB, remoteKB.ID, result)
+	case PreferRemote:
+		s.pullUpdate(ctx, localKB, remoteKB, result)
+	default:
+		result.Conflicted[localKB.Key] = reason.Error()
+		return
+	}
+
+	if _, failed := result.FailedKeys[localKB.Key]; !failed {
+		result.Resolved[localKB.Key] = options.Prefer
+		delete(result.Conflicted, localKB.Key)
+	}
+}
+
+// changedOnServer indicates if the server kb is different from the content both
+// sides had the last time they were synced. Without that content, any difference
+// is taken as a server change.
+func changedOnServer(localKB, remoteKB KB) bool {
+	if localKB.sameContent(remoteKB) {
+		return false
+	}
+
+	return localKB.SyncHash == "" || remoteKB.ContentHash() != localKB.SyncHash
+}
+
+func withDefault(value, defaultValue string) string {
+	if IsStringEmpty(value) {
+		return defaultValue
+	}
+
+	return value
+}
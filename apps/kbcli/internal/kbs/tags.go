@@ -0,0 +1,77 @@
+package kbs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	errNoTagsToMerge = errors.New("at least one tag to merge is required")
+	errSameTag       = errors.New("the new tag is the same as the old one")
+)
+
+// ListTags gets every tag with the number of kbs that have it, the most used first.
+func (s *Service) ListTags(ctx context.Context) ([]TagCount, error) {
+	tags, err := s.storage.GetTagCounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// UnusedTags gets the tags that only one kb has, which are usually typos or
+// tags that should be merged with another one.
+func (s *Service) UnusedTags(ctx context.Context) ([]TagCount, error) {
+	tags, err := s.ListTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(tags, func(tag TagCount) bool {
+		return tag.Count > 1
+	}), nil
+}
+
+// RenameTag replaces the old tag with the new one in every kb out of the trash
+// and returns how many kbs were changed.
+func (s *Service) RenameTag(ctx context.Context, oldTag, newTag string) (int64, error) {
+	if oldTag == newTag {
+		return 0, errSameTag
+	}
+
+	changed, err := s.replaceTags(ctx, []string{oldTag}, newTag)
+	if err != nil {
+		return 0, fmt.Errorf("unable to rename tag: %w", err)
+	}
+
+	return changed, nil
+}
+
+// MergeTags replaces the given tags with the into tag in every kb out of the
+// trash and returns how many kbs were changed. Kbs that end up with the same
+// tag twice keep one.
+func (s *Service) MergeTags(ctx context.Context, tags []string, into string) (int64, error) {
+	if len(tags) == 0 {
+		return 0, errNoTagsToMerge
+	}
+
+	changed, err := s.replaceTags(ctx, tags, into)
+	if err != nil {
+		return 0, fmt.Errorf("unable to merge tags: %w", err)
+	}
+
+	return changed, nil
+}
+
+func (s *Service) replaceTags(ctx context.Context, oldTags []string, newTag string) (int64, error) {
+	for _, tag := range append(slices.Clone(oldTags), newTag) {
+		if !IsLetter(tag) {
+			return 0, fmt.Errorf("%q: %w", tag, errKBTagValues)
+		}
+	}
+
+	return s.storage.ReplaceTags(ctx, oldTags, newTag)
+}
@@ -0,0 +1,235 @@
+package kbs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/adapters/filesystems"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// FormatMarkdown writes one markdown file per kb in a folder, like an obsidian
+// vault. It is not a stream format, so it is not in the codec registry.
+const FormatMarkdown Format = "markdown"
+
+// markdown vault values
+const (
+	markdownExtension    = ".md"
+	frontMatterDelimiter = "---\n"
+	unsafePathCharacters = `/\:*?"<>|%`
+)
+
+// frontMatter is the yaml header of a kb markdown file.
+type frontMatter struct {
+	ID        string   `yaml:"id,omitempty"`
+	Key       string   `yaml:"key"`
+	Category  string   `yaml:"category"`
+	Namespace string   `yaml:"namespace"`
+	Reference string   `yaml:"reference,omitempty"`
+	Tags      []string `yaml:"tags"`
+	Notes     string   `yaml:"notes"`
+}
+
+var errMissingFrontMatter = errors.New("markdown file does not start with a yaml front matter")
+
+// VaultWriter writes kbs in a markdown vault and remembers the files it wrote,
+// so two kbs saved by the same writer never share a file.
+type VaultWriter struct {
+	dir   string
+	paths map[string]struct{}
+}
+
+// NewVaultWriter creates a writer for the vault in dir.
+func NewVaultWriter(dir string) *VaultWriter {
+	return &VaultWriter{
+		dir:   dir,
+		paths: make(map[string]struct{}),
+	}
+}
+
+// SaveToVault writes every kb in its own markdown file under dir, in the
+// namespace/category/key.md path. Existing files are overwritten.
+func SaveToVault(dir string, kbs ...KB) error {
+	return NewVaultWriter(dir).Save(kbs...)
+}
+
+// Save writes every kb in its own markdown file, see KB.VaultPath. If the path
+// of a kb was already written, e.g. by keys that only differ in case on a case
+// insensitive file system, a number is added to the file name. Existing files
+// that were not written by this writer are overwritten.
+func (v *VaultWriter) Save(kbs ...KB) error {
+	for _, kb := range kbs {
+		content, err := kb.ToMarkdown()
+		if err != nil {
+			return fmt.Errorf("unable to save kb %q in vault: %w", kb.Key, err)
+		}
+
+		path := filepath.Join(v.dir, v.reservePath(kb.VaultPath()))
+
+		err = filesystems.MakeFolders(filepath.Dir(path))
+		if err != nil {
+			return fmt.Errorf("unable to save kb %q in vault: %w", kb.Key, err)
+		}
+
+		err = filesystems.SaveFile(path, content)
+		if err != nil {
+			return fmt.Errorf("unable to save kb %q in vault: %w", kb.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// LoadVault reads the kbs of all markdown files under dir.
+func LoadVault(dir string) ([]NewKB, error) {
+	files, err := filesystems.FindFiles(dir, markdownExtension)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load vault: %w", err)
+	}
+
+	newKBs := make([]NewKB, 0, len(files))
+
+	for _, file := range files {
+		content, err := filesystems.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load vault: %w", err)
+		}
+
+		newKB, err := ParseMarkdown(content)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load vault file %q: %w", file, err)
+		}
+
+		newKBs = append(newKBs, newKB)
+	}
+
+	return newKBs, nil
+}
+
+// reservePath returns the path, or the first free path-N variant of it, and
+// marks it as written.
+func (v *VaultWriter) reservePath(path string) string {
+	base := strings.TrimSuffix(path, markdownExtension)
+
+	for n := 2; ; n++ {
+		if _, ok := v.paths[strings.ToLower(path)]; !ok {
+			break
+		}
+
+		path = fmt.Sprintf("%s-%d%s", base, n, markdownExtension)
+	}
+
+	v.paths[strings.ToLower(path)] = struct{}{}
+
+	return path
+}
+
+// VaultPath returns the relative path of the kb markdown file in a vault. Every
+// part of the namespace is a folder, e.g. team/backend/category/key.md.
+func (k KB) VaultPath() string {
+	parts := make([]string, 0, 4)
+
+	for part := range strings.SplitSeq(withDefault(k.Namespace, DefaultNamespace), NamespaceSeparator) {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		parts = append(parts, toPathName(part))
+	}
+
+	parts = append(parts, toPathName(k.Category), toPathName(k.Key)+markdownExtension)
+
+	return filepath.Join(parts...)
+}
+
+// ToMarkdown returns the kb as markdown. Everything but the value goes in the
+// front matter, the value is the body. Notes are a front matter field too, so
+// no text in the value or the notes can be taken as the start of the other.
+func (k KB) ToMarkdown() ([]byte, error) {
+	header, err := yaml.Marshal(frontMatter{
+		ID:        k.ID,
+		Key:       k.Key,
+		Category:  k.Category,
+		Namespace: k.Namespace,
+		Reference: k.Reference,
+		Tags:      k.Tags,
+		Notes:     strings.Trim(k.Notes, "\n"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal front matter: %w", err)
+	}
+
+	var content bytes.Buffer
+
+	content.WriteString(frontMatterDelimiter)
+	content.Write(header)
+	content.WriteString(frontMatterDelimiter)
+	content.WriteString("\n")
+	content.WriteString(strings.Trim(k.Value, "\n"))
+	content.WriteString("\n")
+
+	return content.Bytes(), nil
+}
+
+// ParseMarkdown reads a kb written by ToMarkdown.
+func ParseMarkdown(content []byte) (NewKB, error) {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+
+	if !strings.HasPrefix(text, frontMatterDelimiter) {
+		return NewKB{}, errMissingFrontMatter
+	}
+
+	header, body, found := strings.Cut(text[len(frontMatterDelimiter):], "\n"+frontMatterDelimiter)
+	if !found {
+		return NewKB{}, errMissingFrontMatter
+	}
+
+	var meta frontMatter
+
+	err := yaml.Unmarshal([]byte(header), &meta)
+	if err != nil {
+		return NewKB{}, fmt.Errorf("unable to parse front matter: %w", err)
+	}
+
+	return NewKB{
+		ID:        meta.ID,
+		Key:       meta.Key,
+		Value:     strings.Trim(body, "\n"),
+		Notes:     strings.Trim(meta.Notes, "\n"),
+		Category:  meta.Category,
+		Reference: meta.Reference,
+		Namespace: meta.Namespace,
+		Tags:      meta.Tags,
+	}, nil
+}
+
+// toPathName escapes the characters that are not allowed in file names as %XX,
+// like URLs do, so different values never get the same name, e.g. http/2 is
+// http%2F2 and http-2 stays http-2.
+func toPathName(value string) string {
+	var name strings.Builder
+
+	for _, r := range strings.TrimSpace(value) {
+		if strings.ContainsRune(unsafePathCharacters, r) {
+			fmt.Fprintf(&name, "%%%02X", r)
+
+			continue
+		}
+
+		name.WriteRune(r)
+	}
+
+	switch name.String() {
+	case "":
+		return "_"
+	case ".":
+		return "%2E"
+	case "..":
+		return "%2E%2E"
+	}
+
+	return name.String()
+}
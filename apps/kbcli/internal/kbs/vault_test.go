@@ -0,0 +1,185 @@
+package kbs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fernandoocampo/kbkitt/apps/kbcli/internal/kbs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKBToMarkdown(t *testing.T) {
+	kb := kbs.KB{
+		ID:        "1",
+		Key:       "halving",
+		Value:     "The number of bitcoins generated per block is decreased 50% every four years",
+		Notes:     "Bitcoins have a finite supply",
+		Category:  "bitcoin",
+		Reference: "https://bitcoin.org",
+		Namespace: "cryptos",
+		Tags:      []string{"bitcoin", "halving"},
+	}
+	want := `---
+id: "1"
+key: halving
+category: bitcoin
+namespace: cryptos
+reference: https://bitcoin.org
+tags:
+    - bitcoin
+    - halving
+notes: Bitcoins have a finite supply
+---
+
+The number of bitcoins generated per block is decreased 50% every four years
+`
+
+	got, err := kb.ToMarkdown()
+
+	require.NoError(t, err)
+	assert.Equal(t, want, string(got))
+	assert.Equal(t, filepath.Join("cryptos", "bitcoin", "halving.md"), kb.VaultPath())
+}
+
+func TestParseMarkdown(t *testing.T) {
+	content := "---\r\nkey: compose-up\r\ncategory: command\r\nnamespace: devops\r\ntags: [docker]\r\n---\r\n\r\n" +
+		"```sh\r\ndocker compose up -d\r\n```\r\n"
+
+	got, err := kbs.ParseMarkdown([]byte(content))
+
+	require.NoError(t, err)
+	assert.Equal(t, kbs.NewKB{
+		Key:       "compose-up",
+		Value:     "```sh\ndocker compose up -d\n```",
+		Category:  "command",
+		Namespace: "devops",
+		Tags:      []string{"docker"},
+	}, got)
+}
+
+func TestParseMarkdownWithoutFrontMatter(t *testing.T) {
+	_, err := kbs.ParseMarkdown([]byte("# just a note\n"))
+
+	assert.Error(t, err)
+}
+
+func TestMarkdownRoundTripWithHeadingsInValueAndNotes(t *testing.T) {
+	kb := kbs.KB{
+		Key:       "runbook",
+		Value:     "# Restart\n\n## Notes\n\nrestart the service\n\n---\n\n## Steps\n\n1. stop\n2. start",
+		Notes:     "## Notes\n\nwritten after the outage\n---\nkey: other",
+		Category:  "runbook",
+		Namespace: "ops",
+		Tags:      []string{"ops"},
+	}
+
+	content, err := kb.ToMarkdown()
+	require.NoError(t, err)
+
+	got, err := kbs.ParseMarkdown(content)
+
+	require.NoError(t, err)
+	assert.Equal(t, kb.Key, got.Key)
+	assert.Equal(t, kb.Value, got.Value)
+	assert.Equal(t, kb.Notes, got.Notes)
+}
+
+func TestVaultRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	givenKBs := []kbs.KB{
+		{
+			ID:        "1",
+			Key:       "halving",
+			Value:     "The number of bitcoins generated per block is decreased 50% every four years",
+			Notes:     "Bitcoins have a finite supply",
+			Category:  "bitcoin",
+			Namespace: "cryptos",
+			Tags:      []string{"bitcoin", "halving"},
+		},
+		{
+			ID:       "2",
+			Key:      "http/2",
+			Value:    "binary framing",
+			Category: "protocols",
+			Tags:     []string{"http"},
+		},
+	}
+
+	err := kbs.SaveToVault(dir, givenKBs...)
+	require.NoError(t, err)
+
+	// keys are made safe to be used as file names.
+	_, err = os.Stat(filepath.Join(dir, kbs.DefaultNamespace, "protocols", "http%2F2.md"))
+	require.NoError(t, err)
+
+	got, err := kbs.LoadVault(dir)
+
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, kbs.NewKB{
+		ID:        "1",
+		Key:       "halving",
+		Value:     "The number of bitcoins generated per block is decreased 50% every four years",
+		Notes:     "Bitcoins have a finite supply",
+		Category:  "bitcoin",
+		Namespace: "cryptos",
+		Tags:      []string{"bitcoin", "halving"},
+	}, got[0])
+	assert.Equal(t, "2", got[1].ID)
+	assert.Equal(t, "http/2", got[1].Key)
+}
+
+func TestVaultPathKeepsNamespacesAsFolders(t *testing.T) {
+	kb := kbs.KB{
+		Key:       "retries",
+		Category:  "runbook",
+		Namespace: "team/backend/payments",
+	}
+
+	assert.Equal(t, filepath.Join("team", "backend", "payments", "runbook", "retries.md"), kb.VaultPath())
+}
+
+func TestVaultPathNeverMapsDifferentKBsToTheSameFile(t *testing.T) {
+	givenKBs := []kbs.KB{
+		{Key: "a/b", Category: "x", Namespace: "team"},
+		{Key: "a-b", Category: "x", Namespace: "team"},
+		{Key: "a%2Fb", Category: "x", Namespace: "team"},
+		{Key: "k", Category: "x", Namespace: "a/b"},
+		{Key: "k", Category: "x", Namespace: "a-b"},
+		{Key: "..", Category: "x", Namespace: "team"},
+	}
+
+	paths := make(map[string]string, len(givenKBs))
+
+	for _, kb := range givenKBs {
+		path := kb.VaultPath()
+		assert.NotContains(t, paths, path, "%s/%s and %s", kb.Namespace, kb.Key, paths[path])
+
+		paths[path] = kb.Namespace + "/" + kb.Key
+	}
+}
+
+func TestSaveToVaultDoesNotOverwriteKBsWithTheSamePath(t *testing.T) {
+	dir := t.TempDir()
+	givenKBs := []kbs.KB{
+		{ID: "1", Key: "Go", Value: "upper", Category: "langs", Namespace: "dev"},
+		{ID: "2", Key: "go", Value: "lower", Category: "langs", Namespace: "dev"},
+	}
+
+	writer := kbs.NewVaultWriter(dir)
+
+	// kbs are saved in batches, like export does.
+	require.NoError(t, writer.Save(givenKBs[0]))
+	require.NoError(t, writer.Save(givenKBs[1]))
+
+	_, err := os.Stat(filepath.Join(dir, "dev", "langs", "go-2.md"))
+	require.NoError(t, err)
+
+	got, err := kbs.LoadVault(dir)
+
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.ElementsMatch(t, []string{"upper", "lower"}, []string{got[0].Value, got[1].Value})
+}
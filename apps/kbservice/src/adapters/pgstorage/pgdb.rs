@@ -234,6 +234,57 @@ impl kb_storage for Store {
         }
     }
 
+    /// get a page of all knowledge base entries sorted by key, key and keyword filters are ignored.
+    async fn list_kbs(&self, filter: KBQueryFilter) -> Result<SearchResult, Error> {
+        // let's count first
+        let count: i64 = match sqlx::query_scalar("SELECT COUNT(*) FROM kbs")
+            .fetch_one(&self.connection)
+            .await
+        {
+            Ok(total) => {
+                debug!("total kbs found: {:?}", total);
+                total
+            }
+            Err(e) => {
+                error!("counting kbs: {:?}", e);
+                tracing::event!(tracing::Level::ERROR, "{:?}", e);
+                return Err(Error::DatabaseQueryError);
+            }
+        };
+        // Now let's query the data, KB_ID breaks ties so pages never overlap.
+        match sqlx::query("SELECT KB_ID, KB_KEY, CATEGORY, TAG_VALUES AS TAGS FROM kbs ORDER BY KB_KEY, KB_ID LIMIT $1 OFFSET $2")
+            .bind(i32::from(filter.limit.unwrap_or(5)))
+            .bind(i32::from(filter.offset))
+            .map(|row: PgRow| KBItem {
+                id: KBID(row.get("kb_id")),
+                key: row.get("kb_key"),
+                category: row.get("category"),
+                tags: row.get::<String, _>("tags")
+                    .split(' ')
+                    .map(|s| s.to_string())
+                    .map(|s| s.replace('\'', ""))
+                    .collect::<Vec<String>>()
+            })
+            .fetch_all(&self.connection)
+            .await {
+            Ok(kbs) => {
+                debug!("listed some kbs: {:?}", kbs);
+
+                Ok(SearchResult {
+                    items: kbs,
+                    offset: filter.offset,
+                    total: count,
+                    limit: filter.limit.unwrap_or(0),
+                })
+            }
+            Err(e) => {
+                error!("listing kbs: {:?}", e);
+                tracing::event!(tracing::Level::ERROR, "{:?}", e);
+                Err(Error::DatabaseQueryError)
+            }
+        }
+    }
+
     /// save given knowledge base in the repository.
     async fn save_kb(&self, kb: KnowledgeBase) -> Result<KBID, Error> {
         debug!("adding kb to postgresql db: {:?}", kb);
@@ -290,6 +341,26 @@ impl kb_storage for Store {
             }
         }
     }
+    /// delete the knowledge base with the given id, false if it does not exist.
+    async fn delete_kb(&self, id: KBID) -> Result<bool, Error> {
+        debug!("deleting a kb from postgresql db: {:?}", id);
+
+        match sqlx::query("DELETE FROM kbs WHERE KB_ID = $1")
+            .bind(id.to_string())
+            .execute(&self.connection)
+            .await
+        {
+            Ok(result) => {
+                debug!("kbs deleted from postgres database: {:?}", result.rows_affected());
+                Ok(result.rows_affected() > 0)
+            }
+            Err(e) => {
+                error!("deleting kb {:?}: {:?}", id, e);
+                tracing::event!(tracing::Level::ERROR, "{:?}", e);
+                Err(Error::DatabaseQueryError)
+            }
+        }
+    }
     /// save given category in the repository.
     async fn save_category(&self, category: Category) -> Result<String, Error> {
         debug!("adding new category to postgresql db: {:?}", category);
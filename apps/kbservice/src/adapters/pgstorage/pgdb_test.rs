@@ -174,6 +174,50 @@ mod pgstorage_test {
         runtime.block_on(store.close());
     }
 
+    #[test]
+    fn test_list_kbs() {
+        // Given
+        if !is_integration_test() {
+            info!("==== skipping test");
+            assert_eq!(true, true);
+            return;
+        }
+        info!("==== running integration test");
+
+        // no key or keyword, a search with this filter finds nothing.
+        let first_page = KBQueryFilter {
+            limit: Some(2),
+            offset: 0,
+            ..Default::default()
+        };
+        let second_page = KBQueryFilter {
+            limit: Some(2),
+            offset: 2,
+            ..Default::default()
+        };
+        let runtime = Runtime::new().expect("Unable to create a runtime");
+        let store = runtime.block_on(new_db_storage());
+
+        // When
+        let first = runtime
+            .block_on(store.list_kbs(first_page))
+            .expect("unable to list first page");
+        let second = runtime
+            .block_on(store.list_kbs(second_page))
+            .expect("unable to list second page");
+
+        // Then
+        assert_eq!(2, first.items.len());
+        assert!(first.total >= 3);
+        assert_eq!(first.total, second.total);
+        assert!(!second.items.is_empty());
+        for item in &second.items {
+            assert!(!first.items.contains(item));
+        }
+
+        runtime.block_on(store.close());
+    }
+
     #[test]
     fn test_search() {
         // Given
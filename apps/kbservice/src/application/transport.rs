@@ -37,6 +37,15 @@ pub fn make_create_routes(
             )
         }));
 
+    log::info!("📚\tCreating list kbs endpoint: GET /kbs/all");
+    let list_kbs = warp::get()
+        .and(warp::path("kbs"))
+        .and(warp::path("all"))
+        .and(warp::path::end())
+        .and(warp::query())
+        .and(service_filter.clone())
+        .and_then(kbs::handler::list);
+
     log::info!("📖\tCreating get kb by id endpoint: GET /kbs/{{id}}");
     let get_kb_by_id = warp::get()
         .and(warp::path("kbs"))
@@ -61,6 +70,14 @@ pub fn make_create_routes(
         .and(service_filter.clone())
         .and_then(kbs::handler::update_kb);
 
+    log::info!("📖\tCreating delete kb endpoint: DELETE /kbs/{{id}}");
+    let delete_kb = warp::delete()
+        .and(warp::path("kbs"))
+        .and(warp::path::param::<String>())
+        .and(warp::path::end())
+        .and(service_filter.clone())
+        .and_then(kbs::handler::delete_kb);
+
     log::info!("📗\tCreating add category endpoint: POST /categories");
     let add_category = warp::post()
         .and(warp::path("categories"))
@@ -69,10 +86,13 @@ pub fn make_create_routes(
         .and(service_filter.clone())
         .and_then(kbs::handler::add_category);
 
+    // list_kbs goes before get_kb_by_id, otherwise "all" would be taken as a kb id.
     search_kbs
+        .or(list_kbs)
         .or(get_kb_by_id)
         .or(add_kb)
         .or(update_kb)
+        .or(delete_kb)
         .or(add_category)
         .with(cors)
         .with(warp::trace::request())
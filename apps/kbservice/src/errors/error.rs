@@ -16,6 +16,7 @@ pub enum Error {
     MissingParameters,
     UpdateKBError,
     KBWasNotUpdatedError,
+    DeleteKBError,
     ParseError(ParseIntError),
 }
 
@@ -33,6 +34,7 @@ impl Display for Error {
             Error::DatabaseQueryError => write!(f, "Unable to query repository"),
             Error::MissingParameters => write!(f, "Missing parameter"),
             Error::KBWasNotUpdatedError => write!(f, "KB was not updated"),
+            Error::DeleteKBError => write!(f, "Unable to delete KB"),
             Error::ParseError(ref err) => write!(f, "Cannot parse parameter: {err}"),
         }
     }
@@ -50,6 +50,22 @@ pub async fn search(
     Ok(warp::reply::json(&result))
 }
 
+pub async fn list(
+    params: HashMap<String, String>,
+    service: Service<impl storage::Storer>,
+) -> Result<impl Reply, Rejection> {
+    debug!("start listing");
+
+    let filter = crate::types::kbs::extract_filter_params(params);
+
+    let result = match service.list(filter).await {
+        Ok(res) => res,
+        Err(e) => return Err(warp::reject::custom(e)),
+    };
+
+    Ok(warp::reply::json(&result))
+}
+
 pub async fn add_kb(
     new_kb: NewKnowledgeBase,
     service: Service<impl storage::Storer>,
@@ -86,6 +102,23 @@ pub async fn update_kb(
     }
 }
 
+pub async fn delete_kb(
+    id: String,
+    service: Service<impl storage::Storer>,
+) -> Result<impl Reply, Rejection> {
+    match service.delete_kb(KBID(id.clone())).await {
+        Ok(_) => {
+            debug!("kb was deleted");
+
+            Ok(warp::reply())
+        }
+        Err(e) => {
+            error!("deleting kb {:?}: {:?}", id, e);
+            Err(warp::reject::custom(e))
+        }
+    }
+}
+
 pub async fn add_category(
     new_category: Category,
     service: Service<impl storage::Storer>,
@@ -131,6 +164,11 @@ pub async fn return_error(r: Rejection) -> Result<impl Reply, Rejection> {
             "Unable to search KBs".to_string(),
             StatusCode::INTERNAL_SERVER_ERROR,
         ))
+    } else if let Some(Error::DeleteKBError) = r.find() {
+        Ok(warp::reply::with_status(
+            "Unable to delete KB".to_string(),
+            StatusCode::INTERNAL_SERVER_ERROR,
+        ))
     } else if let Some(Error::DuplicateKBError) = r.find() {
         Ok(warp::reply::with_status(
             "KB already exists".to_string(),
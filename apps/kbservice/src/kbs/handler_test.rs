@@ -271,6 +271,67 @@ fn test_search() {
     assert_eq!(want, got)
 }
 
+#[test]
+fn test_list() {
+    // Given
+    let mut params: HashMap<String, String> = HashMap::new();
+    params.insert(String::from("offset"), String::from("0"));
+    params.insert(String::from("limit"), String::from("2"));
+
+    let want = SearchResult {
+        items: vec![
+            KBItem {
+                id: KBID(String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c8")),
+                key: String::from("red"),
+                category: String::from("concepts"),
+                tags: vec![String::from("concept"), String::from("color")],
+            },
+            KBItem {
+                id: KBID(String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c9")),
+                key: String::from("redemption"),
+                category: String::from("concepts"),
+                tags: vec![String::from("concept"), String::from("saving")],
+            },
+        ],
+        limit: 2,
+        offset: 0,
+        total: 3,
+    };
+    let stored_kbs = SearchResult {
+        items: vec![
+            KBItem {
+                id: KBID(String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c8")),
+                key: String::from("red"),
+                category: String::from("concepts"),
+                tags: vec![String::from("concept"), String::from("color")],
+            },
+            KBItem {
+                id: KBID(String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c9")),
+                key: String::from("redemption"),
+                category: String::from("concepts"),
+                tags: vec![String::from("concept"), String::from("saving")],
+            },
+        ],
+        limit: 2,
+        offset: 0,
+        total: 3,
+    };
+    let store = KBStore::new_with_list(stored_kbs, false);
+    let service = Service::new(store);
+    let runtime = Runtime::new().expect("unable to create runtime to test list");
+    // When
+    let response = runtime.block_on(handler::list(params, service));
+    // Then
+    let response_body = response.unwrap().into_response().into_body();
+    let body_bytes = runtime
+        .block_on(hyper::body::to_bytes(response_body))
+        .unwrap();
+
+    let got: SearchResult = serde_json::from_slice(&body_bytes).unwrap();
+
+    assert_eq!(want, got)
+}
+
 #[test]
 fn test_add_kb() {
     // Given
@@ -429,16 +490,55 @@ fn test_add_kb_with_error() {
     }
 }
 
+#[test]
+fn test_delete_kb() {
+    // Given
+    let kb_id = String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c9");
+    let store = KBStore::new_with_delete_kb(false, true);
+    let service = Service::new(store);
+    let runtime = Runtime::new().expect("unable to create runtime to test delete kb");
+    // When
+    let response = runtime.block_on(handler::delete_kb(kb_id, service));
+    // Then
+    assert_eq!(StatusCode::OK, response.unwrap().into_response().status());
+}
+
+#[test]
+fn test_delete_kb_not_found() {
+    // Given
+    let kb_id = String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c9");
+    let store = KBStore::new_with_delete_kb(false, false);
+    let service = Service::new(store);
+    let runtime = Runtime::new().expect("unable to create runtime to test delete kb not found");
+    // When
+    let response = runtime.block_on(handler::delete_kb(kb_id, service));
+    // Then
+    let got_error = match response {
+        Ok(value) => panic!("unexpected result {:?}", value.into_response()),
+        Err(err) => err,
+    };
+
+    let reply = runtime
+        .block_on(handler::return_error(got_error))
+        .unwrap()
+        .into_response();
+    assert_eq!(StatusCode::NOT_FOUND, reply.status());
+}
+
 #[derive(Debug, Clone, Default)]
 struct KBStore {
     get_kb_value: Option<KnowledgeBase>,
     search_value: SearchResult,
     search_error: Option<bool>,
+    list_value: SearchResult,
+    list_error: Option<bool>,
     get_kb_error: Option<bool>,
     save_kb_error: Option<bool>,
     save_category_error: Option<bool>,
     update_kb_error: Option<bool>,
     update_kb_result: bool,
+    delete_kb_error: Option<bool>,
+    delete_kb_result: bool,
 }
 
 impl KBStore {
@@ -456,6 +556,13 @@ impl KBStore {
             ..Default::default()
         }
     }
+    fn new_with_list(kbs: SearchResult, is_error: bool) -> Self {
+        KBStore {
+            list_value: kbs,
+            list_error: Some(is_error),
+            ..Default::default()
+        }
+    }
     fn new_with_add_kb(kb: Option<KnowledgeBase>, is_error: bool) -> Self {
         KBStore {
             get_kb_value: kb,
@@ -473,6 +580,13 @@ impl KBStore {
             ..Default::default()
         }
     }
+    fn new_with_delete_kb(is_error: bool, result: bool) -> Self {
+        KBStore {
+            delete_kb_error: Some(is_error),
+            delete_kb_result: result,
+            ..Default::default()
+        }
+    }
     fn new_with_add_category(is_error: bool) -> Self {
         KBStore {
             save_category_error: Some(is_error),
@@ -511,6 +625,13 @@ impl Storer for KBStore {
         }
     }
 
+    async fn list_kbs(&self, _: KBQueryFilter) -> Result<SearchResult, Error> {
+        match &self.list_error.unwrap() {
+            false => Ok(self.list_value.clone()),
+            true => Err(Error::SearchError),
+        }
+    }
+
     async fn save_kb(&self, new_kb: KnowledgeBase) -> Result<KBID, Error> {
         match &self.save_kb_error.unwrap() {
             false => Ok(new_kb.id.clone()),
@@ -525,6 +646,13 @@ impl Storer for KBStore {
         }
     }
 
+    async fn delete_kb(&self, _: KBID) -> Result<bool, Error> {
+        match &self.delete_kb_error.unwrap() {
+            false => Ok(self.delete_kb_result),
+            true => Err(Error::DatabaseQueryError),
+        }
+    }
+
     async fn save_category(&self, category: Category) -> Result<String, Error> {
         match &self.save_category_error.unwrap() {
             false => Ok(category.name.clone()),
@@ -99,6 +99,19 @@ impl<T: Storer> Service<T> {
         }
     }
 
+    pub async fn delete_kb(&self, kb_id: KBID) -> Result<(), Error> {
+        debug!("start deleting kb: {}", kb_id);
+
+        match self.store.delete_kb(kb_id).await {
+            Ok(true) => Ok(()),
+            Ok(false) => Err(Error::KBNotFound),
+            Err(e) => {
+                error!("deleting kb: {:?}", e);
+                Err(Error::DeleteKBError)
+            }
+        }
+    }
+
     pub async fn add_category(&self, new_category: Category) -> Result<bool, Error> {
         debug!("start adding category: {:?}", new_category);
 
@@ -132,6 +145,21 @@ impl<T: Storer> Service<T> {
         Ok(result)
     }
 
+    /// list returns a page of all kbs, so clients can walk every kb without
+    /// knowing any key or keyword, e.g. to synchronize them.
+    pub async fn list(&self, query_params: KBQueryFilter) -> Result<SearchResult, Error> {
+        debug!("start listing kbs: {:?}", query_params);
+
+        match self.store.list_kbs(query_params).await {
+            Ok(kbs) => Ok(kbs),
+            Err(e) => {
+                error!("listing kbs: {:?}", e);
+
+                Err(Error::SearchError)
+            }
+        }
+    }
+
     async fn search_by_key(&self, query_params: KBQueryFilter) -> Result<SearchResult, Error> {
         debug!("start searching by key: {:?}", query_params);
 
@@ -376,6 +376,115 @@ fn test_add_kb() {
     }
 }
 
+#[test]
+fn test_search_without_filters() {
+    // Given
+    let query_params = KBQueryFilter {
+        limit: Some(2),
+        ..Default::default()
+    };
+    let want = SearchResult::default();
+    let stored_kbs = SearchResult {
+        items: vec![KBItem {
+            id: KBID(String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c8")),
+            key: String::from("red"),
+            category: String::from("concepts"),
+            tags: vec![String::from("color")],
+        }],
+        limit: 2,
+        offset: 0,
+        total: 1,
+    };
+    let store = KBStore::new_with_search(stored_kbs, false);
+    let service = Service::new(store);
+    let runtime = Runtime::new().expect("unable to create runtime to test search without filters");
+    // When
+    let got = runtime.block_on(service.search(query_params));
+    // Then
+    match got {
+        Ok(kb_got) => assert_eq!(want, kb_got),
+        Err(err) => panic!("unexpected error: {:?}", err),
+    }
+}
+
+#[test]
+fn test_list() {
+    // Given
+    let query_params = KBQueryFilter {
+        limit: Some(2),
+        offset: 2,
+        ..Default::default()
+    };
+    let want = SearchResult {
+        items: vec![
+            KBItem {
+                id: KBID(String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c8")),
+                key: String::from("red"),
+                category: String::from("concepts"),
+                tags: vec![String::from("concept"), String::from("color")],
+            },
+            KBItem {
+                id: KBID(String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c9")),
+                key: String::from("redemption"),
+                category: String::from("concepts"),
+                tags: vec![String::from("concept"), String::from("saving")],
+            },
+        ],
+        limit: 2,
+        offset: 2,
+        total: 5,
+    };
+    let stored_kbs = SearchResult {
+        items: vec![
+            KBItem {
+                id: KBID(String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c8")),
+                key: String::from("red"),
+                category: String::from("concepts"),
+                tags: vec![String::from("concept"), String::from("color")],
+            },
+            KBItem {
+                id: KBID(String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c9")),
+                key: String::from("redemption"),
+                category: String::from("concepts"),
+                tags: vec![String::from("concept"), String::from("saving")],
+            },
+        ],
+        limit: 2,
+        offset: 2,
+        total: 5,
+    };
+    let store = KBStore::new_with_list(stored_kbs, false);
+    let service = Service::new(store);
+    let runtime = Runtime::new().expect("unable to create runtime to test list");
+    // When
+    let got = runtime.block_on(service.list(query_params));
+    // Then
+    match got {
+        Ok(kb_got) => assert_eq!(want, kb_got),
+        Err(err) => panic!("unexpected error: {:?}", err),
+    }
+}
+
+#[test]
+fn test_list_with_error() {
+    // Given
+    let want = Error::SearchError;
+    let query_params = KBQueryFilter {
+        limit: Some(2),
+        ..Default::default()
+    };
+    let store = KBStore::new_with_list(SearchResult::default(), true);
+    let service = Service::new(store);
+    let runtime = Runtime::new().expect("unable to create runtime to test list with error");
+    // When
+    let got = runtime.block_on(service.list(query_params));
+    // Then
+    match got {
+        Ok(kb_got) => panic!("unexpected result: {:?}", kb_got),
+        Err(err) => assert_eq!(err, want),
+    }
+}
+
 #[test]
 fn test_add_category() {
     // Given
@@ -479,16 +588,67 @@ fn test_update_kb() {
     assert_eq!(false, response.is_err())
 }
 
+#[test]
+fn test_delete_kb() {
+    // Given
+    let kb_id = KBID(String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c9"));
+    let store = KBStore::new_with_delete_kb(false, true);
+    let service = Service::new(store);
+    let runtime = Runtime::new().expect("unable to create runtime to test delete kb");
+    // When
+    let response = runtime.block_on(service.delete_kb(kb_id));
+    // Then
+    assert_eq!(Ok(()), response)
+}
+
+#[test]
+fn test_delete_kb_not_found() {
+    // Given
+    let kb_id = KBID(String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c9"));
+    let want = Error::KBNotFound;
+    let store = KBStore::new_with_delete_kb(false, false);
+    let service = Service::new(store);
+    let runtime = Runtime::new().expect("unable to create runtime to test delete kb not found");
+    // When
+    let got = runtime.block_on(service.delete_kb(kb_id));
+    // Then
+    match got {
+        Ok(_) => panic!("unexpected result: kb was deleted"),
+        Err(err) => assert_eq!(err, want),
+    }
+}
+
+#[test]
+fn test_delete_kb_with_error() {
+    // Given
+    let kb_id = KBID(String::from("dcb8fac0-0756-4c8a-b625-a9a4d1c871c9"));
+    let want = Error::DeleteKBError;
+    let store = KBStore::new_with_delete_kb(true, false);
+    let service = Service::new(store);
+    let runtime = Runtime::new().expect("unable to create runtime to test delete kb with error");
+    // When
+    let got = runtime.block_on(service.delete_kb(kb_id));
+    // Then
+    match got {
+        Ok(_) => panic!("unexpected result: kb was deleted"),
+        Err(err) => assert_eq!(err, want),
+    }
+}
+
 #[derive(Debug, Clone)]
 struct KBStore {
     get_kb_value: Option<KnowledgeBase>,
     search_value: SearchResult,
     search_error: Option<bool>,
+    list_value: SearchResult,
+    list_error: Option<bool>,
     get_kb_error: Option<bool>,
     save_kb_error: Option<bool>,
     update_kb_error: Option<bool>,
     save_category_error: Option<bool>,
     update_kb_result: bool,
+    delete_kb_error: Option<bool>,
+    delete_kb_result: bool,
 }
 
 impl Default for KBStore {
@@ -500,8 +660,12 @@ impl Default for KBStore {
             save_category_error: Default::default(),
             get_kb_value: Default::default(),
             search_value: Default::default(),
+            list_value: Default::default(),
+            list_error: Default::default(),
             update_kb_error: Default::default(),
             update_kb_result: Default::default(),
+            delete_kb_error: Default::default(),
+            delete_kb_result: Default::default(),
         }
     }
 }
@@ -523,6 +687,13 @@ impl KBStore {
 
         dummy_store
     }
+    fn new_with_list(kbs: SearchResult, is_error: bool) -> Self {
+        KBStore {
+            list_value: kbs,
+            list_error: Some(is_error),
+            ..Default::default()
+        }
+    }
     fn new_with_add_kb(kb: Option<KnowledgeBase>, is_error: bool) -> Self {
         let mut dummy_store = KBStore::default();
 
@@ -541,6 +712,13 @@ impl KBStore {
             ..Default::default()
         }
     }
+    fn new_with_delete_kb(is_error: bool, result: bool) -> Self {
+        KBStore {
+            delete_kb_error: Some(is_error),
+            delete_kb_result: result,
+            ..Default::default()
+        }
+    }
     fn new_with_add_category(is_error: bool) -> Self {
         let mut dummy_store = KBStore::default();
         dummy_store.save_category_error = Some(is_error);
@@ -579,6 +757,13 @@ impl Storer for KBStore {
         }
     }
 
+    async fn list_kbs(&self, _: KBQueryFilter) -> Result<SearchResult, Error> {
+        match &self.list_error.unwrap() {
+            false => Ok(self.list_value.clone()),
+            true => Err(Error::SearchError),
+        }
+    }
+
     async fn save_kb(&self, new_kb: KnowledgeBase) -> Result<KBID, Error> {
         match &self.save_kb_error.unwrap() {
             false => Ok(new_kb.id.clone()),
@@ -593,6 +778,13 @@ impl Storer for KBStore {
         }
     }
 
+    async fn delete_kb(&self, _: KBID) -> Result<bool, Error> {
+        match &self.delete_kb_error.unwrap() {
+            false => Ok(self.delete_kb_result),
+            true => Err(Error::DatabaseQueryError),
+        }
+    }
+
     async fn save_category(&self, category: Category) -> Result<String, Error> {
         match &self.save_category_error.unwrap() {
             false => Ok(category.name.clone()),
@@ -14,10 +14,14 @@ pub trait Storer {
     async fn search_by_key(&self, filter: KBQueryFilter) -> Result<SearchResult, Error>;
     /// get a list of knowledge base entries where their keys contain the given keywords.
     async fn search(&self, filter: KBQueryFilter) -> Result<SearchResult, Error>;
+    /// get a page of all knowledge base entries sorted by key, key and keyword filters are ignored.
+    async fn list_kbs(&self, filter: KBQueryFilter) -> Result<SearchResult, Error>;
     /// save given knowledge base in the repository.
     async fn save_kb(&self, kb: KnowledgeBase) -> Result<KBID, Error>;
     /// update given knowledge base.
     async fn update_kb(&self, kb: KnowledgeBase) -> Result<bool, Error>;
+    /// delete the knowledge base with the given id, false if it does not exist.
+    async fn delete_kb(&self, id: KBID) -> Result<bool, Error>;
     /// save given category in the repository.
     async fn save_category(&self, category: Category) -> Result<String, Error>;
     /// get a list of categories based on the given filter